@@ -39,6 +39,7 @@ func SetupHandlers(r *mux.Router) {
 	r.HandleFunc("/version", common.GetVersion).Methods("GET")
 	r.HandleFunc("/hostname", getHostname).Methods("GET")
 	r.HandleFunc("/flare", makeFlare).Methods("POST")
+	r.HandleFunc("/flare/preview", makeFlarePreview).Methods("POST")
 	r.HandleFunc("/stop", stopAgent).Methods("POST")
 	r.HandleFunc("/status", getStatus).Methods("GET")
 	r.HandleFunc("/dogstatsd-stats", getDogstatsdStats).Methods("GET")
@@ -91,6 +92,24 @@ func makeFlare(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(filePath))
 }
 
+func makeFlarePreview(w http.ResponseWriter, r *http.Request) {
+	logFile := config.Datadog.GetString("log_file")
+	if logFile == "" {
+		logFile = common.DefaultLogFile
+	}
+
+	log.Infof("Making a flare preview")
+	filePath, report, err := flare.CreatePreviewArchive(false, common.GetDistPath(), common.PyChecksPath, logFile)
+	if err != nil {
+		log.Errorf("The flare preview failed to be created: %s", err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	j, _ := json.Marshal(flare.Preview{Path: filePath, Report: report})
+	w.Write(j)
+}
+
 func componentConfigHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	component := vars["component"]
@@ -25,8 +25,10 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery"
 	"github.com/DataDog/datadog-agent/pkg/collector"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/check/golden"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/metadata"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	"github.com/DataDog/datadog-agent/pkg/status"
 	"github.com/DataDog/datadog-agent/pkg/util"
@@ -52,6 +54,9 @@ var (
 	profileMemoryFilters string
 	profileMemoryUnit    string
 	profileMemoryVerbose string
+	checkRecordFile      string
+	checkCompareFile     string
+	checkCompareStrict   bool
 )
 
 // Make the check cmd aggregator never flush by setting a very high interval
@@ -68,6 +73,9 @@ func init() {
 	checkCmd.Flags().BoolVarP(&formatJSON, "json", "", false, "format aggregator and check runner output as json")
 	checkCmd.Flags().StringVarP(&breakPoint, "breakpoint", "b", "", "set a breakpoint at a particular line number (Python checks only)")
 	checkCmd.Flags().BoolVarP(&profileMemory, "profile-memory", "m", false, "run the memory profiler (Python checks only)")
+	checkCmd.Flags().StringVar(&checkRecordFile, "record", "", "record the metrics, events and service checks sent by the check to a golden file")
+	checkCmd.Flags().StringVar(&checkCompareFile, "compare", "", "compare the metrics, events and service checks sent by the check to a golden file, exit with an error on mismatch")
+	checkCmd.Flags().BoolVar(&checkCompareStrict, "compare-strict", false, "with --compare, also compare metric values, service check statuses and event texts")
 
 	// Power user flags - mark as hidden
 	createHiddenStringFlag(&profileMemoryDir, "m-dir", "", "an existing directory in which to store memory profiling data, ignoring clean-up")
@@ -263,6 +271,11 @@ var checkCmd = &cobra.Command{
 			fmt.Println("Multiple check instances found, running each of them")
 		}
 
+		var recording *golden.Recording
+		if checkRecordFile != "" || checkCompareFile != "" {
+			recording = golden.NewRecording(checkName, hostname)
+		}
+
 		var instancesData []interface{}
 		for _, c := range cs {
 			s := runCheck(c, agg)
@@ -270,8 +283,15 @@ var checkCmd = &cobra.Command{
 			// Sleep for a while to allow the aggregator to finish ingesting all the metrics/events/sc
 			time.Sleep(time.Duration(checkDelay) * time.Millisecond)
 
+			output := flushAggregator(agg)
+			if recording != nil {
+				recording.AddSeries(output.series, output.sketches)
+				recording.AddServiceChecks(output.serviceChecks)
+				recording.AddEvents(output.events)
+			}
+
 			if formatJSON {
-				aggregatorData := getMetricsData(agg)
+				aggregatorData := getMetricsData(output)
 				var collectorData map[string]interface{}
 
 				collectorJSON, _ := status.GetCheckStatusJSON(c, s)
@@ -349,7 +369,7 @@ var checkCmd = &cobra.Command{
 					return fmt.Errorf("no diff data found in %s", profileDataDir)
 				}
 			} else {
-				printMetrics(agg)
+				printMetrics(output)
 				checkStatus, _ := status.GetCheckStatus(c, s)
 				fmt.Println(string(checkStatus))
 			}
@@ -371,7 +391,19 @@ var checkCmd = &cobra.Command{
 			}
 		}
 
-		return nil
+		var compareErr error
+		if checkCompareFile != "" {
+			compareErr = compareCheckOutput(recording)
+		}
+
+		if checkRecordFile != "" {
+			if err := recording.Save(checkRecordFile); err != nil {
+				return fmt.Errorf("unable to record the check output to %s: %v", checkRecordFile, err)
+			}
+			fmt.Fprintln(color.Output, fmt.Sprintf("Check output recorded to %s", color.GreenString(checkRecordFile)))
+		}
+
+		return compareErr
 	},
 }
 
@@ -403,8 +435,47 @@ func runCheck(c check.Check, agg *aggregator.BufferedAggregator) *check.Stats {
 	return s
 }
 
-func printMetrics(agg *aggregator.BufferedAggregator) {
+// aggregatorOutput holds the samples flushed from the aggregator after a check run
+type aggregatorOutput struct {
+	series        metrics.Series
+	sketches      metrics.SketchSeriesList
+	serviceChecks metrics.ServiceChecks
+	events        metrics.Events
+}
+
+func flushAggregator(agg *aggregator.BufferedAggregator) aggregatorOutput {
 	series, sketches := agg.GetSeriesAndSketches()
+	return aggregatorOutput{
+		series:        series,
+		sketches:      sketches,
+		serviceChecks: agg.GetServiceChecks(),
+		events:        agg.GetEvents(),
+	}
+}
+
+// compareCheckOutput compares the recorded check output to the golden file
+// passed with --compare, and returns an error if they don't match
+func compareCheckOutput(actual *golden.Recording) error {
+	expected, err := golden.Load(checkCompareFile)
+	if err != nil {
+		return fmt.Errorf("unable to load the golden file: %v", err)
+	}
+
+	diffs := golden.Compare(expected, actual, checkCompareStrict)
+	if len(diffs) == 0 {
+		fmt.Fprintln(color.Output, fmt.Sprintf("=== %s ===", color.GreenString("Check output matches %s", checkCompareFile)))
+		return nil
+	}
+
+	fmt.Fprintln(color.Output, fmt.Sprintf("=== %s ===", color.RedString("Check output does not match %s", checkCompareFile)))
+	for _, d := range diffs {
+		fmt.Fprintln(color.Output, fmt.Sprintf("* %s %s: %s", d.Kind, color.YellowString(d.Key), d.Reason))
+	}
+	return fmt.Errorf("check output does not match %s: %d difference(s)", checkCompareFile, len(diffs))
+}
+
+func printMetrics(output aggregatorOutput) {
+	series, sketches := output.series, output.sketches
 	if len(series) != 0 {
 		fmt.Fprintln(color.Output, fmt.Sprintf("=== %s ===", color.BlueString("Series")))
 		j, _ := json.MarshalIndent(series, "", "  ")
@@ -416,14 +487,14 @@ func printMetrics(agg *aggregator.BufferedAggregator) {
 		fmt.Println(string(j))
 	}
 
-	serviceChecks := agg.GetServiceChecks()
+	serviceChecks := output.serviceChecks
 	if len(serviceChecks) != 0 {
 		fmt.Fprintln(color.Output, fmt.Sprintf("=== %s ===", color.BlueString("Service Checks")))
 		j, _ := json.MarshalIndent(serviceChecks, "", "  ")
 		fmt.Println(string(j))
 	}
 
-	events := agg.GetEvents()
+	events := output.events
 	if len(events) != 0 {
 		fmt.Fprintln(color.Output, fmt.Sprintf("=== %s ===", color.BlueString("Events")))
 		j, _ := json.MarshalIndent(events, "", "  ")
@@ -431,10 +502,10 @@ func printMetrics(agg *aggregator.BufferedAggregator) {
 	}
 }
 
-func getMetricsData(agg *aggregator.BufferedAggregator) map[string]interface{} {
+func getMetricsData(output aggregatorOutput) map[string]interface{} {
 	aggData := make(map[string]interface{})
 
-	series, sketches := agg.GetSeriesAndSketches()
+	series, sketches := output.series, output.sketches
 	if len(series) != 0 {
 		// Workaround to get the raw sequence of metrics, see:
 		// https://github.com/DataDog/datadog-agent/blob/b2d9527ec0ec0eba1a7ae64585df443c5b761610/pkg/metrics/series.go#L109-L122
@@ -448,12 +519,12 @@ func getMetricsData(agg *aggregator.BufferedAggregator) map[string]interface{} {
 		aggData["sketches"] = sketches
 	}
 
-	serviceChecks := agg.GetServiceChecks()
+	serviceChecks := output.serviceChecks
 	if len(serviceChecks) != 0 {
 		aggData["service_checks"] = serviceChecks
 	}
 
-	events := agg.GetEvents()
+	events := output.events
 	if len(events) != 0 {
 		aggData["events"] = events
 	}
@@ -16,8 +16,12 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var diagnoseJSON bool
+
 func init() {
 	AgentCmd.AddCommand(diagnoseCommand)
+
+	diagnoseCommand.Flags().BoolVarP(&diagnoseJSON, "json", "j", false, "output the results of the diagnosis as JSON")
 }
 
 var diagnoseCommand = &cobra.Command{
@@ -51,5 +55,8 @@ func doDiagnose(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Error while setting up logging, exiting: %v", err)
 	}
 
+	if diagnoseJSON {
+		return diagnose.RunAllJSON(color.Output)
+	}
 	return diagnose.RunAll(color.Output)
 }
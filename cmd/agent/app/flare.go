@@ -7,7 +7,9 @@ package app
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/common"
 	"github.com/DataDog/datadog-agent/pkg/api/util"
@@ -22,6 +24,7 @@ import (
 var (
 	customerEmail string
 	autoconfirm   bool
+	flarePreview  bool
 )
 
 func init() {
@@ -29,6 +32,7 @@ func init() {
 
 	flareCmd.Flags().StringVarP(&customerEmail, "email", "e", "", "Your email")
 	flareCmd.Flags().BoolVarP(&autoconfirm, "send", "s", false, "Automatically send flare (don't prompt for confirmation)")
+	flareCmd.Flags().BoolVarP(&flarePreview, "preview", "", false, "Build the scrubbed flare locally and list the scrubbing rules that matched, without sending it")
 	flareCmd.SetArgs([]string{"caseID"})
 }
 
@@ -54,6 +58,10 @@ var flareCmd = &cobra.Command{
 			return err
 		}
 
+		if flarePreview {
+			return previewFlare()
+		}
+
 		caseID := ""
 		if len(args) > 0 {
 			caseID = args[0]
@@ -72,6 +80,66 @@ var flareCmd = &cobra.Command{
 	},
 }
 
+func getLogFile() string {
+	logFile := config.Datadog.GetString("log_file")
+	if logFile == "" {
+		logFile = common.DefaultLogFile
+	}
+	return logFile
+}
+
+func previewFlare() error {
+	fmt.Fprintln(color.Output, color.BlueString("Asking the agent to build the flare archive preview."))
+	c := util.GetClient(false) // FIX: get certificates right then make this true
+	ipcAddress, err := config.GetIPCAddress()
+	if err != nil {
+		return err
+	}
+	urlstr := fmt.Sprintf("https://%v:%v/agent/flare/preview", ipcAddress, config.Datadog.GetInt("cmd_port"))
+
+	// Set session token
+	if err := util.SetAuthToken(); err != nil {
+		return err
+	}
+
+	var preview flare.Preview
+	r, e := util.DoPost(c, urlstr, "application/json", bytes.NewBuffer([]byte{}))
+	if e == nil {
+		e = json.Unmarshal(r, &preview)
+	}
+	if e != nil {
+		if r != nil && string(r) != "" {
+			fmt.Fprintln(color.Output, fmt.Sprintf("The agent ran into an error while making the flare preview: %s", color.RedString(string(r))))
+		} else {
+			fmt.Fprintln(color.Output, color.RedString("The agent was unable to make the flare preview. (is it running?)"))
+		}
+		// build the preview locally, as the flare would be
+		fmt.Fprintln(color.Output, color.YellowString("Initiating flare preview locally."))
+		preview.Path, preview.Report, e = flare.CreatePreviewArchive(true, common.GetDistPath(), common.PyChecksPath, getLogFile())
+		if e != nil {
+			fmt.Printf("The flare zipfile failed to be created: %s\n", e)
+			return e
+		}
+	}
+	filePath, report := preview.Path, preview.Report
+
+	files := report.Files()
+	if len(files) == 0 {
+		fmt.Fprintln(color.Output, "No custom scrubbing rule matched.")
+	} else {
+		fmt.Fprintln(color.Output, "Custom scrubbing rules matched:")
+		for _, file := range files {
+			var matches []string
+			for _, rule := range report.Rules(file) {
+				matches = append(matches, fmt.Sprintf("%s (%d)", rule, report[file][rule]))
+			}
+			fmt.Fprintln(color.Output, fmt.Sprintf("  %s: %s", color.YellowString(file), strings.Join(matches, ", ")))
+		}
+	}
+	fmt.Fprintln(color.Output, fmt.Sprintf("The scrubbed flare was written to %s, it has not been sent.", color.YellowString(filePath)))
+	return nil
+}
+
 func requestFlare(caseID string) error {
 	fmt.Fprintln(color.Output, color.BlueString("Asking the agent to build the flare archive."))
 	var e error
@@ -82,10 +150,7 @@ func requestFlare(caseID string) error {
 	}
 	urlstr := fmt.Sprintf("https://%v:%v/agent/flare", ipcAddress, config.Datadog.GetInt("cmd_port"))
 
-	logFile := config.Datadog.GetString("log_file")
-	if logFile == "" {
-		logFile = common.DefaultLogFile
-	}
+	logFile := getLogFile()
 
 	// Set session token
 	e = util.SetAuthToken()
@@ -177,6 +177,7 @@ type BufferedAggregator struct {
 
 	statsdSampler      TimeSampler
 	checkSamplers      map[check.ID]*CheckSampler
+	metricFilter       *metricFilter
 	serviceChecks      metrics.ServiceChecks
 	events             metrics.Events
 	flushInterval      time.Duration
@@ -207,6 +208,7 @@ func NewBufferedAggregator(s serializer.MetricSerializer, hostname, agentName st
 
 		statsdSampler:      *NewTimeSampler(bucketSize),
 		checkSamplers:      make(map[check.ID]*CheckSampler),
+		metricFilter:       newMetricFilterFromConfig(),
 		flushInterval:      flushInterval,
 		serializer:         s,
 		hostname:           hostname,
@@ -316,7 +318,7 @@ func (agg *BufferedAggregator) handleSenderSample(ss senderMetricSample) {
 	if checkSampler, ok := agg.checkSamplers[ss.id]; ok {
 		if ss.commit {
 			checkSampler.commit(timeNowNano())
-		} else {
+		} else if agg.metricFilter.filterSample(ss.metricSample) {
 			ss.metricSample.Tags = deduplicateTags(ss.metricSample.Tags)
 			checkSampler.addSample(ss.metricSample)
 		}
@@ -330,6 +332,9 @@ func (agg *BufferedAggregator) handleSenderBucket(checkBucket senderHistogramBuc
 	defer agg.mu.Unlock()
 
 	if checkSampler, ok := agg.checkSamplers[checkBucket.id]; ok {
+		if !agg.metricFilter.filterBucket(checkBucket.bucket) {
+			return
+		}
 		checkBucket.bucket.Tags = deduplicateTags(checkBucket.bucket.Tags)
 		checkSampler.addBucket(checkBucket.bucket)
 	} else {
@@ -359,6 +364,9 @@ func (agg *BufferedAggregator) addEvent(e metrics.Event) {
 
 // addSample adds the metric sample
 func (agg *BufferedAggregator) addSample(metricSample *metrics.MetricSample, timestamp float64) {
+	if !agg.metricFilter.filterSample(metricSample) {
+		return
+	}
 	metricSample.Tags = deduplicateTags(metricSample.Tags)
 	agg.statsdSampler.addSample(metricSample, timestamp)
 }
@@ -0,0 +1,199 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package aggregator
+
+import (
+	"expvar"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Actions of the metric filters
+const (
+	filterActionDrop      = "drop"
+	filterActionStripTags = "strip_tags"
+)
+
+// maximum number of metric names whose matching rules are cached
+const filterCacheSize = 10000
+
+var metricFilterExpvars = expvar.Map{}
+
+func init() {
+	aggregatorExpvars.Set("MetricFilters", &metricFilterExpvars)
+}
+
+type metricFilterRule struct {
+	name    string
+	action  string
+	regex   *regexp.Regexp
+	tagKeys map[string]struct{}
+	samples *expvar.Int // samples dropped, or whose tags were stripped
+}
+
+// metricFilter drops metric samples or strips some of their tags based on
+// their name, before they reach the samplers. As contexts are generated from
+// the filtered samples, samples that only differ by a stripped tag end up in
+// the same context and are aggregated together.
+// A metricFilter is not thread safe, it is only used by the aggregator
+// goroutine.
+type metricFilter struct {
+	rules []*metricFilterRule
+	// indexes of the rules matching a metric name
+	cache map[string][]int
+}
+
+// newMetricFilterFromConfig builds a metricFilter from the `metric_filters`
+// setting, invalid rules are logged and ignored
+func newMetricFilterFromConfig() *metricFilter {
+	var rules []config.MetricFilterRule
+	if err := config.Datadog.UnmarshalKey("metric_filters", &rules); err != nil {
+		log.Errorf("Unable to parse metric_filters: %s", err)
+		return newMetricFilter(nil)
+	}
+	return newMetricFilter(rules)
+}
+
+func newMetricFilter(rules []config.MetricFilterRule) *metricFilter {
+	f := &metricFilter{cache: make(map[string][]int)}
+	metricFilterExpvars.Init()
+
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule_%d", i)
+		}
+		if err := validateMetricFilterRule(rule); err != nil {
+			log.Errorf("Ignoring metric filter %s: %s", name, err)
+			continue
+		}
+		re, err := regexp.Compile("^(?:" + rule.MetricRegex + ")$")
+		if err != nil {
+			log.Errorf("Ignoring metric filter %s: invalid metric_regex: %s", name, err)
+			continue
+		}
+		tagKeys := make(map[string]struct{}, len(rule.Tags))
+		for _, key := range rule.Tags {
+			tagKeys[key] = struct{}{}
+		}
+
+		r := &metricFilterRule{
+			name:    name,
+			action:  rule.Action,
+			regex:   re,
+			tagKeys: tagKeys,
+			samples: &expvar.Int{},
+		}
+		metricFilterExpvars.Set(name, r.samples)
+		f.rules = append(f.rules, r)
+	}
+	return f
+}
+
+func validateMetricFilterRule(rule config.MetricFilterRule) error {
+	if rule.MetricRegex == "" {
+		return fmt.Errorf("no metric_regex defined")
+	}
+	switch rule.Action {
+	case filterActionDrop:
+	case filterActionStripTags:
+		if len(rule.Tags) == 0 {
+			return fmt.Errorf("no tags defined for the %s action", filterActionStripTags)
+		}
+	default:
+		return fmt.Errorf("unknown action %q, must be %s or %s", rule.Action, filterActionDrop, filterActionStripTags)
+	}
+	return nil
+}
+
+// matchingRules returns the indexes of the rules matching a metric name
+func (f *metricFilter) matchingRules(name string) []int {
+	if matching, found := f.cache[name]; found {
+		return matching
+	}
+
+	var matching []int
+	for i, rule := range f.rules {
+		if rule.regex.MatchString(name) {
+			matching = append(matching, i)
+		}
+	}
+
+	if len(f.cache) >= filterCacheSize {
+		f.cache = make(map[string][]int)
+	}
+	f.cache[name] = matching
+	return matching
+}
+
+// apply applies the rules matching a metric name to its tags. It returns
+// false if the metric must be dropped, and the filtered tags otherwise.
+func (f *metricFilter) apply(name string, tags []string) ([]string, bool) {
+	if f == nil || len(f.rules) == 0 {
+		return tags, true
+	}
+
+	for _, i := range f.matchingRules(name) {
+		rule := f.rules[i]
+		if rule.action == filterActionDrop {
+			rule.samples.Add(1)
+			return nil, false
+		}
+		if stripped, ok := stripTags(tags, rule.tagKeys); ok {
+			rule.samples.Add(1)
+			tags = stripped
+		}
+	}
+	return tags, true
+}
+
+// filterSample filters a metric sample in place, and returns false if it
+// must be dropped
+func (f *metricFilter) filterSample(sample *metrics.MetricSample) bool {
+	tags, keep := f.apply(sample.Name, sample.Tags)
+	sample.Tags = tags
+	return keep
+}
+
+// filterBucket filters a histogram bucket in place, and returns false if it
+// must be dropped
+func (f *metricFilter) filterBucket(bucket *metrics.HistogramBucket) bool {
+	tags, keep := f.apply(bucket.Name, bucket.Tags)
+	bucket.Tags = tags
+	return keep
+}
+
+// stripTags returns a copy of tags without the ones whose key is in keys, and
+// whether any tag was removed. The tags slice is left untouched as it might be
+// shared with the caller.
+func stripTags(tags []string, keys map[string]struct{}) ([]string, bool) {
+	var stripped []string
+	for i, tag := range tags {
+		key := tag
+		if idx := strings.IndexByte(tag, ':'); idx >= 0 {
+			key = tag[:idx]
+		}
+		if _, found := keys[key]; !found {
+			if stripped != nil {
+				stripped = append(stripped, tag)
+			}
+			continue
+		}
+		if stripped == nil {
+			stripped = make([]string, i, len(tags))
+			copy(stripped, tags[:i])
+		}
+	}
+	if stripped == nil {
+		return tags, false
+	}
+	return stripped, true
+}
@@ -0,0 +1,110 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestNewMetricFilterInvalidRules(t *testing.T) {
+	f := newMetricFilter([]config.MetricFilterRule{
+		{Name: "valid", MetricRegex: "my\\.metric", Action: "drop"},
+		{Name: "no_regex", Action: "drop"},
+		{Name: "bad_regex", MetricRegex: "(my", Action: "drop"},
+		{Name: "bad_action", MetricRegex: "my", Action: "sample"},
+		{Name: "no_tags", MetricRegex: "my", Action: "strip_tags"},
+	})
+	require.Len(t, f.rules, 1)
+	assert.Equal(t, "valid", f.rules[0].name)
+}
+
+func TestMetricFilterApply(t *testing.T) {
+	f := newMetricFilter([]config.MetricFilterRule{
+		{Name: "drop_debug", MetricRegex: "myapp\\.debug\\..*", Action: "drop"},
+		{Name: "strip_pod", MetricRegex: "myapp\\..*", Action: "strip_tags", Tags: []string{"pod_name", "bare"}},
+	})
+	require.Len(t, f.rules, 2)
+
+	_, keep := f.apply("myapp.debug.requests", []string{"pod_name:foo"})
+	assert.False(t, keep)
+	assert.Equal(t, int64(1), f.rules[0].samples.Value())
+
+	// the regex must match the whole name
+	tags := []string{"pod_name:foo", "env:prod"}
+	filtered, keep := f.apply("other.myapp.requests", tags)
+	assert.True(t, keep)
+	assert.Equal(t, tags, filtered)
+
+	filtered, keep = f.apply("myapp.requests", []string{"bare", "pod_name:foo", "env:prod", "pod_namespace:bar"})
+	assert.True(t, keep)
+	assert.Equal(t, []string{"env:prod", "pod_namespace:bar"}, filtered)
+	assert.Equal(t, int64(1), f.rules[1].samples.Value())
+
+	// samples without any of the tags aren't counted
+	filtered, keep = f.apply("myapp.requests", []string{"env:prod"})
+	assert.True(t, keep)
+	assert.Equal(t, []string{"env:prod"}, filtered)
+	assert.Equal(t, int64(1), f.rules[1].samples.Value())
+
+	assert.Len(t, f.cache, 3)
+}
+
+func TestMetricFilterNoRules(t *testing.T) {
+	var f *metricFilter
+	sample := &metrics.MetricSample{Name: "my.metric", Tags: []string{"a:b"}}
+	assert.True(t, f.filterSample(sample))
+	assert.Equal(t, []string{"a:b"}, sample.Tags)
+
+	f = newMetricFilter(nil)
+	assert.True(t, f.filterSample(sample))
+	assert.Equal(t, []string{"a:b"}, sample.Tags)
+}
+
+func TestStripTagsDoesNotModifyInput(t *testing.T) {
+	tags := []string{"a:1", "pod_name:foo", "b:2"}
+	stripped, ok := stripTags(tags, map[string]struct{}{"pod_name": {}})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a:1", "b:2"}, stripped)
+	assert.Equal(t, []string{"a:1", "pod_name:foo", "b:2"}, tags)
+
+	stripped, ok = stripTags([]string{"pod_name:foo"}, map[string]struct{}{"pod_name": {}})
+	assert.True(t, ok)
+	assert.Empty(t, stripped)
+}
+
+func TestAggregatorMetricFilter(t *testing.T) {
+	agg := NewBufferedAggregator(nil, "hostname", "agent", DefaultFlushInterval)
+	agg.metricFilter = newMetricFilter([]config.MetricFilterRule{
+		{Name: "drop", MetricRegex: "dropped\\..*", Action: "drop"},
+		{Name: "strip", MetricRegex: "kept\\..*", Action: "strip_tags", Tags: []string{"pod_name"}},
+	})
+
+	for _, sample := range []*metrics.MetricSample{
+		{Name: "dropped.metric", Value: 1, Mtype: metrics.GaugeType, Tags: []string{"env:prod"}, SampleRate: 1},
+		{Name: "kept.metric", Value: 1, Mtype: metrics.CountType, Tags: []string{"env:prod", "pod_name:a"}, SampleRate: 1},
+		{Name: "kept.metric", Value: 2, Mtype: metrics.CountType, Tags: []string{"env:prod", "pod_name:b"}, SampleRate: 1},
+	} {
+		agg.addSample(sample, 12345.0)
+	}
+
+	// both kept.metric samples share the same context
+	require.Len(t, agg.statsdSampler.contextResolver.contextsByKey, 1)
+	for _, context := range agg.statsdSampler.contextResolver.contextsByKey {
+		assert.Equal(t, "kept.metric", context.Name)
+		assert.Equal(t, []string{"env:prod"}, context.Tags)
+	}
+
+	series, _ := agg.statsdSampler.flush(12400.0)
+	require.Len(t, series, 1)
+	require.Len(t, series[0].Points, 1)
+	assert.Equal(t, 3.0, series[0].Points[0].Value)
+}
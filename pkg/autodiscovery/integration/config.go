@@ -61,6 +61,8 @@ type CommonInstanceConfig struct {
 	Tags                  []string `yaml:"tags"`
 	Name                  string   `yaml:"name"`
 	Namespace             string   `yaml:"namespace"`
+	Schedule              string   `yaml:"schedule"`
+	Jitter                int      `yaml:"jitter"`
 }
 
 // Equal determines whether the passed config is the same
@@ -24,3 +24,10 @@ type Check interface {
 	Version() string                                                    // return the version of the check if available
 	ConfigSource() string                                               // return the configuration source of the check
 }
+
+// Scheduled is implemented by checks supporting the `schedule` and `jitter`
+// instance options. Checks returning an empty schedule are run every Interval().
+type Scheduled interface {
+	Schedule() string      // return the cron expression the check should run on, if any
+	Jitter() time.Duration // return the maximum random delay to apply before each run
+}
@@ -0,0 +1,212 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package golden
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// relative tolerance used to compare metric values in strict mode
+const valueTolerance = 1e-9
+
+// Difference is a mismatch between a golden file and a new check run
+type Difference struct {
+	Kind   string // metric, service check or event
+	Key    string // identifies the sample: name, type, host and tags
+	Reason string
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s %s: %s", d.Kind, d.Key, d.Reason)
+}
+
+// Compare returns the differences between an expected recording and the
+// actual output of a check run. By default only the presence of metric
+// names, types and tags, service check names and tags, and event titles,
+// types and tags are compared. In strict mode, the number of samples,
+// metric values, service check statuses and messages and event texts are
+// compared as well.
+func Compare(expected, actual *Recording, strict bool) []Difference {
+	var diffs []Difference
+
+	expMetrics, expKeys := groupMetrics(expected.Metrics)
+	actMetrics, actKeys := groupMetrics(actual.Metrics)
+	diffs = append(diffs, compareKeys("metric", expKeys, actKeys)...)
+	if strict {
+		for _, key := range commonKeys(expKeys, actKeys) {
+			diffs = append(diffs, compareMetrics(key, expMetrics[key], actMetrics[key])...)
+		}
+	}
+
+	expChecks, expKeys := groupServiceChecks(expected.ServiceChecks)
+	actChecks, actKeys := groupServiceChecks(actual.ServiceChecks)
+	diffs = append(diffs, compareKeys("service check", expKeys, actKeys)...)
+	if strict {
+		for _, key := range commonKeys(expKeys, actKeys) {
+			diffs = append(diffs, compareServiceChecks(key, expChecks[key], actChecks[key])...)
+		}
+	}
+
+	expEvents, expKeys := groupEvents(expected.Events)
+	actEvents, actKeys := groupEvents(actual.Events)
+	diffs = append(diffs, compareKeys("event", expKeys, actKeys)...)
+	if strict {
+		for _, key := range commonKeys(expKeys, actKeys) {
+			diffs = append(diffs, compareEvents(key, expEvents[key], actEvents[key])...)
+		}
+	}
+
+	return diffs
+}
+
+func compareKeys(kind string, expected, actual map[string]bool) []Difference {
+	var diffs []Difference
+	for _, key := range sortedKeys(expected) {
+		if !actual[key] {
+			diffs = append(diffs, Difference{Kind: kind, Key: key, Reason: "missing"})
+		}
+	}
+	for _, key := range sortedKeys(actual) {
+		if !expected[key] {
+			diffs = append(diffs, Difference{Kind: kind, Key: key, Reason: "unexpected"})
+		}
+	}
+	return diffs
+}
+
+func compareMetrics(key string, expected, actual []Metric) []Difference {
+	if len(expected) != len(actual) {
+		return []Difference{{Kind: "metric", Key: key, Reason: fmt.Sprintf("expected %d sample(s), got %d", len(expected), len(actual))}}
+	}
+
+	sort.Slice(expected, func(i, j int) bool { return expected[i].Value < expected[j].Value })
+	sort.Slice(actual, func(i, j int) bool { return actual[i].Value < actual[j].Value })
+
+	var diffs []Difference
+	for i := range expected {
+		if !floatEquals(expected[i].Value, actual[i].Value) {
+			diffs = append(diffs, Difference{Kind: "metric", Key: key, Reason: fmt.Sprintf("expected value %v, got %v", expected[i].Value, actual[i].Value)})
+		}
+		if expected[i].Count != actual[i].Count {
+			diffs = append(diffs, Difference{Kind: "metric", Key: key, Reason: fmt.Sprintf("expected count %d, got %d", expected[i].Count, actual[i].Count)})
+		}
+	}
+	return diffs
+}
+
+func compareServiceChecks(key string, expected, actual []ServiceCheck) []Difference {
+	if len(expected) != len(actual) {
+		return []Difference{{Kind: "service check", Key: key, Reason: fmt.Sprintf("expected %d sample(s), got %d", len(expected), len(actual))}}
+	}
+
+	sort.Slice(expected, func(i, j int) bool {
+		return expected[i].Status+expected[i].Message < expected[j].Status+expected[j].Message
+	})
+	sort.Slice(actual, func(i, j int) bool {
+		return actual[i].Status+actual[i].Message < actual[j].Status+actual[j].Message
+	})
+
+	var diffs []Difference
+	for i := range expected {
+		if expected[i].Status != actual[i].Status {
+			diffs = append(diffs, Difference{Kind: "service check", Key: key, Reason: fmt.Sprintf("expected status %s, got %s", expected[i].Status, actual[i].Status)})
+		}
+		if expected[i].Message != actual[i].Message {
+			diffs = append(diffs, Difference{Kind: "service check", Key: key, Reason: fmt.Sprintf("expected message %q, got %q", expected[i].Message, actual[i].Message)})
+		}
+	}
+	return diffs
+}
+
+func compareEvents(key string, expected, actual []Event) []Difference {
+	if len(expected) != len(actual) {
+		return []Difference{{Kind: "event", Key: key, Reason: fmt.Sprintf("expected %d sample(s), got %d", len(expected), len(actual))}}
+	}
+
+	sort.Slice(expected, func(i, j int) bool { return expected[i].Text < expected[j].Text })
+	sort.Slice(actual, func(i, j int) bool { return actual[i].Text < actual[j].Text })
+
+	var diffs []Difference
+	for i := range expected {
+		if expected[i].Text != actual[i].Text {
+			diffs = append(diffs, Difference{Kind: "event", Key: key, Reason: fmt.Sprintf("expected text %q, got %q", expected[i].Text, actual[i].Text)})
+		}
+		if expected[i].Priority != actual[i].Priority {
+			diffs = append(diffs, Difference{Kind: "event", Key: key, Reason: fmt.Sprintf("expected priority %q, got %q", expected[i].Priority, actual[i].Priority)})
+		}
+		if expected[i].AggregationKey != actual[i].AggregationKey {
+			diffs = append(diffs, Difference{Kind: "event", Key: key, Reason: fmt.Sprintf("expected aggregation key %q, got %q", expected[i].AggregationKey, actual[i].AggregationKey)})
+		}
+	}
+	return diffs
+}
+
+// groupMetrics groups samples by key, and returns the set of keys
+func groupMetrics(samples []Metric) (map[string][]Metric, map[string]bool) {
+	groups := make(map[string][]Metric)
+	keys := make(map[string]bool)
+	for _, m := range samples {
+		m.Tags = normalizeTags(m.Tags)
+		key := metricKey(m)
+		groups[key] = append(groups[key], m)
+		keys[key] = true
+	}
+	return groups, keys
+}
+
+// groupServiceChecks groups samples by key, and returns the set of keys
+func groupServiceChecks(samples []ServiceCheck) (map[string][]ServiceCheck, map[string]bool) {
+	groups := make(map[string][]ServiceCheck)
+	keys := make(map[string]bool)
+	for _, sc := range samples {
+		sc.Tags = normalizeTags(sc.Tags)
+		key := serviceCheckKey(sc)
+		groups[key] = append(groups[key], sc)
+		keys[key] = true
+	}
+	return groups, keys
+}
+
+// groupEvents groups samples by key, and returns the set of keys
+func groupEvents(samples []Event) (map[string][]Event, map[string]bool) {
+	groups := make(map[string][]Event)
+	keys := make(map[string]bool)
+	for _, e := range samples {
+		e.Tags = normalizeTags(e.Tags)
+		key := eventKey(e)
+		groups[key] = append(groups[key], e)
+		keys[key] = true
+	}
+	return groups, keys
+}
+
+func commonKeys(a, b map[string]bool) []string {
+	var common []string
+	for _, key := range sortedKeys(a) {
+		if b[key] {
+			common = append(common, key)
+		}
+	}
+	return common
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func floatEquals(a, b float64) bool {
+	if a == b {
+		return true
+	}
+	return math.Abs(a-b) <= valueTolerance*math.Max(math.Abs(a), math.Abs(b))
+}
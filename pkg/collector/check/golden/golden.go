@@ -0,0 +1,213 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package golden
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// formatVersion is bumped on incompatible changes of the golden file format
+const formatVersion = 1
+
+// Metric is a recorded metric context, sketches are recorded with the
+// `distribution` type, their count and their sum as value.
+type Metric struct {
+	Name  string   `json:"name"`
+	Type  string   `json:"type"`
+	Tags  []string `json:"tags"`
+	Host  string   `json:"host,omitempty"`
+	Value float64  `json:"value"`
+	Count int64    `json:"count,omitempty"`
+}
+
+// ServiceCheck is a recorded service check
+type ServiceCheck struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Tags    []string `json:"tags"`
+	Host    string   `json:"host,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+// Event is a recorded event
+type Event struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text,omitempty"`
+	AlertType      string   `json:"alert_type,omitempty"`
+	Priority       string   `json:"priority,omitempty"`
+	SourceTypeName string   `json:"source_type_name,omitempty"`
+	AggregationKey string   `json:"aggregation_key,omitempty"`
+	Tags           []string `json:"tags"`
+	Host           string   `json:"host,omitempty"`
+}
+
+// Recording holds the output of one or several check runs
+type Recording struct {
+	Version       int            `json:"version"`
+	Check         string         `json:"check"`
+	Metrics       []Metric       `json:"metrics"`
+	ServiceChecks []ServiceCheck `json:"service_checks"`
+	Events        []Event        `json:"events"`
+
+	// hosts matching the agent hostname are not recorded, so that golden
+	// files can be compared across machines
+	hostname string
+}
+
+// NewRecording returns an empty recording for a check. Samples sent with
+// the given default hostname are recorded without host.
+func NewRecording(checkName, hostname string) *Recording {
+	return &Recording{
+		Version:       formatVersion,
+		Check:         checkName,
+		Metrics:       []Metric{},
+		ServiceChecks: []ServiceCheck{},
+		Events:        []Event{},
+		hostname:      hostname,
+	}
+}
+
+// Load reads a recording from a golden file
+func Load(path string) (*Recording, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recording{}
+	if err := json.Unmarshal(content, r); err != nil {
+		return nil, fmt.Errorf("invalid golden file %s: %s", path, err)
+	}
+	if r.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported golden file version %d in %s, expected %d", r.Version, path, formatVersion)
+	}
+	return r, nil
+}
+
+// Save writes the recording to a golden file, samples are sorted so that
+// the file can be reviewed and versioned.
+func (r *Recording) Save(path string) error {
+	r.sort()
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(content, '\n'), 0644)
+}
+
+// AddSeries records series and sketches flushed from the aggregator
+func (r *Recording) AddSeries(series metrics.Series, sketches metrics.SketchSeriesList) {
+	for _, serie := range series {
+		m := Metric{
+			Name: serie.Name,
+			Type: serie.MType.String(),
+			Tags: normalizeTags(serie.Tags),
+			Host: r.host(serie.Host),
+		}
+		if len(serie.Points) > 0 {
+			m.Value = serie.Points[len(serie.Points)-1].Value
+		}
+		r.Metrics = append(r.Metrics, m)
+	}
+
+	for _, sketch := range sketches {
+		m := Metric{
+			Name: sketch.Name,
+			Type: "distribution",
+			Tags: normalizeTags(sketch.Tags),
+			Host: r.host(sketch.Host),
+		}
+		for _, p := range sketch.Points {
+			if p.Sketch == nil {
+				continue
+			}
+			m.Value += p.Sketch.Basic.Sum
+			m.Count += p.Sketch.Basic.Cnt
+		}
+		r.Metrics = append(r.Metrics, m)
+	}
+}
+
+// AddServiceChecks records service checks flushed from the aggregator
+func (r *Recording) AddServiceChecks(serviceChecks metrics.ServiceChecks) {
+	for _, sc := range serviceChecks {
+		r.ServiceChecks = append(r.ServiceChecks, ServiceCheck{
+			Name:    sc.CheckName,
+			Status:  sc.Status.String(),
+			Tags:    normalizeTags(sc.Tags),
+			Host:    r.host(sc.Host),
+			Message: sc.Message,
+		})
+	}
+}
+
+// AddEvents records events flushed from the aggregator
+func (r *Recording) AddEvents(events metrics.Events) {
+	for _, e := range events {
+		r.Events = append(r.Events, Event{
+			Title:          e.Title,
+			Text:           e.Text,
+			AlertType:      string(e.AlertType),
+			Priority:       string(e.Priority),
+			SourceTypeName: e.SourceTypeName,
+			AggregationKey: e.AggregationKey,
+			Tags:           normalizeTags(e.Tags),
+			Host:           r.host(e.Host),
+		})
+	}
+}
+
+func (r *Recording) host(host string) string {
+	if host == r.hostname {
+		return ""
+	}
+	return host
+}
+
+func (r *Recording) sort() {
+	sort.SliceStable(r.Metrics, func(i, j int) bool {
+		return metricKey(r.Metrics[i]) < metricKey(r.Metrics[j])
+	})
+	sort.SliceStable(r.ServiceChecks, func(i, j int) bool {
+		return serviceCheckKey(r.ServiceChecks[i]) < serviceCheckKey(r.ServiceChecks[j])
+	})
+	sort.SliceStable(r.Events, func(i, j int) bool {
+		return eventKey(r.Events[i]) < eventKey(r.Events[j])
+	})
+}
+
+// normalizeTags returns a sorted copy of tags, without duplicates
+func normalizeTags(tags []string) []string {
+	set := make(map[string]struct{}, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if _, found := set[t]; found {
+			continue
+		}
+		set[t] = struct{}{}
+		normalized = append(normalized, t)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func metricKey(m Metric) string {
+	return fmt.Sprintf("%s (%s) host:%q tags:[%s]", m.Name, m.Type, m.Host, strings.Join(m.Tags, ","))
+}
+
+func serviceCheckKey(sc ServiceCheck) string {
+	return fmt.Sprintf("%s host:%q tags:[%s]", sc.Name, sc.Host, strings.Join(sc.Tags, ","))
+}
+
+func eventKey(e Event) string {
+	return fmt.Sprintf("%q (%s, %s) host:%q tags:[%s]", e.Title, e.AlertType, e.SourceTypeName, e.Host, strings.Join(e.Tags, ","))
+}
@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package golden
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/quantile"
+)
+
+func buildRecording(gaugeValue float64, status metrics.ServiceCheckStatus) *Recording {
+	r := NewRecording("my_check", "myhost")
+	sketch := &quantile.Sketch{}
+	sketch.Basic.Cnt = 3
+	sketch.Basic.Sum = 12
+	r.AddSeries(
+		metrics.Series{
+			{Name: "my.gauge", MType: metrics.APIGaugeType, Tags: []string{"b:2", "a:1", "a:1"}, Host: "myhost", Points: []metrics.Point{{Ts: 10, Value: gaugeValue}}},
+			{Name: "my.rate", MType: metrics.APIRateType, Tags: []string{"a:1"}, Host: "otherhost", Points: []metrics.Point{{Ts: 10, Value: 2}}},
+		},
+		metrics.SketchSeriesList{
+			{Name: "my.distribution", Tags: []string{"a:1"}, Host: "myhost", Points: []metrics.SketchPoint{{Ts: 10, Sketch: sketch}}},
+		},
+	)
+	r.AddServiceChecks(metrics.ServiceChecks{
+		{CheckName: "my_check.can_connect", Status: status, Tags: []string{"a:1"}, Host: "myhost"},
+	})
+	r.AddEvents(metrics.Events{
+		{Title: "Something happened", Text: "details", AlertType: metrics.EventAlertTypeInfo, Tags: []string{"a:1"}, Host: "myhost"},
+	})
+	return r
+}
+
+func TestRecording(t *testing.T) {
+	r := buildRecording(1, metrics.ServiceCheckOK)
+
+	require.Len(t, r.Metrics, 3)
+	assert.Equal(t, Metric{Name: "my.gauge", Type: "gauge", Tags: []string{"a:1", "b:2"}, Value: 1}, r.Metrics[0])
+	assert.Equal(t, Metric{Name: "my.rate", Type: "rate", Tags: []string{"a:1"}, Host: "otherhost", Value: 2}, r.Metrics[1])
+	assert.Equal(t, Metric{Name: "my.distribution", Type: "distribution", Tags: []string{"a:1"}, Value: 12, Count: 3}, r.Metrics[2])
+
+	require.Len(t, r.ServiceChecks, 1)
+	assert.Equal(t, "OK", r.ServiceChecks[0].Status)
+	assert.Equal(t, "", r.ServiceChecks[0].Host)
+
+	require.Len(t, r.Events, 1)
+	assert.Equal(t, "info", r.Events[0].AlertType)
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golden")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "my_check.json")
+
+	r := buildRecording(1, metrics.ServiceCheckOK)
+	require.NoError(t, r.Save(path))
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "my_check", loaded.Check)
+	assert.Equal(t, formatVersion, loaded.Version)
+	// samples are sorted when saved
+	require.Len(t, loaded.Metrics, 3)
+	assert.Equal(t, "my.distribution", loaded.Metrics[0].Name)
+	assert.Empty(t, Compare(r, loaded, true))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"version": 42}`), 0644))
+	_, err = Load(path)
+	assert.Error(t, err)
+
+	_, err = Load(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	expected := buildRecording(1, metrics.ServiceCheckOK)
+
+	// values are ignored by default
+	actual := buildRecording(5, metrics.ServiceCheckCritical)
+	assert.Empty(t, Compare(expected, actual, false))
+
+	// but not in strict mode
+	diffs := Compare(expected, actual, true)
+	require.Len(t, diffs, 2)
+	assert.Equal(t, "metric", diffs[0].Kind)
+	assert.Equal(t, "expected value 1, got 5", diffs[0].Reason)
+	assert.Equal(t, "service check", diffs[1].Kind)
+	assert.Equal(t, "expected status OK, got CRITICAL", diffs[1].Reason)
+
+	// missing and unexpected contexts are always reported
+	actual = buildRecording(1, metrics.ServiceCheckOK)
+	actual.Metrics[0].Tags = []string{"a:1", "b:3"}
+	actual.Events = nil
+	diffs = Compare(expected, actual, false)
+	require.Len(t, diffs, 3)
+	assert.Equal(t, Difference{Kind: "metric", Key: `my.gauge (gauge) host:"" tags:[a:1,b:2]`, Reason: "missing"}, diffs[0])
+	assert.Equal(t, Difference{Kind: "metric", Key: `my.gauge (gauge) host:"" tags:[a:1,b:3]`, Reason: "unexpected"}, diffs[1])
+	assert.Equal(t, "event", diffs[2].Kind)
+	assert.Equal(t, "missing", diffs[2].Reason)
+
+	// the number of samples is only compared in strict mode
+	actual = buildRecording(1, metrics.ServiceCheckOK)
+	actual.AddServiceChecks(metrics.ServiceChecks{
+		{CheckName: "my_check.can_connect", Status: metrics.ServiceCheckOK, Tags: []string{"a:1"}, Host: "myhost"},
+	})
+	assert.Empty(t, Compare(expected, actual, false))
+	diffs = Compare(expected, actual, true)
+	require.Len(t, diffs, 1)
+	assert.Equal(t, "expected 1 sample(s), got 2", diffs[0].Reason)
+}
@@ -16,6 +16,8 @@ type Stats struct {
 	CheckVersion         string
 	CheckConfigSource    string
 	CheckID              ID
+	CheckSchedule        string // cron expression the check runs on, if any
+	CheckJitter          int64  // maximum random delay applied before each run, in seconds
 	TotalRuns            uint64
 	TotalErrors          uint64
 	TotalWarnings        uint64
@@ -36,12 +38,17 @@ type Stats struct {
 
 // NewStats returns a new check stats instance
 func NewStats(c Check) *Stats {
-	return &Stats{
+	stats := &Stats{
 		CheckID:           c.ID(),
 		CheckName:         c.String(),
 		CheckVersion:      c.Version(),
 		CheckConfigSource: c.ConfigSource(),
 	}
+	if sc, ok := c.(Scheduled); ok {
+		stats.CheckSchedule = sc.Schedule()
+		stats.CheckJitter = int64(sc.Jitter() / time.Second)
+	}
+	return stats
 }
 
 // Add tracks a new execution time
@@ -41,6 +41,8 @@ type CheckBase struct {
 	checkID        check.ID
 	latestWarnings []error
 	checkInterval  time.Duration
+	checkSchedule  string
+	checkJitter    time.Duration
 	source         string
 }
 
@@ -85,6 +87,12 @@ func (c *CheckBase) CommonConfigure(instance integration.Data, source string) er
 		c.checkInterval = time.Duration(commonOptions.MinCollectionInterval) * time.Second
 	}
 
+	// See if a cron schedule or a jitter was specified
+	c.checkSchedule = commonOptions.Schedule
+	if commonOptions.Jitter > 0 {
+		c.checkJitter = time.Duration(commonOptions.Jitter) * time.Second
+	}
+
 	// Disable default hostname if specified
 	if commonOptions.EmptyDefaultHostname {
 		s, err := aggregator.GetSender(c.checkID)
@@ -135,6 +143,17 @@ func (c *CheckBase) Interval() time.Duration {
 	return c.checkInterval
 }
 
+// Schedule returns the cron expression the check runs on, empty
+// if the check is scheduled every Interval().
+func (c *CheckBase) Schedule() string {
+	return c.checkSchedule
+}
+
+// Jitter returns the maximum random delay applied before each run.
+func (c *CheckBase) Jitter() time.Duration {
+	return c.checkJitter
+}
+
 // String returns the name of the check, the same for every instance
 func (c *CheckBase) String() string {
 	return c.checkName
@@ -0,0 +1,369 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build kubelet
+
+package containers
+
+import (
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	kubeletCheckName           = "kubelet_core"
+	defaultCadvisorMetricsPath = "/metrics/cadvisor"
+)
+
+var (
+	// prefixes of the metrics of the node system containers
+	systemContainerPrefixes = map[string]string{
+		"kubelet": "kubernetes.kubelet",
+		"runtime": "kubernetes.runtime",
+	}
+	// reasons reported by kubernetes.containers.state.waiting, the other ones
+	// are transient states not worth a metric
+	waitingReasons = map[string]bool{
+		"ErrImagePull":               true,
+		"ImagePullBackOff":           true,
+		"CrashLoopBackOff":           true,
+		"ContainerCreating":          true,
+		"CreateContainerError":       true,
+		"CreateContainerConfigError": true,
+		"InvalidImageName":           true,
+	}
+	// reasons reported by kubernetes.containers.state.terminated and
+	// kubernetes.containers.last_state.terminated
+	terminatedReasons = map[string]bool{
+		"OOMKilled":          true,
+		"ContainerCannotRun": true,
+		"Error":              true,
+	}
+)
+
+// kubeletClient is the subset of kubelet.KubeUtil used by the check
+type kubeletClient interface {
+	GetLocalPodList() ([]*kubelet.Pod, error)
+	GetStatsSummary() (*kubelet.Summary, error)
+	QueryKubelet(path string) ([]byte, int, error)
+}
+
+// For testing purpose
+var (
+	getKubeletClient = func() (kubeletClient, error) { return kubelet.GetKubeUtil() }
+	tagsForEntity    = tagger.Tag
+)
+
+// KubeletConfig holds the config of the check
+type KubeletConfig struct {
+	CollectCadvisorMetrics bool   `yaml:"collect_cadvisor_metrics"`
+	CadvisorMetricsPath    string `yaml:"cadvisor_metrics_path"`
+}
+
+// KubeletCheck grabs the node, pod and container metrics of the kubelet
+type KubeletCheck struct {
+	core.CheckBase
+	instance *KubeletConfig
+}
+
+func init() {
+	core.RegisterCheck(kubeletCheckName, KubeletFactory)
+}
+
+// KubeletFactory is exported for integration testing
+func KubeletFactory() check.Check {
+	return &KubeletCheck{
+		CheckBase: core.NewCheckBase(kubeletCheckName),
+		instance:  &KubeletConfig{},
+	}
+}
+
+// Parse parses the KubeletCheck config and set default values
+func (c *KubeletConfig) Parse(data []byte) error {
+	// default values
+	c.CollectCadvisorMetrics = false
+	c.CadvisorMetricsPath = defaultCadvisorMetricsPath
+
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Configure parses the check configuration and init the check
+func (c *KubeletCheck) Configure(config, initConfig integration.Data, source string) error {
+	err := c.CommonConfigure(config, source)
+	if err != nil {
+		return err
+	}
+
+	return c.instance.Parse(config)
+}
+
+// Run executes the check
+func (c *KubeletCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	ku, err := getKubeletClient()
+	if err != nil {
+		c.Warnf("Error initialising check: %s", err)
+		return err
+	}
+
+	pods, err := ku.GetLocalPodList()
+	if err != nil {
+		c.Warnf("Cannot get the pod list from the kubelet: %s", err)
+		return err
+	}
+	index := newPodIndex(pods)
+	c.processPodList(sender, pods)
+
+	// the cpu, memory and network usages of the stats summary are only
+	// reported if the cadvisor metrics are disabled or unavailable
+	collectedCadvisor := false
+	if c.instance.CollectCadvisorMetrics {
+		if err := c.processCadvisorMetrics(sender, ku, index); err != nil {
+			c.Warnf("Cannot get the cadvisor metrics, using the stats summary instead: %s", err)
+		} else {
+			collectedCadvisor = true
+		}
+	}
+
+	summary, err := ku.GetStatsSummary()
+	if err != nil {
+		c.Warnf("Cannot get the stats summary from the kubelet: %s", err)
+	} else {
+		c.processStatsSummary(sender, summary, index, !collectedCadvisor)
+	}
+
+	sender.Commit()
+	return err
+}
+
+// processPodList reports the running pods and containers, and the state of
+// the containers
+func (c *KubeletCheck) processPodList(sender aggregator.Sender, pods []*kubelet.Pod) {
+	runningPods := make(tagsCounter)
+	runningContainers := make(tagsCounter)
+
+	for _, pod := range pods {
+		podRunning := false
+		for _, container := range pod.Status.GetAllContainers() {
+			if container.IsPending() {
+				continue
+			}
+			entityID, err := kubelet.KubeContainerIDToTaggerEntityID(container.ID)
+			if err != nil {
+				log.Debugf("Skipping container %s of pod %s: %s", container.Name, pod.Metadata.Name, err)
+				continue
+			}
+			tags := entityTags(entityID)
+
+			sender.Gauge("kubernetes.containers.restarts", float64(container.RestartCount), "", tags)
+			submitContainerState(sender, "kubernetes.containers.state", container.State, tags)
+			if terminated := container.LastState.Terminated; terminated != nil && terminatedReasons[terminated.Reason] {
+				sender.Gauge("kubernetes.containers.last_state.terminated", 1, "", withReason(tags, terminated.Reason))
+			}
+
+			if container.State.Running != nil {
+				runningContainers.inc(tags)
+				podRunning = true
+			}
+		}
+		if podRunning {
+			runningPods.inc(entityTags(kubelet.PodUIDToTaggerEntityName(pod.Metadata.UID)))
+		}
+	}
+
+	runningPods.submit(sender, "kubernetes.pods.running")
+	runningContainers.submit(sender, "kubernetes.containers.running")
+}
+
+func submitContainerState(sender aggregator.Sender, prefix string, state kubelet.ContainerState, tags []string) {
+	if waiting := state.Waiting; waiting != nil && waitingReasons[waiting.Reason] {
+		sender.Gauge(prefix+".waiting", 1, "", withReason(tags, waiting.Reason))
+	}
+	if terminated := state.Terminated; terminated != nil && terminatedReasons[terminated.Reason] {
+		sender.Gauge(prefix+".terminated", 1, "", withReason(tags, terminated.Reason))
+	}
+}
+
+// processStatsSummary reports the usage of the system containers and the
+// ephemeral storage of the pods. The usage of the pod containers is reported
+// when withUsage is set.
+func (c *KubeletCheck) processStatsSummary(sender aggregator.Sender, summary *kubelet.Summary, index *podIndex, withUsage bool) {
+	for _, container := range summary.Node.SystemContainers {
+		prefix, found := systemContainerPrefixes[container.Name]
+		if !found {
+			continue
+		}
+		if container.CPU != nil {
+			submitIfSet(sender.Gauge, prefix+".cpu.usage", container.CPU.UsageNanoCores, nil)
+		}
+		if container.Memory != nil {
+			submitIfSet(sender.Gauge, prefix+".memory.rss", container.Memory.RSSBytes, nil)
+		}
+	}
+
+	for _, podStats := range summary.Pods {
+		pod := index.byUID[podStats.PodRef.UID]
+		if pod == nil {
+			// expired pods are filtered out of the pod list
+			continue
+		}
+		podTags := entityTags(kubelet.PodUIDToTaggerEntityName(pod.Metadata.UID))
+
+		if podStats.EphemeralStorage != nil {
+			submitIfSet(sender.Gauge, "kubernetes.ephemeral_storage.usage", podStats.EphemeralStorage.UsedBytes, podTags)
+		}
+		if !withUsage {
+			continue
+		}
+
+		if podStats.Network != nil {
+			interfaces := podStats.Network.Interfaces
+			if len(interfaces) == 0 {
+				interfaces = []kubelet.InterfaceStats{podStats.Network.InterfaceStats}
+			}
+			var rxBytes, txBytes, rxErrors, txErrors *uint64
+			for _, iface := range interfaces {
+				rxBytes = addIfSet(rxBytes, iface.RxBytes)
+				txBytes = addIfSet(txBytes, iface.TxBytes)
+				rxErrors = addIfSet(rxErrors, iface.RxErrors)
+				txErrors = addIfSet(txErrors, iface.TxErrors)
+			}
+			submitIfSet(sender.Rate, "kubernetes.network.rx_bytes", rxBytes, podTags)
+			submitIfSet(sender.Rate, "kubernetes.network.tx_bytes", txBytes, podTags)
+			submitIfSet(sender.Rate, "kubernetes.network.rx_errors", rxErrors, podTags)
+			submitIfSet(sender.Rate, "kubernetes.network.tx_errors", txErrors, podTags)
+		}
+
+		for _, containerStats := range podStats.Containers {
+			entityID := index.containerEntityID(pod, containerStats.Name)
+			if entityID == "" {
+				continue
+			}
+			tags := entityTags(entityID)
+			if containerStats.CPU != nil {
+				// sent as a rate, like the cadvisor metric it replaces
+				submitIfSet(sender.Rate, "kubernetes.cpu.usage.total", containerStats.CPU.UsageCoreNanoSeconds, tags)
+			}
+			if containerStats.Memory != nil {
+				submitIfSet(sender.Gauge, "kubernetes.memory.usage", containerStats.Memory.UsageBytes, tags)
+				submitIfSet(sender.Gauge, "kubernetes.memory.working_set", containerStats.Memory.WorkingSetBytes, tags)
+				submitIfSet(sender.Gauge, "kubernetes.memory.rss", containerStats.Memory.RSSBytes, tags)
+			}
+		}
+	}
+}
+
+// podIndex allows to find the pods of the stats summary and of the cadvisor
+// metrics in the pod list
+type podIndex struct {
+	byUID  map[string]*kubelet.Pod
+	byName map[string]*kubelet.Pod
+}
+
+func newPodIndex(pods []*kubelet.Pod) *podIndex {
+	index := &podIndex{
+		byUID:  make(map[string]*kubelet.Pod, len(pods)),
+		byName: make(map[string]*kubelet.Pod, len(pods)),
+	}
+	for _, pod := range pods {
+		index.byUID[pod.Metadata.UID] = pod
+		index.byName[pod.Metadata.Namespace+"/"+pod.Metadata.Name] = pod
+	}
+	return index
+}
+
+// containerEntityID returns the tagger entity of a container of a pod, or an
+// empty string if the container isn't found
+func (i *podIndex) containerEntityID(pod *kubelet.Pod, containerName string) string {
+	for _, container := range pod.Status.GetAllContainers() {
+		if container.Name != containerName || container.IsPending() {
+			continue
+		}
+		entityID, err := kubelet.KubeContainerIDToTaggerEntityID(container.ID)
+		if err != nil {
+			log.Debugf("Skipping container %s of pod %s: %s", containerName, pod.Metadata.Name, err)
+			return ""
+		}
+		return entityID
+	}
+	return ""
+}
+
+func entityTags(entityID string) []string {
+	tags, err := tagsForEntity(entityID, tagger.ChecksCardinality)
+	if err != nil {
+		log.Errorf("Could not collect tags for %s: %s", entityID, err)
+	}
+	return tags
+}
+
+// withReason returns a copy of the tags with the reason tag, to avoid
+// modifying the slice returned by the tagger
+func withReason(tags []string, reason string) []string {
+	reasonTags := make([]string, 0, len(tags)+1)
+	reasonTags = append(reasonTags, tags...)
+	return append(reasonTags, "reason:"+strings.ToLower(reason))
+}
+
+func submitIfSet(submit func(string, float64, string, []string), metric string, value *uint64, tags []string) {
+	if value != nil {
+		submit(metric, float64(*value), "", tags)
+	}
+}
+
+func addIfSet(sum, value *uint64) *uint64 {
+	if value == nil {
+		return sum
+	}
+	total := *value
+	if sum != nil {
+		total += *sum
+	}
+	return &total
+}
+
+// tagsCounter counts the pods or containers sharing the same tags
+type tagsCounter map[string]*taggedCount
+
+type taggedCount struct {
+	tags  []string
+	count float64
+}
+
+func (t tagsCounter) inc(tags []string) {
+	sorted := make([]string, len(tags))
+	copy(sorted, tags)
+	sort.Strings(sorted)
+	key := strings.Join(sorted, ",")
+
+	if entry, found := t[key]; found {
+		entry.count++
+		return
+	}
+	t[key] = &taggedCount{tags: tags, count: 1}
+}
+
+func (t tagsCounter) submit(sender aggregator.Sender, metric string) {
+	for _, entry := range t {
+		sender.Gauge(metric, entry.count, "", entry.tags)
+	}
+}
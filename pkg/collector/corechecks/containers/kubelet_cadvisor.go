@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build kubelet
+
+package containers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+// cadvisorMetric describes how a cadvisor metric is reported
+type cadvisorMetric struct {
+	name  string
+	rate  bool
+	scale float64
+}
+
+var (
+	// per container cadvisor metrics, the series of the same container (one
+	// per device for the io metrics) are summed
+	cadvisorContainerMetrics = map[string]cadvisorMetric{
+		"container_cpu_usage_seconds_total":         {name: "kubernetes.cpu.usage.total", rate: true, scale: 1e9},
+		"container_cpu_system_seconds_total":        {name: "kubernetes.cpu.system.total", rate: true, scale: 1e9},
+		"container_cpu_user_seconds_total":          {name: "kubernetes.cpu.user.total", rate: true, scale: 1e9},
+		"container_cpu_load_average_10s":            {name: "kubernetes.cpu.load.10s.avg", scale: 1},
+		"container_cpu_cfs_periods_total":           {name: "kubernetes.cpu.cfs.periods", rate: true, scale: 1},
+		"container_cpu_cfs_throttled_periods_total": {name: "kubernetes.cpu.cfs.throttled.periods", rate: true, scale: 1},
+		"container_cpu_cfs_throttled_seconds_total": {name: "kubernetes.cpu.cfs.throttled.seconds", rate: true, scale: 1},
+		"container_memory_usage_bytes":              {name: "kubernetes.memory.usage", scale: 1},
+		"container_memory_working_set_bytes":        {name: "kubernetes.memory.working_set", scale: 1},
+		"container_memory_rss":                      {name: "kubernetes.memory.rss", scale: 1},
+		"container_memory_cache":                    {name: "kubernetes.memory.cache", scale: 1},
+		"container_memory_swap":                     {name: "kubernetes.memory.swap", scale: 1},
+		"container_fs_reads_bytes_total":            {name: "kubernetes.io.read_bytes", rate: true, scale: 1},
+		"container_fs_writes_bytes_total":           {name: "kubernetes.io.write_bytes", rate: true, scale: 1},
+	}
+	// per pod cadvisor metrics, reported on the pod sandbox and summed over
+	// the interfaces
+	cadvisorPodMetrics = map[string]string{
+		"container_network_receive_bytes_total":            "kubernetes.network.rx_bytes",
+		"container_network_transmit_bytes_total":           "kubernetes.network.tx_bytes",
+		"container_network_receive_errors_total":           "kubernetes.network.rx_errors",
+		"container_network_transmit_errors_total":          "kubernetes.network.tx_errors",
+		"container_network_receive_packets_dropped_total":  "kubernetes.network.rx_dropped",
+		"container_network_transmit_packets_dropped_total": "kubernetes.network.tx_dropped",
+	}
+)
+
+// processCadvisorMetrics reports the container and pod metrics of the
+// cadvisor prometheus endpoint of the kubelet
+func (c *KubeletCheck) processCadvisorMetrics(sender aggregator.Sender, ku kubeletClient, index *podIndex) error {
+	data, code, err := ku.QueryKubelet(c.instance.CadvisorMetricsPath)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d on %s", code, c.instance.CadvisorMetricsPath)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	for name, family := range families {
+		if metric, found := cadvisorContainerMetrics[name]; found {
+			submitCadvisorContainerMetric(sender, metric, family, index)
+		} else if metricName, found := cadvisorPodMetrics[name]; found {
+			submitCadvisorPodMetric(sender, metricName, family, index)
+		}
+	}
+	return nil
+}
+
+func submitCadvisorContainerMetric(sender aggregator.Sender, metric cadvisorMetric, family *dto.MetricFamily, index *podIndex) {
+	values := make(map[string]float64)
+	for _, m := range family.GetMetric() {
+		containerName := labelValue(m, "container", "container_name")
+		if containerName == "" || containerName == "POD" {
+			// cgroup of the pod or of the pod sandbox
+			continue
+		}
+		pod := index.byName[labelValue(m, "namespace")+"/"+labelValue(m, "pod", "pod_name")]
+		if pod == nil {
+			continue
+		}
+		entityID := index.containerEntityID(pod, containerName)
+		if entityID == "" {
+			continue
+		}
+		values[entityID] += metricValue(family.GetType(), m)
+	}
+
+	for entityID, value := range values {
+		submit := sender.Gauge
+		if metric.rate {
+			submit = sender.Rate
+		}
+		submit(metric.name, value*metric.scale, "", entityTags(entityID))
+	}
+}
+
+func submitCadvisorPodMetric(sender aggregator.Sender, metricName string, family *dto.MetricFamily, index *podIndex) {
+	// the values by interface of every pod, depending on the version of
+	// kubernetes the series are reported with the POD container or with no
+	// container, only one of them is kept
+	values := make(map[string]map[string]float64)
+	for _, m := range family.GetMetric() {
+		containerName := labelValue(m, "container", "container_name")
+		if containerName != "" && containerName != "POD" {
+			continue
+		}
+		pod := index.byName[labelValue(m, "namespace")+"/"+labelValue(m, "pod", "pod_name")]
+		if pod == nil {
+			continue
+		}
+		if values[pod.Metadata.UID] == nil {
+			values[pod.Metadata.UID] = make(map[string]float64)
+		}
+		values[pod.Metadata.UID][labelValue(m, "interface")] = metricValue(family.GetType(), m)
+	}
+
+	for podUID, interfaces := range values {
+		var total float64
+		for _, value := range interfaces {
+			total += value
+		}
+		sender.Rate(metricName, total, "", entityTags(kubelet.PodUIDToTaggerEntityName(podUID)))
+	}
+}
+
+// labelValue returns the value of the first label found, cadvisor renamed
+// some of them in Kubernetes 1.16
+func labelValue(m *dto.Metric, names ...string) string {
+	for _, name := range names {
+		for _, label := range m.GetLabel() {
+			if label.GetName() == name && label.GetValue() != "" {
+				return label.GetValue()
+			}
+		}
+	}
+	return ""
+}
+
+func metricValue(metricType dto.MetricType, m *dto.Metric) float64 {
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue()
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue()
+	default:
+		return m.GetUntyped().GetValue()
+	}
+}
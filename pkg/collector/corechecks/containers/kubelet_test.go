@@ -0,0 +1,210 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build kubelet
+
+package containers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+// fakeKubelet serves the recorded responses of a kubelet
+type fakeKubelet struct {
+	podListPath  string
+	summaryPath  string
+	cadvisorPath string
+}
+
+func (f *fakeKubelet) GetLocalPodList() ([]*kubelet.Pod, error) {
+	var podList kubelet.PodList
+	if err := readJSONFixture(f.podListPath, &podList); err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
+
+func (f *fakeKubelet) GetStatsSummary() (*kubelet.Summary, error) {
+	if f.summaryPath == "" {
+		return nil, fmt.Errorf("unexpected status code 404 on /stats/summary")
+	}
+	var summary kubelet.Summary
+	if err := readJSONFixture(f.summaryPath, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+func (f *fakeKubelet) QueryKubelet(path string) ([]byte, int, error) {
+	if path != defaultCadvisorMetricsPath || f.cadvisorPath == "" {
+		return nil, http.StatusNotFound, nil
+	}
+	data, err := ioutil.ReadFile(f.cadvisorPath)
+	return data, http.StatusOK, err
+}
+
+func readJSONFixture(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+var testEntityTags = map[string][]string{
+	"kubernetes_pod_uid://8c1a6d2e-0f31-4b6e-9d2a-5e7f1c3b9a01": {"kube_namespace:default"},
+	"kubernetes_pod_uid://3b9e4f70-6a2c-4d18-8e53-0c2d7a9f4b02": {"kube_namespace:default"},
+	"kubernetes_pod_uid://d4f2a8c1-7e95-4b3a-a6c0-2f8e1d5b7c03": {"kube_namespace:batch"},
+	"container_id://1f0e5a2b7c3d":                               {"kube_container_name:nginx"},
+	"container_id://2a7d9c4e1b0f":                               {"kube_container_name:sidecar"},
+	"container_id://3c5b8e1f9a2d":                               {"kube_container_name:postgres"},
+	"container_id://4d2f6a9c3e8b":                               {"kube_container_name:report"},
+}
+
+func runKubeletCheck(t *testing.T, client *fakeKubelet, instance string) (*mocksender.MockSender, error) {
+	getKubeletClient = func() (kubeletClient, error) { return client, nil }
+	tagsForEntity = func(entity string, cardinality collectors.TagCardinality) ([]string, error) {
+		return testEntityTags[entity], nil
+	}
+	defer func() {
+		getKubeletClient = func() (kubeletClient, error) { return kubelet.GetKubeUtil() }
+		tagsForEntity = tagger.Tag
+	}()
+
+	kubeletCheck := KubeletFactory()
+	require.NoError(t, kubeletCheck.Configure([]byte(instance), nil, "test"))
+
+	mockSender := mocksender.NewMockSender(kubeletCheck.ID())
+	mockSender.SetupAcceptAll()
+	err := kubeletCheck.Run()
+	mockSender.AssertNumberOfCalls(t, "Commit", 1)
+	return mockSender, err
+}
+
+func TestKubeletConfigParse(t *testing.T) {
+	conf := &KubeletConfig{}
+	require.NoError(t, conf.Parse([]byte("")))
+	assert.False(t, conf.CollectCadvisorMetrics)
+	assert.Equal(t, "/metrics/cadvisor", conf.CadvisorMetricsPath)
+
+	require.NoError(t, conf.Parse([]byte("collect_cadvisor_metrics: true\ncadvisor_metrics_path: /metrics/resource")))
+	assert.True(t, conf.CollectCadvisorMetrics)
+	assert.Equal(t, "/metrics/resource", conf.CadvisorMetricsPath)
+}
+
+func TestKubeletCheckPodList(t *testing.T) {
+	mockSender, err := runKubeletCheck(t, &fakeKubelet{
+		podListPath: "testdata/kubelet/podlist.json",
+		summaryPath: "testdata/kubelet/summary.json",
+	}, "")
+	require.NoError(t, err)
+
+	// the two pods of the default namespace have running containers
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.pods.running", 2, "", []string{"kube_namespace:default"})
+	mockSender.AssertNotCalled(t, "Gauge", "kubernetes.pods.running", mock.Anything, "", []string{"kube_namespace:batch"})
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.containers.running", 1, "", []string{"kube_container_name:nginx"})
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.containers.running", 1, "", []string{"kube_container_name:postgres"})
+	mockSender.AssertNotCalled(t, "Gauge", "kubernetes.containers.running", mock.Anything, "", []string{"kube_container_name:sidecar"})
+
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.containers.restarts", 2, "", []string{"kube_container_name:nginx"})
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.containers.restarts", 5, "", []string{"kube_container_name:sidecar"})
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.containers.restarts", 0, "", []string{"kube_container_name:report"})
+
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.containers.state.waiting", 1, "", []string{"kube_container_name:sidecar", "reason:crashloopbackoff"})
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.containers.last_state.terminated", 1, "", []string{"kube_container_name:nginx", "reason:oomkilled"})
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.containers.last_state.terminated", 1, "", []string{"kube_container_name:sidecar", "reason:error"})
+	// Completed isn't a reason worth reporting
+	mockSender.AssertNotCalled(t, "Gauge", "kubernetes.containers.state.terminated", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestKubeletCheckStatsSummary(t *testing.T) {
+	mockSender, err := runKubeletCheck(t, &fakeKubelet{
+		podListPath: "testdata/kubelet/podlist.json",
+		summaryPath: "testdata/kubelet/summary.json",
+	}, "")
+	require.NoError(t, err)
+
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.kubelet.cpu.usage", 36402890, "", nil)
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.kubelet.memory.rss", 61865984, "", nil)
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.runtime.cpu.usage", 12108552, "", nil)
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.runtime.memory.rss", 47185920, "", nil)
+
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.ephemeral_storage.usage", 57344, "", []string{"kube_namespace:default"})
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.ephemeral_storage.usage", 12288, "", []string{"kube_namespace:default"})
+	// the evicted pod isn't in the pod list
+	mockSender.AssertNotCalled(t, "Gauge", "kubernetes.ephemeral_storage.usage", float64(4096), mock.Anything, mock.Anything)
+
+	// summed over the interfaces
+	mockSender.AssertMetric(t, "Rate", "kubernetes.network.rx_bytes", 1052672, "", []string{"kube_namespace:default"})
+	mockSender.AssertMetric(t, "Rate", "kubernetes.network.tx_errors", 1, "", []string{"kube_namespace:default"})
+	mockSender.AssertMetric(t, "Rate", "kubernetes.network.tx_bytes", 8388608, "", []string{"kube_namespace:default"})
+
+	mockSender.AssertMetric(t, "Rate", "kubernetes.cpu.usage.total", 4.12e9, "", []string{"kube_container_name:nginx"})
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.memory.usage", 15728640, "", []string{"kube_container_name:nginx"})
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.memory.working_set", 134217728, "", []string{"kube_container_name:postgres"})
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.memory.rss", 67108864, "", []string{"kube_container_name:postgres"})
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.memory.working_set", 0, "", []string{"kube_container_name:sidecar"})
+	mockSender.AssertNotCalled(t, "Rate", "kubernetes.cpu.usage.total", mock.Anything, "", []string{"kube_container_name:sidecar"})
+}
+
+func TestKubeletCheckCadvisor(t *testing.T) {
+	mockSender, err := runKubeletCheck(t, &fakeKubelet{
+		podListPath:  "testdata/kubelet/podlist.json",
+		summaryPath:  "testdata/kubelet/summary.json",
+		cadvisorPath: "testdata/kubelet/cadvisor.txt",
+	}, "collect_cadvisor_metrics: true")
+	require.NoError(t, err)
+
+	mockSender.AssertMetric(t, "Rate", "kubernetes.cpu.usage.total", 4.12e9, "", []string{"kube_container_name:nginx"})
+	mockSender.AssertMetric(t, "Rate", "kubernetes.cpu.usage.total", 2410e9, "", []string{"kube_container_name:postgres"})
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.memory.usage", 15728640, "", []string{"kube_container_name:nginx"})
+	// summed over the devices
+	mockSender.AssertMetric(t, "Rate", "kubernetes.io.read_bytes", 12288, "", []string{"kube_container_name:postgres"})
+	// summed over the interfaces, the series without container is a duplicate
+	mockSender.AssertMetric(t, "Rate", "kubernetes.network.rx_bytes", 1052672, "", []string{"kube_namespace:default"})
+	mockSender.AssertMetric(t, "Rate", "kubernetes.network.tx_bytes", 8388608, "", []string{"kube_namespace:default"})
+	// the pod and sandbox cgroups aren't containers
+	mockSender.AssertNumberOfCalls(t, "Rate", 5)
+
+	// the usages of the stats summary aren't reported twice
+	mockSender.AssertNotCalled(t, "Gauge", "kubernetes.cpu.usage.total", mock.Anything, mock.Anything, mock.Anything)
+	mockSender.AssertNotCalled(t, "Gauge", "kubernetes.memory.working_set", mock.Anything, mock.Anything, mock.Anything)
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.ephemeral_storage.usage", 57344, "", []string{"kube_namespace:default"})
+}
+
+func TestKubeletCheckCadvisorUnavailable(t *testing.T) {
+	mockSender, err := runKubeletCheck(t, &fakeKubelet{
+		podListPath: "testdata/kubelet/podlist.json",
+		summaryPath: "testdata/kubelet/summary.json",
+	}, "collect_cadvisor_metrics: true")
+	require.NoError(t, err)
+
+	// the usages are taken from the stats summary instead
+	mockSender.AssertMetric(t, "Rate", "kubernetes.cpu.usage.total", 4.12e9, "", []string{"kube_container_name:nginx"})
+	mockSender.AssertMetric(t, "Rate", "kubernetes.network.rx_bytes", 1052672, "", []string{"kube_namespace:default"})
+}
+
+func TestKubeletCheckSummaryUnavailable(t *testing.T) {
+	mockSender, err := runKubeletCheck(t, &fakeKubelet{
+		podListPath: "testdata/kubelet/podlist.json",
+	}, "")
+	require.Error(t, err)
+
+	// the metrics of the pod list are still reported
+	mockSender.AssertMetric(t, "Gauge", "kubernetes.pods.running", 2, "", []string{"kube_namespace:default"})
+}
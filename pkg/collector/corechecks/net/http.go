@@ -0,0 +1,384 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package net
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	httpCheckName = "http"
+
+	httpCanConnectServiceCheck = "http.can_connect"
+	httpSSLCertServiceCheck    = "http.ssl_cert"
+
+	defaultHTTPTimeout        = 10
+	defaultHTTPStatusCode     = `(1|2|3)\d\d`
+	defaultSSLDaysWarning     = 14
+	defaultSSLDaysCritical    = 7
+	maxHTTPContentMatchLength = 1024 * 1024 // only the first MB of the body is searched
+)
+
+// for testing purpose
+var timeNow = time.Now
+
+// HTTPCheck checks the availability of an HTTP endpoint, and the expiration
+// of its TLS certificate
+type HTTPCheck struct {
+	core.CheckBase
+	cfg    *httpInstanceConfig
+	client *http.Client
+	tags   []string
+}
+
+type httpInstanceConfig struct {
+	Name                       string            `yaml:"name"`
+	URL                        string            `yaml:"url"`
+	Method                     string            `yaml:"method"`
+	Headers                    map[string]string `yaml:"headers"`
+	Data                       string            `yaml:"data"`
+	Timeout                    int               `yaml:"timeout"`
+	HTTPResponseStatusCode     string            `yaml:"http_response_status_code"`
+	ContentMatch               string            `yaml:"content_match"`
+	ReverseContentMatch        bool              `yaml:"reverse_content_match"`
+	AllowRedirects             *bool             `yaml:"allow_redirects"`
+	TLSVerify                  *bool             `yaml:"tls_verify"`
+	TLSCACert                  string            `yaml:"tls_ca_cert"`
+	TLSCert                    string            `yaml:"tls_cert"`
+	TLSPrivateKey              string            `yaml:"tls_private_key"`
+	TLSServerName              string            `yaml:"tls_server_name"`
+	SkipProxy                  bool              `yaml:"skip_proxy"`
+	CheckCertificateExpiration *bool             `yaml:"check_certificate_expiration"`
+	DaysWarning                int               `yaml:"days_warning"`
+	DaysCritical               int               `yaml:"days_critical"`
+
+	statusCodeRegex   *regexp.Regexp
+	contentMatchRegex *regexp.Regexp
+}
+
+func (c *HTTPCheck) String() string {
+	return httpCheckName
+}
+
+func (c *httpInstanceConfig) parse(data []byte) error {
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return err
+	}
+
+	if c.URL == "" {
+		return errors.New("the url parameter is required")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %s", c.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid url %q: the scheme must be http or https", c.URL)
+	}
+
+	if c.Name == "" {
+		c.Name = c.URL
+	}
+	if c.Method == "" {
+		c.Method = http.MethodGet
+	}
+	c.Method = strings.ToUpper(c.Method)
+	if c.Timeout <= 0 {
+		c.Timeout = defaultHTTPTimeout
+	}
+	if c.HTTPResponseStatusCode == "" {
+		c.HTTPResponseStatusCode = defaultHTTPStatusCode
+	}
+	if c.statusCodeRegex, err = regexp.Compile("^(?:" + c.HTTPResponseStatusCode + ")$"); err != nil {
+		return fmt.Errorf("invalid http_response_status_code: %s", err)
+	}
+	if c.ContentMatch != "" {
+		if c.contentMatchRegex, err = regexp.Compile(c.ContentMatch); err != nil {
+			return fmt.Errorf("invalid content_match: %s", err)
+		}
+	}
+	if c.AllowRedirects == nil {
+		allow := true
+		c.AllowRedirects = &allow
+	}
+	if c.TLSVerify == nil {
+		verify := true
+		c.TLSVerify = &verify
+	}
+	if c.CheckCertificateExpiration == nil {
+		checkExpiration := true
+		c.CheckCertificateExpiration = &checkExpiration
+	}
+	if c.DaysWarning <= 0 {
+		c.DaysWarning = defaultSSLDaysWarning
+	}
+	if c.DaysCritical <= 0 {
+		c.DaysCritical = defaultSSLDaysCritical
+	}
+	if (c.TLSCert == "") != (c.TLSPrivateKey == "") {
+		return errors.New("tls_cert and tls_private_key must be set together")
+	}
+
+	return nil
+}
+
+// tlsConfig builds the TLS configuration of the requests
+func (c *httpInstanceConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: !*c.TLSVerify,
+		ServerName:         c.TLSServerName,
+	}
+
+	if c.TLSCACert != "" {
+		ca, err := ioutil.ReadFile(c.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read tls_ca_cert: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in tls_ca_cert %s", c.TLSCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// newClient builds an HTTP client honoring the proxy settings of the agent
+func (c *httpInstanceConfig) newClient() (*http.Client, error) {
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := httputils.CreateHTTPTransport()
+	transport.TLSClientConfig = tlsConfig
+	// every run should open a new connection to measure the actual response time
+	transport.DisableKeepAlives = true
+	if c.SkipProxy {
+		transport.Proxy = nil
+	}
+
+	client := &http.Client{
+		Timeout:   time.Duration(c.Timeout) * time.Second,
+		Transport: transport,
+	}
+	if !*c.AllowRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return client, nil
+}
+
+// Configure parses the check configuration and init the check
+func (c *HTTPCheck) Configure(data integration.Data, initConfig integration.Data, source string) error {
+	cfg := &httpInstanceConfig{}
+	if err := cfg.parse(data); err != nil {
+		log.Errorf("Error parsing configuration file: %s", err)
+		return err
+	}
+
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+
+	c.BuildID(data, initConfig)
+	c.cfg = cfg
+	c.client = client
+	c.tags = []string{"url:" + cfg.URL, "instance:" + cfg.Name}
+
+	return c.CommonConfigure(data, source)
+}
+
+// Run executes the check
+func (c *HTTPCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	resp, responseTime, err := c.doRequest()
+	status, message := c.checkResponse(resp, err)
+	if err == nil {
+		sender.Gauge("network.http.response_time", responseTime.Seconds(), "", c.tags)
+	}
+	if status == metrics.ServiceCheckOK {
+		sender.Gauge("network.http.can_connect", 1, "", c.tags)
+		sender.Gauge("network.http.cant_connect", 0, "", c.tags)
+	} else {
+		sender.Gauge("network.http.can_connect", 0, "", c.tags)
+		sender.Gauge("network.http.cant_connect", 1, "", c.tags)
+	}
+	sender.ServiceCheck(httpCanConnectServiceCheck, status, "", c.tags, message)
+
+	if strings.HasPrefix(c.cfg.URL, "https://") && *c.cfg.CheckCertificateExpiration {
+		c.checkCertificate(sender, resp)
+	}
+
+	sender.Commit()
+	return nil
+}
+
+func (c *HTTPCheck) doRequest() (*http.Response, time.Duration, error) {
+	req, err := http.NewRequest(c.cfg.Method, c.cfg.URL, strings.NewReader(c.cfg.Data))
+	if err != nil {
+		return nil, 0, err
+	}
+	for name, value := range c.cfg.Headers {
+		if strings.EqualFold(name, "host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	responseTime := time.Since(start)
+	return resp, responseTime, nil
+}
+
+// checkResponse returns the status and message of the can_connect service
+// check, and closes the response body
+func (c *HTTPCheck) checkResponse(resp *http.Response, err error) (metrics.ServiceCheckStatus, string) {
+	if err != nil {
+		return metrics.ServiceCheckCritical, fmt.Sprintf("Unable to reach %s: %s", c.cfg.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if !c.cfg.statusCodeRegex.MatchString(fmt.Sprintf("%d", resp.StatusCode)) {
+		return metrics.ServiceCheckCritical, fmt.Sprintf("Incorrect HTTP return code for url %s. Expected %s, got %d.", c.cfg.URL, c.cfg.HTTPResponseStatusCode, resp.StatusCode)
+	}
+
+	if c.cfg.contentMatchRegex == nil {
+		return metrics.ServiceCheckOK, ""
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxHTTPContentMatchLength))
+	if err != nil {
+		return metrics.ServiceCheckCritical, fmt.Sprintf("Unable to read the response of %s: %s", c.cfg.URL, err)
+	}
+	found := c.cfg.contentMatchRegex.Match(body)
+	switch {
+	case found && c.cfg.ReverseContentMatch:
+		return metrics.ServiceCheckCritical, fmt.Sprintf("Content %q found in the response", c.cfg.ContentMatch)
+	case !found && !c.cfg.ReverseContentMatch:
+		return metrics.ServiceCheckCritical, fmt.Sprintf("Content %q not found in the response", c.cfg.ContentMatch)
+	}
+	return metrics.ServiceCheckOK, ""
+}
+
+// checkCertificate reports the number of days left before the expiration
+// of the certificate of the endpoint
+func (c *HTTPCheck) checkCertificate(sender aggregator.Sender, resp *http.Response) {
+	var cert *x509.Certificate
+	if resp != nil && resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		cert = resp.TLS.PeerCertificates[0]
+	} else {
+		// the request failed, possibly because of an expired certificate:
+		// fetch the certificate without verifying it
+		var err error
+		cert, err = c.fetchCertificate()
+		if err != nil {
+			sender.ServiceCheck(httpSSLCertServiceCheck, metrics.ServiceCheckUnknown, "", c.tags, fmt.Sprintf("Unable to get the certificate: %s", err))
+			return
+		}
+	}
+
+	daysLeft := cert.NotAfter.Sub(timeNow()).Hours() / 24
+	sender.Gauge("http.ssl.days_left", daysLeft, "", c.tags)
+
+	status := metrics.ServiceCheckOK
+	message := ""
+	switch {
+	case daysLeft < 0:
+		status = metrics.ServiceCheckCritical
+		message = fmt.Sprintf("The certificate expired on %s", cert.NotAfter.UTC().Format(time.RFC3339))
+	case daysLeft < float64(c.cfg.DaysCritical):
+		status = metrics.ServiceCheckCritical
+		message = fmt.Sprintf("The certificate expires in %d days", int(math.Floor(daysLeft)))
+	case daysLeft < float64(c.cfg.DaysWarning):
+		status = metrics.ServiceCheckWarning
+		message = fmt.Sprintf("The certificate expires in %d days", int(math.Floor(daysLeft)))
+	}
+	sender.ServiceCheck(httpSSLCertServiceCheck, status, "", c.tags, message)
+}
+
+func (c *HTTPCheck) fetchCertificate() (*x509.Certificate, error) {
+	u, err := url.Parse(c.cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), "443")
+	}
+	serverName := c.cfg.TLSServerName
+	if serverName == "" {
+		serverName = u.Hostname()
+	}
+
+	dialer := &net.Dialer{Timeout: time.Duration(c.cfg.Timeout) * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         serverName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate presented by the server")
+	}
+	return certs[0], nil
+}
+
+func httpFactory() check.Check {
+	return &HTTPCheck{
+		CheckBase: core.NewCheckBase(httpCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(httpCheckName, httpFactory)
+}
@@ -0,0 +1,220 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package net
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func runHTTPCheck(t *testing.T, cfg string) *mocksender.MockSender {
+	httpCheck := httpFactory()
+	require.NoError(t, httpCheck.Configure([]byte(cfg), []byte(""), "test"))
+
+	mockSender := mocksender.NewMockSender(httpCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, httpCheck.Run())
+	mockSender.AssertNumberOfCalls(t, "Commit", 1)
+	return mockSender
+}
+
+func TestHTTPConfigParse(t *testing.T) {
+	cfg := &httpInstanceConfig{}
+	require.NoError(t, cfg.parse([]byte("url: http://localhost:8080/health")))
+	assert.Equal(t, "http://localhost:8080/health", cfg.Name)
+	assert.Equal(t, "GET", cfg.Method)
+	assert.Equal(t, defaultHTTPTimeout, cfg.Timeout)
+	assert.True(t, *cfg.AllowRedirects)
+	assert.True(t, *cfg.TLSVerify)
+	assert.True(t, *cfg.CheckCertificateExpiration)
+	assert.Equal(t, defaultSSLDaysWarning, cfg.DaysWarning)
+	assert.Equal(t, defaultSSLDaysCritical, cfg.DaysCritical)
+	assert.True(t, cfg.statusCodeRegex.MatchString("204"))
+	assert.False(t, cfg.statusCodeRegex.MatchString("404"))
+	assert.False(t, cfg.statusCodeRegex.MatchString("2000"))
+
+	for _, invalid := range []string{
+		"name: foo",
+		"url: ftp://localhost",
+		"url: localhost:8080",
+		"url: http://localhost\nhttp_response_status_code: '(2'",
+		"url: http://localhost\ncontent_match: '[a'",
+		"url: https://localhost\ntls_cert: /etc/cert.pem",
+	} {
+		cfg := &httpInstanceConfig{}
+		assert.Error(t, cfg.parse([]byte(invalid)), invalid)
+	}
+}
+
+func TestHTTPCheckOK(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "status: healthy")
+	}))
+	defer ts.Close()
+
+	cfg := fmt.Sprintf("url: %s\nname: test\ncontent_match: 'health[a-z]+'", ts.URL)
+	mockSender := runHTTPCheck(t, cfg)
+
+	tags := []string{"url:" + ts.URL, "instance:test"}
+	mockSender.AssertServiceCheck(t, httpCanConnectServiceCheck, metrics.ServiceCheckOK, "", tags, "")
+	mockSender.AssertMetric(t, "Gauge", "network.http.can_connect", 1, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "network.http.cant_connect", 0, "", tags)
+	mockSender.AssertMetricInRange(t, "Gauge", "network.http.response_time", 0, 10, "", tags)
+	mockSender.AssertNotCalled(t, "ServiceCheck", httpSSLCertServiceCheck, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHTTPCheckRequest(t *testing.T) {
+	var method, header, host, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		header = r.Header.Get("X-Custom")
+		host = r.Host
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer ts.Close()
+
+	cfg := fmt.Sprintf(`
+url: %s
+method: post
+data: '{"ping": true}'
+headers:
+  X-Custom: foo
+  Host: example.com
+`, ts.URL)
+	mockSender := runHTTPCheck(t, cfg)
+
+	mockSender.AssertServiceCheck(t, httpCanConnectServiceCheck, metrics.ServiceCheckOK, "", nil, "")
+	assert.Equal(t, "POST", method)
+	assert.Equal(t, "foo", header)
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, `{"ping": true}`, body)
+}
+
+func TestHTTPCheckStatusCode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	mockSender := runHTTPCheck(t, "url: "+ts.URL)
+	mockSender.AssertServiceCheck(t, httpCanConnectServiceCheck, metrics.ServiceCheckCritical, "", nil,
+		fmt.Sprintf(`Incorrect HTTP return code for url %s. Expected (1|2|3)\d\d, got 503.`, ts.URL))
+	mockSender.AssertMetric(t, "Gauge", "network.http.can_connect", 0, "", nil)
+	mockSender.AssertMetric(t, "Gauge", "network.http.cant_connect", 1, "", nil)
+
+	mockSender = runHTTPCheck(t, fmt.Sprintf("url: %s\nhttp_response_status_code: '503'", ts.URL))
+	mockSender.AssertServiceCheck(t, httpCanConnectServiceCheck, metrics.ServiceCheckOK, "", nil, "")
+}
+
+func TestHTTPCheckContentMatch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "status: degraded")
+	}))
+	defer ts.Close()
+
+	mockSender := runHTTPCheck(t, fmt.Sprintf("url: %s\ncontent_match: healthy", ts.URL))
+	mockSender.AssertServiceCheck(t, httpCanConnectServiceCheck, metrics.ServiceCheckCritical, "", nil, `Content "healthy" not found in the response`)
+
+	mockSender = runHTTPCheck(t, fmt.Sprintf("url: %s\ncontent_match: degraded\nreverse_content_match: true", ts.URL))
+	mockSender.AssertServiceCheck(t, httpCanConnectServiceCheck, metrics.ServiceCheckCritical, "", nil, `Content "degraded" found in the response`)
+
+	mockSender = runHTTPCheck(t, fmt.Sprintf("url: %s\ncontent_match: healthy\nreverse_content_match: true", ts.URL))
+	mockSender.AssertServiceCheck(t, httpCanConnectServiceCheck, metrics.ServiceCheckOK, "", nil, "")
+}
+
+func TestHTTPCheckRedirects(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+
+	cfg := fmt.Sprintf("url: %s/old\nhttp_response_status_code: '302'", ts.URL)
+	mockSender := runHTTPCheck(t, cfg)
+	mockSender.AssertServiceCheck(t, httpCanConnectServiceCheck, metrics.ServiceCheckCritical, "", nil,
+		fmt.Sprintf("Incorrect HTTP return code for url %s/old. Expected 302, got 200.", ts.URL))
+
+	mockSender = runHTTPCheck(t, cfg+"\nallow_redirects: false")
+	mockSender.AssertServiceCheck(t, httpCanConnectServiceCheck, metrics.ServiceCheckOK, "", nil, "")
+}
+
+func TestHTTPCheckUnreachable(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	addr := ts.URL
+	ts.Close()
+
+	mockSender := runHTTPCheck(t, "url: "+addr)
+	mockSender.AssertCalled(t, "ServiceCheck", httpCanConnectServiceCheck, metrics.ServiceCheckCritical, "", mock.Anything, mock.AnythingOfType("string"))
+	mockSender.AssertMetric(t, "Gauge", "network.http.can_connect", 0, "", nil)
+	mockSender.AssertNotCalled(t, "Gauge", "network.http.response_time", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHTTPCheckTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	// the certificate of the test server isn't trusted by default
+	mockSender := runHTTPCheck(t, "url: "+ts.URL)
+	mockSender.AssertCalled(t, "ServiceCheck", httpCanConnectServiceCheck, metrics.ServiceCheckCritical, "", mock.Anything, mock.AnythingOfType("string"))
+	// the certificate is still fetched to check its expiration
+	mockSender.AssertServiceCheck(t, httpSSLCertServiceCheck, metrics.ServiceCheckOK, "", nil, "")
+
+	mockSender = runHTTPCheck(t, fmt.Sprintf("url: %s\ntls_verify: false", ts.URL))
+	mockSender.AssertServiceCheck(t, httpCanConnectServiceCheck, metrics.ServiceCheckOK, "", nil, "")
+	mockSender.AssertServiceCheck(t, httpSSLCertServiceCheck, metrics.ServiceCheckOK, "", nil, "")
+
+	caFile, err := ioutil.TempFile("", "http-check-ca")
+	require.NoError(t, err)
+	defer os.Remove(caFile.Name())
+	require.NoError(t, pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}))
+	caFile.Close()
+
+	mockSender = runHTTPCheck(t, fmt.Sprintf("url: %s\ntls_ca_cert: %s", ts.URL, caFile.Name()))
+	mockSender.AssertServiceCheck(t, httpCanConnectServiceCheck, metrics.ServiceCheckOK, "", nil, "")
+
+	mockSender = runHTTPCheck(t, fmt.Sprintf("url: %s\ntls_verify: false\ncheck_certificate_expiration: false", ts.URL))
+	mockSender.AssertNotCalled(t, "ServiceCheck", httpSSLCertServiceCheck, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHTTPCheckCertificateExpiration(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	notAfter := ts.Certificate().NotAfter
+	defer func() { timeNow = time.Now }()
+
+	cfg := fmt.Sprintf("url: %s\ntls_verify: false\ndays_warning: 20\ndays_critical: 5", ts.URL)
+	for _, tc := range []struct {
+		daysLeft int
+		status   metrics.ServiceCheckStatus
+		message  string
+	}{
+		{30, metrics.ServiceCheckOK, ""},
+		{10, metrics.ServiceCheckWarning, "The certificate expires in 10 days"},
+		{3, metrics.ServiceCheckCritical, "The certificate expires in 3 days"},
+		{-1, metrics.ServiceCheckCritical, "The certificate expired on " + notAfter.UTC().Format(time.RFC3339)},
+	} {
+		timeNow = func() time.Time { return notAfter.Add(-time.Duration(tc.daysLeft) * 24 * time.Hour) }
+
+		mockSender := runHTTPCheck(t, cfg)
+		mockSender.AssertMetric(t, "Gauge", "http.ssl.days_left", float64(tc.daysLeft), "", nil)
+		mockSender.AssertServiceCheck(t, httpSSLCertServiceCheck, tc.status, "", nil, tc.message)
+	}
+}
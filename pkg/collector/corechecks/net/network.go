@@ -20,6 +20,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/shirou/gopsutil/net"
 	yaml "gopkg.in/yaml.v2"
@@ -72,12 +73,16 @@ var (
 // NetworkCheck represent a network check
 type NetworkCheck struct {
 	core.CheckBase
-	net    networkStats
-	config networkConfig
+	net      networkStats
+	config   networkConfig
+	procPath string
 }
 
 type networkInstanceConfig struct {
 	CollectConnectionState   bool     `yaml:"collect_connection_state"`
+	CollectConntrackMetrics  bool     `yaml:"collect_conntrack_metrics"`
+	CollectSoftnetMetrics    bool     `yaml:"collect_softnet_metrics"`
+	CollectInterfaceDrops    bool     `yaml:"collect_interface_drops"`
 	ExcludedInterfaces       []string `yaml:"excluded_interfaces"`
 	ExcludedInterfaceRe      string   `yaml:"excluded_interface_re"`
 	ExcludedInterfacePattern *regexp.Regexp
@@ -129,6 +134,9 @@ func (c *NetworkCheck) Run() error {
 	for _, interfaceIO := range ioByInterface {
 		if !c.isDeviceExcluded(interfaceIO.Name) {
 			submitInterfaceMetrics(sender, interfaceIO)
+			if c.config.instance.CollectInterfaceDrops {
+				submitInterfaceDropMetrics(sender, interfaceIO)
+			}
 		}
 	}
 
@@ -178,6 +186,13 @@ func (c *NetworkCheck) Run() error {
 		submitConnectionsMetrics(sender, "tcp6", tcpStateMetricsSuffixMapping, connectionsStats)
 	}
 
+	if c.config.instance.CollectConntrackMetrics {
+		c.collectConntrackMetrics(sender)
+	}
+	if c.config.instance.CollectSoftnetMetrics {
+		c.collectSoftnetMetrics(sender)
+	}
+
 	sender.Commit()
 	return nil
 }
@@ -204,6 +219,14 @@ func submitInterfaceMetrics(sender aggregator.Sender, interfaceIO net.IOCounters
 	sender.Rate("system.net.packets_out.error", float64(interfaceIO.Errout), "", tags)
 }
 
+func submitInterfaceDropMetrics(sender aggregator.Sender, interfaceIO net.IOCountersStat) {
+	tags := []string{fmt.Sprintf("device:%s", interfaceIO.Name)}
+	sender.Rate("system.net.packets_in.drop", float64(interfaceIO.Dropin), "", tags)
+	sender.Rate("system.net.packets_out.drop", float64(interfaceIO.Dropout), "", tags)
+	sender.Rate("system.net.packets_in.fifo", float64(interfaceIO.Fifoin), "", tags)
+	sender.Rate("system.net.packets_out.fifo", float64(interfaceIO.Fifoout), "", tags)
+}
+
 func submitProtocolMetrics(sender aggregator.Sender, protocolStats net.ProtoCountersStat) {
 	if protocolMapping, ok := protocolsMetricsMapping[protocolStats.Protocol]; ok {
 		for rawMetricName, metricName := range protocolMapping {
@@ -299,6 +322,11 @@ func (c *NetworkCheck) Configure(rawInstance integration.Data, rawInitConfig int
 		}
 	}
 
+	c.procPath = "/proc"
+	if config.Datadog.IsSet("procfs_path") {
+		c.procPath = config.Datadog.GetString("procfs_path")
+	}
+
 	return nil
 }
 
@@ -0,0 +1,184 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build linux
+
+package net
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	// columns of /proc/net/stat/nf_conntrack reported as rates, summed over
+	// all the CPUs. The `entries` column is the same for every CPU and is
+	// reported by nf_conntrack_count.
+	conntrackStatsMetricsMapping = map[string]string{
+		"searched":       "system.net.conntrack.searched",
+		"found":          "system.net.conntrack.found",
+		"new":            "system.net.conntrack.new",
+		"invalid":        "system.net.conntrack.invalid",
+		"ignore":         "system.net.conntrack.ignore",
+		"delete":         "system.net.conntrack.delete",
+		"delete_list":    "system.net.conntrack.delete_list",
+		"insert":         "system.net.conntrack.insert",
+		"insert_failed":  "system.net.conntrack.insert_failed",
+		"drop":           "system.net.conntrack.drop",
+		"early_drop":     "system.net.conntrack.early_drop",
+		"icmp_error":     "system.net.conntrack.icmp_error",
+		"expect_new":     "system.net.conntrack.expect_new",
+		"expect_create":  "system.net.conntrack.expect_create",
+		"expect_delete":  "system.net.conntrack.expect_delete",
+		"search_restart": "system.net.conntrack.search_restart",
+	}
+
+	// columns of /proc/net/softnet_stat, in order
+	softnetMetrics = []string{
+		"system.net.softnet.processed",
+		"system.net.softnet.dropped",
+		"system.net.softnet.times_squeezed",
+	}
+	// optional columns of /proc/net/softnet_stat, by index
+	softnetExtraMetrics = map[int]string{
+		9:  "system.net.softnet.received_rps",
+		10: "system.net.softnet.flow_limit_count",
+	}
+	// index of the column holding the CPU id, added in Linux 5.10
+	softnetCPUColumn = 12
+)
+
+// collectConntrackMetrics reports the usage of the connection tracking table
+// and the conntrack statistics. Nothing is reported when the nf_conntrack
+// module isn't loaded.
+func (c *NetworkCheck) collectConntrackMetrics(sender aggregator.Sender) {
+	for _, name := range []string{"count", "max"} {
+		path := filepath.Join(c.procPath, "sys", "net", "netfilter", "nf_conntrack_"+name)
+		value, err := readSingleValueFile(path)
+		if err != nil {
+			log.Debugf("Unable to read %s, is the nf_conntrack module loaded? %s", path, err)
+			return
+		}
+		sender.Gauge("system.net.conntrack."+name, value, "", nil)
+	}
+
+	path := filepath.Join(c.procPath, "net", "stat", "nf_conntrack")
+	stats, err := readConntrackStats(path)
+	if err != nil {
+		log.Debugf("Unable to read %s: %s", path, err)
+		return
+	}
+	for column, metricName := range conntrackStatsMetricsMapping {
+		if value, ok := stats[column]; ok {
+			sender.Rate(metricName, value, "", nil)
+		}
+	}
+}
+
+// collectSoftnetMetrics reports the packets processed and dropped by the
+// network softirq of every CPU
+func (c *NetworkCheck) collectSoftnetMetrics(sender aggregator.Sender) {
+	path := filepath.Join(c.procPath, "net", "softnet_stat")
+	stats, err := readSoftnetStats(path)
+	if err != nil {
+		log.Debugf("Unable to read %s: %s", path, err)
+		return
+	}
+	for cpu, values := range stats {
+		tags := []string{fmt.Sprintf("cpu:%d", cpu)}
+		for i, metricName := range softnetMetrics {
+			sender.Rate(metricName, values[i], "", tags)
+		}
+		for i, metricName := range softnetExtraMetrics {
+			if i < len(values) {
+				sender.Rate(metricName, values[i], "", tags)
+			}
+		}
+	}
+}
+
+func readSingleValueFile(path string) (float64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(content)), 64)
+}
+
+// readConntrackStats parses /proc/net/stat/nf_conntrack, which holds a header
+// line followed by one line of hexadecimal values per CPU, and returns the
+// sum of every column
+func readConntrackStats(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	columns := strings.Fields(scanner.Text())
+
+	stats := make(map[string]float64, len(columns))
+	for scanner.Scan() {
+		values := strings.Fields(scanner.Text())
+		if len(values) != len(columns) {
+			return nil, fmt.Errorf("%s is not formatted correctly, expected %d columns, got %d", path, len(columns), len(values))
+		}
+		for i, column := range columns {
+			value, err := strconv.ParseUint(values[i], 16, 64)
+			if err != nil {
+				return nil, err
+			}
+			stats[column] += float64(value)
+		}
+	}
+	return stats, scanner.Err()
+}
+
+// readSoftnetStats parses /proc/net/softnet_stat, which holds one line of
+// hexadecimal values per online CPU, and returns the values by CPU
+func readSoftnetStats(path string) (map[int][]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stats := make(map[int][]float64)
+	scanner := bufio.NewScanner(f)
+	for line := 0; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < len(softnetMetrics) {
+			return nil, fmt.Errorf("%s is not formatted correctly, expected at least %d columns, got %d", path, len(softnetMetrics), len(fields))
+		}
+		values := make([]float64, len(fields))
+		for i, field := range fields {
+			value, err := strconv.ParseUint(field, 16, 64)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = float64(value)
+		}
+		// offline CPUs are skipped, the line number only matches the CPU id
+		// on older kernels not reporting it
+		cpu := line
+		if len(values) > softnetCPUColumn {
+			cpu = int(values[softnetCPUColumn])
+		}
+		stats[cpu] = values
+	}
+	return stats, scanner.Err()
+}
@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build linux
+
+package net
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+)
+
+func runNetworkCheckWithProcfs(t *testing.T, procPath string, rawInstanceConfig []byte) *mocksender.MockSender {
+	networkCheck := NetworkCheck{
+		net: &fakeNetworkStats{
+			counterStats: []net.IOCountersStat{
+				{
+					Name:    "eth0",
+					Dropin:  40,
+					Dropout: 41,
+					Fifoin:  42,
+					Fifoout: 43,
+				},
+			},
+		},
+	}
+	err := networkCheck.Configure(rawInstanceConfig, []byte(``), "test")
+	assert.Nil(t, err)
+	networkCheck.procPath = procPath
+
+	mockSender := mocksender.NewMockSender(networkCheck.ID())
+	mockSender.SetupAcceptAll()
+
+	err = networkCheck.Run()
+	assert.Nil(t, err)
+	return mockSender
+}
+
+func TestNetworkCheckExtendedGroupsDisabled(t *testing.T) {
+	mockSender := runNetworkCheckWithProcfs(t, "testfiles/proc", []byte(``))
+
+	mockSender.AssertNotCalled(t, "Rate", "system.net.packets_in.drop", mock.Anything, mock.Anything, mock.Anything)
+	mockSender.AssertNotCalled(t, "Gauge", "system.net.conntrack.count", mock.Anything, mock.Anything, mock.Anything)
+	mockSender.AssertNotCalled(t, "Rate", "system.net.softnet.dropped", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNetworkCheckInterfaceDrops(t *testing.T) {
+	mockSender := runNetworkCheckWithProcfs(t, "testfiles/proc", []byte(`
+collect_interface_drops: true
+`))
+
+	eth0Tags := []string{"device:eth0"}
+	mockSender.AssertCalled(t, "Rate", "system.net.packets_in.drop", float64(40), "", eth0Tags)
+	mockSender.AssertCalled(t, "Rate", "system.net.packets_out.drop", float64(41), "", eth0Tags)
+	mockSender.AssertCalled(t, "Rate", "system.net.packets_in.fifo", float64(42), "", eth0Tags)
+	mockSender.AssertCalled(t, "Rate", "system.net.packets_out.fifo", float64(43), "", eth0Tags)
+}
+
+func TestNetworkCheckConntrack(t *testing.T) {
+	mockSender := runNetworkCheckWithProcfs(t, "testfiles/proc", []byte(`
+collect_conntrack_metrics: true
+`))
+
+	var customTags []string
+	mockSender.AssertCalled(t, "Gauge", "system.net.conntrack.count", float64(65123), "", customTags)
+	mockSender.AssertCalled(t, "Gauge", "system.net.conntrack.max", float64(262144), "", customTags)
+	// summed over the CPUs
+	mockSender.AssertCalled(t, "Rate", "system.net.conntrack.invalid", float64(64), "", customTags)
+	mockSender.AssertCalled(t, "Rate", "system.net.conntrack.ignore", float64(48412), "", customTags)
+	mockSender.AssertCalled(t, "Rate", "system.net.conntrack.insert_failed", float64(4), "", customTags)
+	mockSender.AssertCalled(t, "Rate", "system.net.conntrack.drop", float64(15), "", customTags)
+	mockSender.AssertCalled(t, "Rate", "system.net.conntrack.search_restart", float64(18), "", customTags)
+	mockSender.AssertNotCalled(t, "Rate", "system.net.conntrack.entries", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNetworkCheckConntrackNotLoaded(t *testing.T) {
+	mockSender := runNetworkCheckWithProcfs(t, "testfiles/missing", []byte(`
+collect_conntrack_metrics: true
+collect_softnet_metrics: true
+`))
+
+	mockSender.AssertNotCalled(t, "Gauge", "system.net.conntrack.count", mock.Anything, mock.Anything, mock.Anything)
+	mockSender.AssertNotCalled(t, "Rate", "system.net.softnet.dropped", mock.Anything, mock.Anything, mock.Anything)
+	mockSender.AssertCalled(t, "Commit")
+}
+
+func TestNetworkCheckSoftnet(t *testing.T) {
+	mockSender := runNetworkCheckWithProcfs(t, "testfiles/proc", []byte(`
+collect_softnet_metrics: true
+`))
+
+	cpu0Tags := []string{"cpu:0"}
+	mockSender.AssertCalled(t, "Rate", "system.net.softnet.processed", float64(7580955), "", cpu0Tags)
+	mockSender.AssertCalled(t, "Rate", "system.net.softnet.dropped", float64(0), "", cpu0Tags)
+	mockSender.AssertCalled(t, "Rate", "system.net.softnet.times_squeezed", float64(44), "", cpu0Tags)
+
+	cpu1Tags := []string{"cpu:1"}
+	mockSender.AssertCalled(t, "Rate", "system.net.softnet.processed", float64(10667249), "", cpu1Tags)
+	mockSender.AssertCalled(t, "Rate", "system.net.softnet.dropped", float64(18), "", cpu1Tags)
+	mockSender.AssertCalled(t, "Rate", "system.net.softnet.times_squeezed", float64(500), "", cpu1Tags)
+	mockSender.AssertCalled(t, "Rate", "system.net.softnet.received_rps", float64(7), "", cpu1Tags)
+
+	// CPU 2 is offline, the last line belongs to CPU 3
+	cpu3Tags := []string{"cpu:3"}
+	mockSender.AssertCalled(t, "Rate", "system.net.softnet.dropped", float64(3), "", cpu3Tags)
+	mockSender.AssertCalled(t, "Rate", "system.net.softnet.flow_limit_count", float64(2), "", cpu3Tags)
+	mockSender.AssertNotCalled(t, "Rate", "system.net.softnet.dropped", mock.Anything, mock.Anything, []string{"cpu:2"})
+}
+
+func TestReadSoftnetStatsOldKernel(t *testing.T) {
+	// kernels older than 5.10 don't report the CPU id: the line number is used
+	stats, err := readSoftnetStats("testfiles/proc/net/softnet_stat_legacy")
+	assert.Nil(t, err)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, float64(5), stats[1][1])
+}
@@ -9,18 +9,23 @@ package system
 import (
 	"regexp"
 	"strings"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
 const (
 	diskCheckName = "disk"
 	diskMetric    = "system.disk.%s"
 	inodeMetric   = "system.fs.inodes.%s"
+
+	diskReadWriteServiceCheck = "disk.read_write"
+	defaultDiskTimeout        = 5 * time.Second
 )
 
 type diskConfig struct {
@@ -32,6 +37,11 @@ type diskConfig struct {
 	excludedMountpointRe *regexp.Regexp
 	allPartitions        bool
 	deviceTagRe          map[*regexp.Regexp][]string
+	serviceCheckRw       bool
+	tagByLabel           bool
+	tagByUUID            bool
+	tagByDeviceMapper    bool
+	timeout              time.Duration
 }
 
 func (c *DiskCheck) excludeDisk(mountpoint, device, fstype string) bool {
@@ -80,7 +90,7 @@ func (c *DiskCheck) excludeDisk(mountpoint, device, fstype string) bool {
 
 func (c *DiskCheck) instanceConfigure(data integration.Data) error {
 	conf := make(map[interface{}]interface{})
-	c.cfg = &diskConfig{}
+	c.cfg = &diskConfig{timeout: defaultDiskTimeout}
 	err := yaml.Unmarshal([]byte(data), &conf)
 	if err != nil {
 		return err
@@ -130,6 +140,31 @@ func (c *DiskCheck) instanceConfigure(data integration.Data) error {
 		c.cfg.allPartitions = allPartitions
 	}
 
+	serviceCheckRw, found := conf["service_check_rw"]
+	if serviceCheckRw, ok := serviceCheckRw.(bool); found && ok {
+		c.cfg.serviceCheckRw = serviceCheckRw
+	}
+
+	tagByLabel, found := conf["tag_by_label"]
+	if tagByLabel, ok := tagByLabel.(bool); found && ok {
+		c.cfg.tagByLabel = tagByLabel
+	}
+
+	tagByUUID, found := conf["tag_by_uuid"]
+	if tagByUUID, ok := tagByUUID.(bool); found && ok {
+		c.cfg.tagByUUID = tagByUUID
+	}
+
+	tagByDeviceMapper, found := conf["tag_by_device_mapper"]
+	if tagByDeviceMapper, ok := tagByDeviceMapper.(bool); found && ok {
+		c.cfg.tagByDeviceMapper = tagByDeviceMapper
+	}
+
+	timeout, found := conf["timeout"]
+	if timeout, ok := timeout.(int); found && ok && timeout > 0 {
+		c.cfg.timeout = time.Duration(timeout) * time.Second
+	}
+
 	deviceTagRe, found := conf["device_tag_re"]
 	if deviceTagRe, ok := deviceTagRe.(map[interface{}]interface{}); found && ok {
 		c.cfg.deviceTagRe = make(map[*regexp.Regexp][]string)
@@ -149,6 +184,20 @@ func (c *DiskCheck) instanceConfigure(data integration.Data) error {
 	return nil
 }
 
+// readWriteStatus returns the status of the disk.read_write service check
+// from the mount options of a partition
+func readWriteStatus(opts string) metrics.ServiceCheckStatus {
+	for _, opt := range strings.Split(opts, ",") {
+		switch opt {
+		case "rw":
+			return metrics.ServiceCheckOK
+		case "ro":
+			return metrics.ServiceCheckCritical
+		}
+	}
+	return metrics.ServiceCheckUnknown
+}
+
 func stringSliceContain(slice []string, x string) bool {
 	for _, e := range slice {
 		if e == x {
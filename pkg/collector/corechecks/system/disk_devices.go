@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build !windows
+
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// For testing purpose
+var (
+	devDiskPath  = "/dev/disk"
+	sysBlockPath = "/sys/block"
+)
+
+// deviceTagger provides the label, UUID and device-mapper tags of the block
+// devices, read from /dev/disk/by-{label,uuid} and /sys/block. These
+// locations only exist on Linux, no tags are added on other platforms.
+type deviceTagger struct {
+	// tags by kernel name of the device (e.g. sda1, dm-0)
+	tags map[string][]string
+}
+
+func newDeviceTagger(byLabel, byUUID, byDeviceMapper bool) *deviceTagger {
+	t := &deviceTagger{tags: make(map[string][]string)}
+	if byLabel {
+		t.addLinks("by-label", "label")
+	}
+	if byUUID {
+		t.addLinks("by-uuid", "uuid")
+	}
+	if byDeviceMapper {
+		t.addDeviceMapperNames()
+	}
+	return t
+}
+
+// addLinks reads the symlinks of a /dev/disk sub-directory, which are named
+// after an attribute of the device they point to
+func (t *deviceTagger) addLinks(dir, tagName string) {
+	linksDir := filepath.Join(devDiskPath, dir)
+	links, err := ioutil.ReadDir(linksDir)
+	if err != nil {
+		log.Debugf("Unable to list %s: %s", linksDir, err)
+		return
+	}
+	for _, link := range links {
+		target, err := os.Readlink(filepath.Join(linksDir, link.Name()))
+		if err != nil {
+			continue
+		}
+		kname := filepath.Base(target)
+		t.tags[kname] = append(t.tags[kname], tagName+":"+unescapeDeviceLink(link.Name()))
+	}
+}
+
+// addDeviceMapperNames reads the names of the device-mapper devices, such as
+// LVM logical volumes or LUKS volumes
+func (t *deviceTagger) addDeviceMapperNames() {
+	devices, err := filepath.Glob(filepath.Join(sysBlockPath, "dm-*"))
+	if err != nil {
+		return
+	}
+	for _, device := range devices {
+		name, err := ioutil.ReadFile(filepath.Join(device, "dm", "name"))
+		if err != nil {
+			log.Debugf("Unable to read the device-mapper name of %s: %s", device, err)
+			continue
+		}
+		kname := filepath.Base(device)
+		t.tags[kname] = append(t.tags[kname], "device_mapper:"+strings.TrimSpace(string(name)))
+	}
+}
+
+// deviceTags returns the tags of a device, such as /dev/sda1 or
+// /dev/mapper/vg-root which is a symlink to /dev/dm-0
+func (t *deviceTagger) deviceTags(device string) []string {
+	if len(t.tags) == 0 || !strings.HasPrefix(device, "/dev/") {
+		return nil
+	}
+	kname := filepath.Base(device)
+	if target, err := os.Readlink(device); err == nil {
+		kname = filepath.Base(target)
+	}
+	return t.tags[kname]
+}
+
+// unescapeDeviceLink decodes the \xHH sequences udev uses to escape the
+// characters of labels not allowed in file names, such as spaces
+func unescapeDeviceLink(name string) string {
+	if !strings.Contains(name, `\x`) {
+		return name
+	}
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) && name[i+1] == 'x' {
+			if c, err := strconv.ParseUint(name[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
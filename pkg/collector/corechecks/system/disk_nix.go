@@ -9,6 +9,8 @@ package system
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/shirou/gopsutil/disk"
 
@@ -28,6 +30,10 @@ var (
 type DiskCheck struct {
 	core.CheckBase
 	cfg *diskConfig
+
+	// mount points whose usage query didn't return yet
+	pendingMountpoints     map[string]struct{}
+	pendingMountpointsLock sync.Mutex
 }
 
 // Run executes the check
@@ -56,23 +62,13 @@ func (c *DiskCheck) collectPartitionMetrics(sender aggregator.Sender) error {
 		return err
 	}
 
+	devices := newDeviceTagger(c.cfg.tagByLabel, c.cfg.tagByUUID, c.cfg.tagByDeviceMapper)
+
 	for _, partition := range partitions {
 		if c.excludeDisk(partition.Mountpoint, partition.Device, partition.Fstype) {
 			continue
 		}
 
-		// Get disk metrics here to be able to exclude on total usage
-		usage, err := diskUsage(partition.Mountpoint)
-		if err != nil {
-			log.Warnf("Unable to get disk metrics of %s mount point: %s", partition.Mountpoint, err)
-			continue
-		}
-
-		// Exclude disks with total disk size 0
-		if usage.Total == 0 {
-			continue
-		}
-
 		tags := make([]string, 0, 2)
 
 		if c.cfg.tagByFilesystem {
@@ -87,6 +83,23 @@ func (c *DiskCheck) collectPartitionMetrics(sender aggregator.Sender) error {
 		tags = append(tags, fmt.Sprintf("device:%s", deviceName))
 
 		tags = c.applyDeviceTags(partition.Device, partition.Mountpoint, tags)
+		tags = append(tags, devices.deviceTags(partition.Device)...)
+
+		// Get disk metrics here to be able to exclude on total usage
+		usage, err := c.usageWithTimeout(partition.Mountpoint)
+		if err != nil {
+			log.Warnf("Unable to get disk metrics of %s mount point: %s", partition.Mountpoint, err)
+			continue
+		}
+
+		// Exclude disks with total disk size 0
+		if usage.Total == 0 {
+			continue
+		}
+
+		if c.cfg.serviceCheckRw {
+			sender.ServiceCheck(diskReadWriteServiceCheck, readWriteStatus(partition.Opts), "", tags, "")
+		}
 
 		c.sendPartitionMetrics(sender, usage, tags)
 	}
@@ -94,6 +107,45 @@ func (c *DiskCheck) collectPartitionMetrics(sender aggregator.Sender) error {
 	return nil
 }
 
+// usageWithTimeout gets the usage of a mount point, giving up after the
+// configured timeout so that a hung mount, such as an unreachable NFS share,
+// doesn't block the check. The mount point is skipped until the hung query
+// returns.
+func (c *DiskCheck) usageWithTimeout(mountpoint string) (*disk.UsageStat, error) {
+	c.pendingMountpointsLock.Lock()
+	if c.pendingMountpoints == nil {
+		c.pendingMountpoints = make(map[string]struct{})
+	}
+	if _, pending := c.pendingMountpoints[mountpoint]; pending {
+		c.pendingMountpointsLock.Unlock()
+		return nil, fmt.Errorf("a previous query is still pending")
+	}
+	c.pendingMountpoints[mountpoint] = struct{}{}
+	c.pendingMountpointsLock.Unlock()
+
+	type usageResult struct {
+		usage *disk.UsageStat
+		err   error
+	}
+	// buffered so that the goroutine can exit once the query returns, even
+	// after the timeout
+	result := make(chan usageResult, 1)
+	go func() {
+		usage, err := diskUsage(mountpoint)
+		c.pendingMountpointsLock.Lock()
+		delete(c.pendingMountpoints, mountpoint)
+		c.pendingMountpointsLock.Unlock()
+		result <- usageResult{usage, err}
+	}()
+
+	select {
+	case r := <-result:
+		return r.usage, r.err
+	case <-time.After(c.cfg.timeout):
+		return nil, fmt.Errorf("timed out after %s", c.cfg.timeout)
+	}
+}
+
 func (c *DiskCheck) collectDiskMetrics(sender aggregator.Sender) error {
 	iomap, err := ioCounters()
 	if err != nil {
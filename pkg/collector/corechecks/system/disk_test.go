@@ -9,10 +9,13 @@ package system
 import (
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/shirou/gopsutil/disk"
+	"github.com/stretchr/testify/assert"
 )
 
 var (
@@ -228,3 +231,116 @@ func TestDiskCheckTags(t *testing.T) {
 	mock.AssertNumberOfCalls(t, "Rate", expectedRates)
 	mock.AssertNumberOfCalls(t, "Commit", 1)
 }
+
+func TestDiskCheckReadWriteServiceCheck(t *testing.T) {
+	diskPartitions = func(all bool) ([]disk.PartitionStat, error) {
+		return []disk.PartitionStat{
+			{Device: "/dev/sda2", Mountpoint: "/", Fstype: "ext4", Opts: "rw,relatime,errors=remount-ro"},
+			{Device: "/dev/sda1", Mountpoint: "/boot/efi", Fstype: "vfat", Opts: "ro,relatime"},
+		}, nil
+	}
+	defer func() { diskPartitions = diskSampler }()
+	diskUsage = diskUsageSampler
+	ioCounters = diskIoSampler
+	diskCheck := new(DiskCheck)
+	diskCheck.Configure([]byte("service_check_rw: true"), nil, "test")
+
+	mock := mocksender.NewMockSender(diskCheck.ID())
+	mock.SetupAcceptAll()
+
+	diskCheck.Run()
+	mock.AssertServiceCheck(t, "disk.read_write", metrics.ServiceCheckOK, "", []string{"device:/dev/sda2"}, "")
+	mock.AssertServiceCheck(t, "disk.read_write", metrics.ServiceCheckCritical, "", []string{"device:/dev/sda1"}, "")
+	mock.AssertNumberOfCalls(t, "ServiceCheck", 2)
+}
+
+func TestReadWriteStatus(t *testing.T) {
+	assert.Equal(t, metrics.ServiceCheckOK, readWriteStatus("rw,noatime"))
+	assert.Equal(t, metrics.ServiceCheckCritical, readWriteStatus("relatime,ro"))
+	// errors=remount-ro is not the current state of the mount
+	assert.Equal(t, metrics.ServiceCheckUnknown, readWriteStatus("relatime,errors=remount-ro"))
+}
+
+func TestDiskCheckDeviceTags(t *testing.T) {
+	devDiskPath = "testfiles/disk/dev/disk"
+	sysBlockPath = "testfiles/disk/sys/block"
+	defer func() {
+		devDiskPath = "/dev/disk"
+		sysBlockPath = "/sys/block"
+	}()
+	diskPartitions = func(all bool) ([]disk.PartitionStat, error) {
+		return []disk.PartitionStat{
+			{Device: "/dev/sda2", Mountpoint: "/", Fstype: "ext4", Opts: "rw"},
+			{Device: "/dev/dm-0", Mountpoint: "/data", Fstype: "xfs", Opts: "rw"},
+		}, nil
+	}
+	defer func() { diskPartitions = diskSampler }()
+	diskUsage = func(mountpoint string) (*disk.UsageStat, error) {
+		return diskUsageSamples["/"], nil
+	}
+	defer func() { diskUsage = diskUsageSampler }()
+	ioCounters = diskIoSampler
+	diskCheck := new(DiskCheck)
+	diskCheck.Configure([]byte("tag_by_label: true\ntag_by_uuid: true\ntag_by_device_mapper: true"), nil, "test")
+
+	mock := mocksender.NewMockSender(diskCheck.ID())
+	mock.SetupAcceptAll()
+
+	diskCheck.Run()
+	mock.AssertMetric(t, "Gauge", "system.disk.total", 50825728.0, "", []string{"device:/dev/sda2", "label:root fs", "uuid:2f5a3c1e-8b7d-4e4a-9c61-0d2b7e9f1a45"})
+	mock.AssertMetric(t, "Gauge", "system.disk.total", 50825728.0, "", []string{"device:/dev/dm-0", "label:data", "device_mapper:vg0-data"})
+	mock.AssertMetricNotTaggedWith(t, "Gauge", "system.disk.total", []string{"device:/dev/dm-0", "uuid:2f5a3c1e-8b7d-4e4a-9c61-0d2b7e9f1a45"})
+}
+
+func TestDiskCheckTimeout(t *testing.T) {
+	release := make(chan struct{})
+	diskPartitions = diskSampler
+	diskUsage = func(mountpoint string) (*disk.UsageStat, error) {
+		if mountpoint == "/" {
+			// hung mount
+			<-release
+		}
+		return diskUsageSamples[mountpoint], nil
+	}
+	defer func() { diskUsage = diskUsageSampler }()
+	ioCounters = diskIoSampler
+	diskCheck := new(DiskCheck)
+	diskCheck.Configure(nil, nil, "test")
+	diskCheck.cfg.timeout = 10 * time.Millisecond
+
+	mock := mocksender.NewMockSender(diskCheck.ID())
+	mock.SetupAcceptAll()
+
+	// the hung mount point is skipped, the others are still reported
+	diskCheck.Run()
+	mock.AssertMetric(t, "Gauge", "system.disk.total", 523248.0, "", []string{"device:/dev/sda1"})
+	mock.AssertMetricNotTaggedWith(t, "Gauge", "system.disk.total", []string{"device:/dev/sda2"})
+
+	// while the query is still pending, no new one is started
+	start := time.Now()
+	diskCheck.Run()
+	assert.True(t, time.Since(start) < diskCheck.cfg.timeout)
+	mock.AssertMetricNotTaggedWith(t, "Gauge", "system.disk.total", []string{"device:/dev/sda2"})
+
+	close(release)
+	for i := 0; i < 100 && diskCheck.hasPendingMountpoints(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	diskCheck.Run()
+	mock.AssertMetric(t, "Gauge", "system.disk.total", 50825728.0, "", []string{"device:/dev/sda2"})
+	mock.AssertNumberOfCalls(t, "Commit", 3)
+}
+
+func (c *DiskCheck) hasPendingMountpoints() bool {
+	c.pendingMountpointsLock.Lock()
+	defer c.pendingMountpointsLock.Unlock()
+	return len(c.pendingMountpoints) > 0
+}
+
+func TestUnescapeDeviceLink(t *testing.T) {
+	assert.Equal(t, "root fs", unescapeDeviceLink(`root\x20fs`))
+	assert.Equal(t, "EFI", unescapeDeviceLink("EFI"))
+	assert.Equal(t, `bad\x2`, unescapeDeviceLink(`bad\x2`))
+	assert.Equal(t, `bad\xzz`, unescapeDeviceLink(`bad\xzz`))
+}
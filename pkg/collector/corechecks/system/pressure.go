@@ -0,0 +1,217 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build linux
+
+package system
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const pressureCheckName = "pressure"
+
+// resources reported by the pressure stall information
+var psiResources = []string{"cpu", "memory", "io"}
+
+// vmstatMetrics maps the /proc/vmstat counters to the metrics they are
+// reported as. The counters split by memory zone are summed.
+var vmstatMetrics = map[string]string{
+	"pgfault":            "system.vmstat.pgfault",
+	"pgmajfault":         "system.vmstat.pgmajfault",
+	"pswpin":             "system.vmstat.pswpin",
+	"pswpout":            "system.vmstat.pswpout",
+	"oom_kill":           "system.vmstat.oom_kill",
+	"compact_stall":      "system.vmstat.compact_stall",
+	"compact_fail":       "system.vmstat.compact_fail",
+	"compact_success":    "system.vmstat.compact_success",
+	"allocstall":         "system.vmstat.allocstall",
+	"allocstall_dma":     "system.vmstat.allocstall",
+	"allocstall_dma32":   "system.vmstat.allocstall",
+	"allocstall_normal":  "system.vmstat.allocstall",
+	"allocstall_movable": "system.vmstat.allocstall",
+}
+
+// For testing purpose
+var scrapeCgroups = metrics.ScrapeAllCgroups
+
+type pressureConfig struct {
+	CollectContainers *bool `yaml:"collect_containers"`
+}
+
+// PressureCheck reports the pressure stall information (PSI) of the host and
+// of the containers, and the memory contention counters of /proc/vmstat
+type PressureCheck struct {
+	core.CheckBase
+	procPath          string
+	collectContainers bool
+	missingPSI        bool
+}
+
+// Run executes the check
+func (c *PressureCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	c.collectHostPSI(sender)
+	if err := c.collectVMStat(sender); err != nil {
+		log.Warnf("system.PressureCheck: could not read vmstat: %s", err)
+	}
+	if c.collectContainers {
+		c.collectContainerPSI(sender)
+	}
+
+	sender.Commit()
+	return nil
+}
+
+func (c *PressureCheck) collectHostPSI(sender aggregator.Sender) {
+	for _, resource := range psiResources {
+		psiFile := filepath.Join(c.procPath, "pressure", resource)
+		stats, err := metrics.ReadPSIFile(psiFile)
+		if os.IsNotExist(err) {
+			// PSI requires Linux 4.20 and can be disabled at boot time
+			if !c.missingPSI {
+				log.Infof("system.PressureCheck: %s is missing, pressure stall information is not available", psiFile)
+				c.missingPSI = true
+			}
+			continue
+		} else if err != nil {
+			log.Warnf("system.PressureCheck: could not read %s: %s", psiFile, err)
+			continue
+		}
+		submitPSI(sender, "system.pressure."+resource, stats, nil)
+	}
+}
+
+func (c *PressureCheck) collectVMStat(sender aggregator.Sender) error {
+	f, err := os.Open(filepath.Join(c.procPath, "vmstat"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	values := make(map[string]float64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		metric, found := vmstatMetrics[fields[0]]
+		if !found {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			log.Debugf("system.PressureCheck: invalid vmstat value for %s: %s", fields[0], err)
+			continue
+		}
+		values[metric] += float64(v)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for metric, value := range values {
+		sender.Rate(metric, value, "", nil)
+	}
+	return nil
+}
+
+func (c *PressureCheck) collectContainerPSI(sender aggregator.Sender) {
+	cgroups, err := scrapeCgroups()
+	if err != nil {
+		log.Debugf("system.PressureCheck: could not list the container cgroups: %s", err)
+		return
+	}
+
+	for containerID, cgroup := range cgroups {
+		var tags []string
+		for _, resource := range psiResources {
+			stats, err := cgroup.PSI(resource)
+			if err != nil {
+				log.Debugf("system.PressureCheck: could not get the %s pressure of container %s: %s", resource, containerID, err)
+				continue
+			}
+			if stats == nil {
+				continue
+			}
+			if tags == nil {
+				tags, err = tagger.Tag(containers.BuildTaggerEntityName(containerID), collectors.HighCardinality)
+				if err != nil {
+					log.Errorf("Could not collect tags for container %s: %s", containerID, err)
+				}
+				tags = append(tags, "container_id:"+containerID)
+			}
+			submitPSI(sender, "container.pressure."+resource, stats, tags)
+		}
+	}
+}
+
+// submitPSI reports the averages of the pressure stall information as
+// gauges, and the total stall time in microseconds as a rate
+func submitPSI(sender aggregator.Sender, prefix string, stats *metrics.PSIStats, tags []string) {
+	submitPSILine(sender, prefix+".some", stats.Some, tags)
+	if stats.Full != nil {
+		submitPSILine(sender, prefix+".full", *stats.Full, tags)
+	}
+}
+
+func submitPSILine(sender aggregator.Sender, prefix string, line metrics.PSILine, tags []string) {
+	sender.Gauge(prefix+".avg10", line.Avg10, "", tags)
+	sender.Gauge(prefix+".avg60", line.Avg60, "", tags)
+	sender.Gauge(prefix+".avg300", line.Avg300, "", tags)
+	sender.Rate(prefix+".total", float64(line.Total), "", tags)
+}
+
+// Configure the pressure check
+func (c *PressureCheck) Configure(data integration.Data, initConfig integration.Data, source string) error {
+	err := c.CommonConfigure(data, source)
+	if err != nil {
+		return err
+	}
+
+	conf := pressureConfig{}
+	if err := yaml.Unmarshal(data, &conf); err != nil {
+		return err
+	}
+	c.collectContainers = conf.CollectContainers == nil || *conf.CollectContainers
+
+	c.procPath = "/proc"
+	if config.Datadog.IsSet("procfs_path") {
+		c.procPath = config.Datadog.GetString("procfs_path")
+	}
+	return nil
+}
+
+func pressureFactory() check.Check {
+	return &PressureCheck{
+		CheckBase: core.NewCheckBase(pressureCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(pressureCheckName, pressureFactory)
+}
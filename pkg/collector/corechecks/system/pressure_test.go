@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build linux
+
+package system
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
+)
+
+const pressureContainerID = "3a9f1c2b"
+
+func testScrapeCgroups() (map[string]*metrics.ContainerCgroup, error) {
+	return map[string]*metrics.ContainerCgroup{
+		pressureContainerID: {
+			ContainerID: pressureContainerID,
+			Mounts: map[string]string{
+				"memory": "testfiles/pressure/cgroup/memory",
+				"cpu":    "testfiles/pressure/cgroup/cpu",
+			},
+			Paths: map[string]string{
+				"memory": "/docker/" + pressureContainerID,
+				"cpu":    "/docker/" + pressureContainerID,
+			},
+		},
+	}, nil
+}
+
+func runPressureCheck(t *testing.T, procPath string, instance string) *mocksender.MockSender {
+	config.Datadog.Set("procfs_path", procPath)
+	defer config.Datadog.Set("procfs_path", "/proc")
+	scrapeCgroups = testScrapeCgroups
+	defer func() { scrapeCgroups = metrics.ScrapeAllCgroups }()
+
+	pressureCheck := pressureFactory()
+	require.NoError(t, pressureCheck.Configure([]byte(instance), nil, "test"))
+
+	mockSender := mocksender.NewMockSender(pressureCheck.ID())
+	mockSender.SetupAcceptAll()
+	require.NoError(t, pressureCheck.Run())
+	mockSender.AssertNumberOfCalls(t, "Commit", 1)
+	return mockSender
+}
+
+func TestPressureCheckHost(t *testing.T) {
+	mockSender := runPressureCheck(t, "testfiles/pressure/proc", "")
+
+	mockSender.AssertMetric(t, "Gauge", "system.pressure.cpu.some.avg60", 0.1, "", nil)
+	mockSender.AssertMetric(t, "Rate", "system.pressure.cpu.some.total", 42010, "", nil)
+	mockSender.AssertMetric(t, "Gauge", "system.pressure.memory.some.avg10", 12.5, "", nil)
+	mockSender.AssertMetric(t, "Gauge", "system.pressure.memory.some.avg300", 2.1, "", nil)
+	mockSender.AssertMetric(t, "Gauge", "system.pressure.memory.full.avg60", 2.5, "", nil)
+	mockSender.AssertMetric(t, "Rate", "system.pressure.memory.full.total", 456789, "", nil)
+	mockSender.AssertMetric(t, "Gauge", "system.pressure.io.full.avg10", 0.8, "", nil)
+	mockSender.AssertMetric(t, "Rate", "system.pressure.io.some.total", 120000, "", nil)
+
+	mockSender.AssertMetric(t, "Rate", "system.vmstat.pgfault", 152348601, "", nil)
+	mockSender.AssertMetric(t, "Rate", "system.vmstat.pgmajfault", 4521, "", nil)
+	mockSender.AssertMetric(t, "Rate", "system.vmstat.pswpin", 120, "", nil)
+	mockSender.AssertMetric(t, "Rate", "system.vmstat.pswpout", 340, "", nil)
+	mockSender.AssertMetric(t, "Rate", "system.vmstat.oom_kill", 2, "", nil)
+	mockSender.AssertMetric(t, "Rate", "system.vmstat.compact_stall", 25, "", nil)
+	mockSender.AssertMetric(t, "Rate", "system.vmstat.compact_fail", 7, "", nil)
+	mockSender.AssertMetric(t, "Rate", "system.vmstat.compact_success", 18, "", nil)
+	// sum of the allocstall counters of every zone
+	mockSender.AssertMetric(t, "Rate", "system.vmstat.allocstall", 25, "", nil)
+	mockSender.AssertNotCalled(t, "Rate", "system.vmstat.pgpgin", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPressureCheckContainers(t *testing.T) {
+	mockSender := runPressureCheck(t, "testfiles/pressure/proc", "")
+
+	tags := []string{"container_id:" + pressureContainerID}
+	mockSender.AssertMetric(t, "Gauge", "container.pressure.memory.some.avg10", 30, "", tags)
+	mockSender.AssertMetric(t, "Rate", "container.pressure.memory.some.total", 5000000, "", tags)
+	mockSender.AssertMetric(t, "Gauge", "container.pressure.memory.full.avg300", 5, "", tags)
+	// no cpu.pressure file in the fixtures, and no blkio hierarchy
+	mockSender.AssertNotCalled(t, "Gauge", "container.pressure.cpu.some.avg10", mock.Anything, mock.Anything, mock.Anything)
+	mockSender.AssertNotCalled(t, "Gauge", "container.pressure.io.some.avg10", mock.Anything, mock.Anything, mock.Anything)
+
+	mockSender = runPressureCheck(t, "testfiles/pressure/proc", "collect_containers: false")
+	mockSender.AssertNotCalled(t, "Gauge", "container.pressure.memory.some.avg10", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPressureCheckNoPSI(t *testing.T) {
+	// kernels older than 4.20 don't expose /proc/pressure
+	mockSender := runPressureCheck(t, "testfiles/pressure/missing", "collect_containers: false")
+
+	mockSender.AssertNotCalled(t, "Gauge", "system.pressure.memory.some.avg10", mock.Anything, mock.Anything, mock.Anything)
+	mockSender.AssertNotCalled(t, "Rate", "system.vmstat.pgfault", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -42,6 +42,8 @@ type PythonCheck struct {
 	class        *C.rtloader_pyobject_t
 	ModuleName   string
 	interval     time.Duration
+	schedule     string
+	jitter       time.Duration
 	lastWarnings []error
 	source       string
 }
@@ -176,6 +178,12 @@ func (c *PythonCheck) Configure(data integration.Data, initConfig integration.Da
 		c.interval = time.Duration(commonOptions.MinCollectionInterval) * time.Second
 	}
 
+	// See if a cron schedule or a jitter was specified
+	c.schedule = commonOptions.Schedule
+	if commonOptions.Jitter > 0 {
+		c.jitter = time.Duration(commonOptions.Jitter) * time.Second
+	}
+
 	// Disable default hostname if specified
 	if commonOptions.EmptyDefaultHostname {
 		s, err := aggregator.GetSender(c.id)
@@ -242,6 +250,16 @@ func (c *PythonCheck) Interval() time.Duration {
 	return c.interval
 }
 
+// Schedule returns the cron expression the check runs on, if any
+func (c *PythonCheck) Schedule() string {
+	return c.schedule
+}
+
+// Jitter returns the maximum random delay applied before each run
+func (c *PythonCheck) Jitter() time.Duration {
+	return c.jitter
+}
+
 // ID returns the ID of the check
 func (c *PythonCheck) ID() check.ID {
 	return c.id
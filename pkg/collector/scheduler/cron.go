@@ -0,0 +1,212 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed cron expression. Every field is stored as a
+// bitset where bit N is set if the value N matches. Schedules are always
+// evaluated in UTC.
+type cronSchedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// whether the day fields are unrestricted, following the usual cron
+	// semantics: when both the day of month and the day of week are
+	// restricted, a day matches if either of them matches.
+	domStar bool
+	dowStar bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDom    = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// parseCronSchedule parses a standard 5-field cron expression
+// (minute, hour, day of month, month, day of week) or one of the
+// `@hourly`, `@daily`, `@weekly`, `@monthly` and `@yearly` descriptors.
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if d, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = d
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, found %d", expr, len(fields))
+	}
+
+	s := &cronSchedule{
+		domStar: fields[2] == "*" || fields[2] == "?",
+		dowStar: fields[4] == "*" || fields[4] == "?",
+	}
+	var err error
+	if s.minute, err = cronMinute.parse(fields[0]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %s", expr, err)
+	}
+	if s.hour, err = cronHour.parse(fields[1]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %s", expr, err)
+	}
+	if s.dom, err = cronDom.parse(fields[2]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %s", expr, err)
+	}
+	if s.month, err = cronMonth.parse(fields[3]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %s", expr, err)
+	}
+	if s.dow, err = cronDow.parse(fields[4]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %s", expr, err)
+	}
+	// both 0 and 7 mean Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	return s, nil
+}
+
+// parse returns the bitset matching a comma-separated list of values,
+// ranges and steps for this field.
+func (f cronField) parse(value string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(value, ",") {
+		b, err := f.parsePart(part)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+func (f cronField) parsePart(part string) (uint64, error) {
+	rangePart, step := part, 1
+	if i := strings.Index(part, "/"); i >= 0 {
+		var err error
+		rangePart = part[:i]
+		step, err = strconv.Atoi(part[i+1:])
+		if err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step %q in %s field", part[i+1:], f.name)
+		}
+	}
+
+	var low, high int
+	switch {
+	case rangePart == "*" || rangePart == "?":
+		low, high = f.min, f.max
+	case strings.Contains(rangePart, "-"):
+		bounds := strings.SplitN(rangePart, "-", 2)
+		var err error
+		if low, err = f.value(bounds[0]); err != nil {
+			return 0, err
+		}
+		if high, err = f.value(bounds[1]); err != nil {
+			return 0, err
+		}
+		if low > high {
+			return 0, fmt.Errorf("invalid range %q in %s field", rangePart, f.name)
+		}
+	default:
+		v, err := f.value(rangePart)
+		if err != nil {
+			return 0, err
+		}
+		low, high = v, v
+		// `N/step` means every step starting at N
+		if step > 1 {
+			high = f.max
+		}
+	}
+
+	var bits uint64
+	for i := low; i <= high; i += step {
+		bits |= 1 << uint(i)
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", s, f.name)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d-%d] in %s field", v, f.min, f.max, f.name)
+	}
+	return v, nil
+}
+
+// next returns the first time strictly after t matching the schedule,
+// or the zero time if no such time exists in the next five years.
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronScheduleErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@every 5m",
+	} {
+		_, err := parseCronSchedule(expr)
+		assert.NotNil(t, err, "expression %q should be invalid", expr)
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	from := time.Date(2019, 7, 10, 14, 37, 12, 0, time.UTC) // a Wednesday
+
+	for _, tc := range []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2019, 7, 10, 14, 38, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2019, 7, 10, 14, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2019, 7, 11, 2, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2019, 7, 10, 15, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2019, 7, 11, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2019, 7, 14, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 9-17/4 * * mon-fri", time.Date(2019, 7, 10, 17, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2019, 7, 14, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"10,20 3 * * *", time.Date(2019, 7, 11, 3, 10, 0, 0, time.UTC)},
+		// both day fields restricted: either of them matches
+		{"0 0 1 * fri", time.Date(2019, 7, 12, 0, 0, 0, 0, time.UTC)},
+	} {
+		s, err := parseCronSchedule(tc.expr)
+		require.Nil(t, err, "expression %q should be valid", tc.expr)
+		assert.Equal(t, tc.expected, s.next(from), "unexpected next run for %q", tc.expr)
+	}
+}
+
+func TestCronScheduleNextIsUTC(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	s, err := parseCronSchedule("0 2 * * *")
+	require.Nil(t, err)
+
+	next := s.next(time.Date(2019, 7, 10, 3, 0, 0, 0, loc))
+	assert.Equal(t, time.Date(2019, 7, 10, 2, 0, 0, 0, time.UTC), next)
+}
+
+func TestCronScheduleNeverMatches(t *testing.T) {
+	s, err := parseCronSchedule("0 0 30 2 *")
+	require.Nil(t, err)
+	assert.True(t, s.next(time.Now()).IsZero())
+}
@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/status/health"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// cronJob is a check scheduled with a cron expression
+type cronJob struct {
+	check    check.Check
+	expr     string
+	schedule *cronSchedule
+	next     time.Time
+}
+
+// cronQueue contains the checks (called jobs) scheduled with a cron
+// expression instead of an interval. The queue ticks every second and
+// sends every job whose next run time is due.
+type cronQueue struct {
+	jobs    map[check.ID]*cronJob
+	stop    chan bool    // to stop this queue
+	stopped chan bool    // signals that this queue has stopped
+	ticker  *time.Ticker // running while the queue runs
+	running bool
+	health  *health.Handle
+	mu      sync.RWMutex // to protect critical sections in struct's fields
+}
+
+// newCronQueue creates a new cronQueue instance
+func newCronQueue() *cronQueue {
+	return &cronQueue{
+		jobs:    make(map[check.ID]*cronJob),
+		stop:    make(chan bool),
+		stopped: make(chan bool),
+		health:  health.Register("collector-cron-queue"),
+	}
+}
+
+// addJob adds a check to the queue, its first run is computed from now
+func (cq *cronQueue) addJob(c check.Check, expr string, schedule *cronSchedule) {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+
+	cq.jobs[c.ID()] = &cronJob{
+		check:    c,
+		expr:     expr,
+		schedule: schedule,
+		next:     schedule.next(time.Now()),
+	}
+}
+
+func (cq *cronQueue) removeJob(id check.ID) error {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+
+	if _, found := cq.jobs[id]; !found {
+		return fmt.Errorf("check with id %s is not in the Cron Queue", id)
+	}
+	delete(cq.jobs, id)
+	return nil
+}
+
+func (cq *cronQueue) hasJob(id check.ID) bool {
+	cq.mu.RLock()
+	defer cq.mu.RUnlock()
+
+	_, found := cq.jobs[id]
+	return found
+}
+
+func (cq *cronQueue) stats() map[string]interface{} {
+	cq.mu.RLock()
+	defer cq.mu.RUnlock()
+
+	jobs := make([]map[string]interface{}, 0, len(cq.jobs))
+	for id, job := range cq.jobs {
+		jobs = append(jobs, map[string]interface{}{
+			"CheckID":  string(id),
+			"Schedule": job.expr,
+			"NextRun":  job.next.Unix(),
+		})
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i]["CheckID"].(string) < jobs[j]["CheckID"].(string)
+	})
+
+	return map[string]interface{}{
+		"Schedule": "cron",
+		"Size":     len(cq.jobs),
+		"Jobs":     jobs,
+	}
+}
+
+// dueJobs returns the checks that need to run at t and computes
+// their next run time.
+func (cq *cronQueue) dueJobs(t time.Time) []check.Check {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+
+	var due []check.Check
+	for _, job := range cq.jobs {
+		if job.next.IsZero() || job.next.After(t) {
+			continue
+		}
+		due = append(due, job.check)
+		job.next = job.schedule.next(t)
+	}
+	return due
+}
+
+// run schedules the checks in the queue by posting them to the
+// execution pipeline.
+// Not blocking, runs in a new goroutine.
+func (cq *cronQueue) run(s *Scheduler) {
+	// the ticker is stopped with the queue, and created again if it restarts
+	cq.ticker = time.NewTicker(time.Second)
+	go func() {
+		defer cq.ticker.Stop()
+		log.Debugf("Cron queue is running...")
+		for cq.process(s) {
+			// empty
+		}
+		cq.stopped <- true
+	}()
+}
+
+// process enqueues the checks due at a tick, and returns whether the queue
+// should listen to the following tick (or stop)
+func (cq *cronQueue) process(s *Scheduler) bool {
+	select {
+	case <-cq.stop:
+		cq.health.Deregister()
+		return false
+	case t := <-cq.ticker.C:
+		for _, c := range cq.dueJobs(t) {
+			if !s.enqueue(c, cq.stop) {
+				cq.health.Deregister()
+				return false
+			}
+		}
+	case <-cq.health.C:
+		// nothing
+	}
+
+	return true
+}
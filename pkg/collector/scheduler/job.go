@@ -186,10 +186,8 @@ func (jq *jobQueue) process(s *Scheduler) bool {
 				continue
 			}
 
-			select {
 			// blocking, we'll be here as long as it takes
-			case s.checksPipe <- check:
-			case <-jq.stop:
+			if !s.enqueue(check, jq.stop) {
 				jq.health.Deregister()
 				return false
 			}
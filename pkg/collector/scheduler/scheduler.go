@@ -8,6 +8,7 @@ package scheduler
 import (
 	"expvar"
 	"fmt"
+	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -40,10 +41,14 @@ type Scheduler struct {
 	started      chan bool                   // Used to internally communicate the queues are up
 	jobQueues    map[time.Duration]*jobQueue // We have one scheduling queue for every interval
 	checkToQueue map[check.ID]*jobQueue      // Keep track of what is the queue for any Check
+	cronQueue    *cronQueue                  // Single queue for checks scheduled with a cron expression, created on demand
 	mu           sync.Mutex                  // To protect critical sections in struct's fields
 
 	cancelOneTime chan bool      // Used to internally communicate a cancel signal to one-time schedule goroutines
 	wgOneTime     sync.WaitGroup // WaitGroup to track the exit of one-time schedule goroutines
+
+	delayed   map[check.ID]bool // Checks with a delayed enqueue pending, at most one per check
+	delayedMu sync.Mutex        // To protect `delayed`, distinct from `mu` which is held while stopping the queues
 }
 
 // NewScheduler create a Scheduler and returns a pointer to it.
@@ -58,11 +63,15 @@ func NewScheduler(checksPipe chan<- check.Check) *Scheduler {
 		running:       0,
 		cancelOneTime: make(chan bool),
 		wgOneTime:     sync.WaitGroup{},
+		delayed:       make(map[check.ID]bool),
 	}
 }
 
 // Enter schedules a `Check`s for execution accordingly to the `Check.Interval()` value.
 // If the interval is 0, the check is supposed to run only once.
+// Checks implementing `check.Scheduled` with a non-empty cron expression are run
+// on that schedule instead, and every run is delayed by a random amount of time
+// up to their jitter.
 func (s *Scheduler) Enter(check check.Check) error {
 	// enqueue immediately if this is a one-time schedule
 	if check.Interval() == 0 {
@@ -70,11 +79,23 @@ func (s *Scheduler) Enter(check check.Check) error {
 		return nil
 	}
 
+	expr, jitter := scheduleOptions(check)
+	if expr != "" {
+		return s.enterCron(check, expr, jitter)
+	}
+
 	if check.Interval() < minAllowedInterval {
 		return fmt.Errorf("Schedule interval must be greater than %v or 0", minAllowedInterval)
 	}
 
-	log.Infof("Scheduling check %v with an interval of %v", check, check.Interval())
+	if jitter > 0 {
+		if jitter >= check.Interval() {
+			log.Warnf("The jitter of check %v (%v) is not lower than its interval (%v), some runs will be skipped", check, jitter, check.Interval())
+		}
+		log.Infof("Scheduling check %v with an interval of %v and a jitter of %v", check, check.Interval(), jitter)
+	} else {
+		log.Infof("Scheduling check %v with an interval of %v", check, check.Interval())
+	}
 
 	// sync when accessing `jobQueues` and `check2queue`
 	s.mu.Lock()
@@ -102,6 +123,15 @@ func (s *Scheduler) Cancel(id check.ID) error {
 
 	log.Infof("Unscheduling check %s", string(id))
 
+	if s.cronQueue != nil && s.cronQueue.hasJob(id) {
+		if err := s.cronQueue.removeJob(id); err != nil {
+			return fmt.Errorf("unable to remove the Job from the queue: %s", err)
+		}
+		schedulerChecksEntered.Add(-1)
+		schedulerExpvars.Set("Queues", expvar.Func(expQueues(s)))
+		return nil
+	}
+
 	if _, ok := s.checkToQueue[id]; !ok {
 		return nil
 	}
@@ -184,6 +214,9 @@ func (s *Scheduler) IsCheckScheduled(id check.ID) bool {
 	defer s.mu.Unlock()
 
 	_, found := s.checkToQueue[id]
+	if !found && s.cronQueue != nil {
+		found = s.cronQueue.hasJob(id)
+	}
 	return found
 }
 
@@ -204,6 +237,12 @@ func (s *Scheduler) stopQueues() {
 			q.running = false
 		}
 	}
+	if s.cronQueue != nil && s.cronQueue.running {
+		s.cronQueue.stop <- true
+		<-s.cronQueue.stopped
+		log.Debugf("Stopped cron queue")
+		s.cronQueue.running = false
+	}
 }
 
 // startQueues loads the timer for each queue
@@ -215,6 +254,9 @@ func (s *Scheduler) startQueues() {
 	for _, q := range s.jobQueues {
 		s.startQueue(q)
 	}
+	if s.cronQueue != nil {
+		s.startCronQueue()
+	}
 }
 
 // startQueue starts a queue (non-blocking operation) if it's not running yet
@@ -225,6 +267,101 @@ func (s *Scheduler) startQueue(q *jobQueue) {
 	}
 }
 
+// startCronQueue starts the cron queue (non-blocking operation) if it's not running yet
+func (s *Scheduler) startCronQueue() {
+	if !s.cronQueue.running {
+		s.cronQueue.run(s)
+		s.cronQueue.running = true
+	}
+}
+
+// enterCron schedules a check accordingly to a cron expression
+func (s *Scheduler) enterCron(c check.Check, expr string, jitter time.Duration) error {
+	schedule, err := parseCronSchedule(expr)
+	if err != nil {
+		return err
+	}
+	if schedule.next(time.Now()).IsZero() {
+		return fmt.Errorf("cron expression %q never matches", expr)
+	}
+
+	log.Infof("Scheduling check %v with the cron schedule %q and a jitter of %v", c, expr, jitter)
+
+	// sync when accessing `cronQueue`
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cronQueue == nil {
+		s.cronQueue = newCronQueue()
+		s.startCronQueue()
+		schedulerQueuesCount.Add(1)
+	}
+	s.cronQueue.addJob(c, expr, schedule)
+
+	schedulerChecksEntered.Add(1)
+	schedulerExpvars.Set("Queues", expvar.Func(expQueues(s)))
+	return nil
+}
+
+// enqueue sends a check to the checksPipe, blocking until the runner
+// picks it up or stop is signaled. Returns false if stop was signaled.
+// Checks configured with a jitter are sent asynchronously after a random delay.
+func (s *Scheduler) enqueue(c check.Check, stop <-chan bool) bool {
+	if _, jitter := scheduleOptions(c); jitter > 0 {
+		s.enqueueDelayed(c, time.Duration(rand.Int63n(int64(jitter))))
+		return true
+	}
+
+	select {
+	case s.checksPipe <- c:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// enqueueDelayed enqueues a check to the checksPipe after delay, if it is
+// still scheduled by then. The run is skipped if the previous delayed enqueue
+// of the check is still pending, so that a slow runner doesn't pile them up.
+// The queuing can be cancelled by closing the `cancelOneTime` channel.
+func (s *Scheduler) enqueueDelayed(c check.Check, delay time.Duration) {
+	s.delayedMu.Lock()
+	defer s.delayedMu.Unlock()
+	if s.delayed[c.ID()] {
+		log.Debugf("Skipping a run of check %v, the previous one is still waiting to be enqueued", c)
+		return
+	}
+	s.delayed[c.ID()] = true
+
+	log.Tracef("Delaying check %v by %v", c, delay)
+	s.wgOneTime.Add(1)
+
+	go func(cancelOneTime <-chan bool) {
+		defer s.wgOneTime.Done()
+		defer func() {
+			s.delayedMu.Lock()
+			delete(s.delayed, c.ID())
+			s.delayedMu.Unlock()
+		}()
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-cancelOneTime:
+			return
+		}
+
+		if !s.IsCheckScheduled(c.ID()) {
+			return
+		}
+		select {
+		case s.checksPipe <- c:
+		case <-cancelOneTime:
+		}
+	}(s.cancelOneTime)
+}
+
 // enqueueOnce enqueues a check once to the checksPipe.
 // Do not block, in case the runner has not started yet.
 // The queuing can be cancelled by closing the `cancelOneTime` channel.
@@ -251,6 +388,18 @@ func expQueues(s *Scheduler) func() interface{} {
 		for _, queue := range s.jobQueues {
 			queues = append(queues, queue.stats())
 		}
+		if s.cronQueue != nil {
+			queues = append(queues, s.cronQueue.stats())
+		}
 		return queues
 	}
 }
+
+// scheduleOptions returns the cron expression and the jitter of a check,
+// if it supports them
+func scheduleOptions(c check.Check) (string, time.Duration) {
+	if sc, ok := c.(check.Scheduled); ok {
+		return sc.Schedule(), sc.Jitter()
+	}
+	return "", 0
+}
@@ -28,6 +28,15 @@ func (c *TestCheck) ID() check.ID
 func (c *TestCheck) GetWarnings() []error                                       { return []error{} }
 func (c *TestCheck) GetMetricStats() (map[string]int64, error)                  { return make(map[string]int64), nil }
 
+type TestScheduledCheck struct {
+	TestCheck
+	schedule string
+	jitter   time.Duration
+}
+
+func (c *TestScheduledCheck) Schedule() string      { return c.schedule }
+func (c *TestScheduledCheck) Jitter() time.Duration { return c.jitter }
+
 var initialMinAllowedInterval = minAllowedInterval
 
 // wait 1s for a predicate function to return true, use polling
@@ -217,3 +226,131 @@ func TestStopOneTimeSchedule(t *testing.T) {
 	// sleep to make the runtime schedule the hanging goroutines, if there are any
 	time.Sleep(time.Millisecond)
 }
+
+func TestEnterCron(t *testing.T) {
+	s := getScheduler()
+	defer s.Stop()
+
+	// invalid expressions are rejected
+	err := s.Enter(&TestScheduledCheck{TestCheck: TestCheck{intl: 15 * time.Second}, schedule: "0 25 * * *"})
+	assert.NotNil(t, err)
+	err = s.Enter(&TestScheduledCheck{TestCheck: TestCheck{intl: 15 * time.Second}, schedule: "0 0 31 2 *"})
+	assert.NotNil(t, err)
+	assert.Nil(t, s.cronQueue)
+
+	c := &TestScheduledCheck{TestCheck: TestCheck{intl: 15 * time.Second}, schedule: "@daily"}
+	err = s.Enter(c)
+	assert.Nil(t, err)
+	assert.Len(t, s.jobQueues, 0)
+	assert.NotNil(t, s.cronQueue)
+	assert.True(t, s.IsCheckScheduled(c.ID()))
+
+	next := s.cronQueue.jobs[c.ID()].next
+	assert.Equal(t, 0, next.Hour())
+	assert.Equal(t, 0, next.Minute())
+	assert.True(t, next.After(time.Now()))
+
+	s.Run()
+	assert.True(t, s.cronQueue.running)
+
+	err = s.Cancel(c.ID())
+	assert.Nil(t, err)
+	assert.False(t, s.IsCheckScheduled(c.ID()))
+	assert.Len(t, s.cronQueue.jobs, 0)
+}
+
+func TestCronQueueDueJobs(t *testing.T) {
+	schedule, err := parseCronSchedule("*/5 * * * *")
+	assert.Nil(t, err)
+
+	cq := newCronQueue()
+	c := &TestScheduledCheck{schedule: "*/5 * * * *"}
+	cq.addJob(c, c.schedule, schedule)
+
+	now := time.Date(2019, 7, 1, 10, 3, 0, 0, time.UTC)
+	cq.jobs[c.ID()].next = now.Add(2 * time.Minute)
+
+	assert.Len(t, cq.dueJobs(now), 0)
+	assert.Len(t, cq.dueJobs(now.Add(2*time.Minute)), 1)
+	assert.Equal(t, time.Date(2019, 7, 1, 10, 10, 0, 0, time.UTC), cq.jobs[c.ID()].next)
+	assert.Len(t, cq.dueJobs(now.Add(3*time.Minute)), 0)
+}
+
+func TestJitter(t *testing.T) {
+	ch := make(chan check.Check)
+	s := NewScheduler(ch)
+
+	minAllowedInterval = time.Millisecond // for the purpose of this test, so that the scheduler actually schedules the checks
+	defer resetMinAllowedInterval()
+
+	c := &TestScheduledCheck{TestCheck: TestCheck{intl: time.Second}, jitter: 50 * time.Millisecond}
+	err := s.Enter(c)
+	assert.Nil(t, err)
+	assert.Len(t, s.jobQueues, 1)
+	assert.Nil(t, s.cronQueue)
+
+	s.Run()
+
+	select {
+	case received := <-ch:
+		assert.Equal(t, c.ID(), received.ID())
+	case <-time.After(3 * time.Second):
+		assert.Fail(t, "the check was not enqueued")
+	}
+
+	stop := make(chan bool)
+	go consume(ch, stop)
+	s.Stop()
+	close(stop)
+}
+
+// Test that cancelled checks are not enqueued once their jitter elapsed
+func TestJitterCancelled(t *testing.T) {
+	ch := make(chan check.Check)
+	s := NewScheduler(ch)
+
+	c := &TestScheduledCheck{TestCheck: TestCheck{intl: time.Second}, jitter: time.Second}
+	s.Enter(c)
+	s.Run()
+	s.enqueueDelayed(c, 10*time.Millisecond)
+	s.Cancel(c.ID())
+
+	select {
+	case <-ch:
+		assert.Fail(t, "a cancelled check was enqueued")
+	case <-time.After(50 * time.Millisecond):
+	}
+	s.Stop()
+}
+
+// Test that a delayed enqueue is skipped while the previous one is pending
+func TestJitterPending(t *testing.T) {
+	ch := make(chan check.Check)
+	s := NewScheduler(ch)
+
+	c := &TestScheduledCheck{TestCheck: TestCheck{intl: time.Second}, jitter: time.Second}
+	s.Enter(c)
+	s.Run()
+	for i := 0; i < 10; i++ {
+		s.enqueueDelayed(c, 0)
+	}
+
+	// the runner is busy, only the first enqueue is pending
+	time.Sleep(10 * time.Millisecond)
+	s.delayedMu.Lock()
+	assert.Len(t, s.delayed, 1)
+	s.delayedMu.Unlock()
+
+	// once picked up, the check can be enqueued again
+	<-ch
+	assert.True(t, consistently(func() bool {
+		s.delayedMu.Lock()
+		defer s.delayedMu.Unlock()
+		return len(s.delayed) == 0
+	}))
+	s.enqueueDelayed(c, 0)
+	<-ch
+
+	s.Stop()
+	assert.Len(t, s.delayed, 0)
+}
@@ -91,6 +91,22 @@ type Listeners struct {
 	Name string `mapstructure:"name"`
 }
 
+// FlareScrubbingRule helps unmarshalling `flare_scrubbing_rules` config param
+type FlareScrubbingRule struct {
+	Name        string   `mapstructure:"name"`
+	Regex       string   `mapstructure:"regex"`
+	Replacement string   `mapstructure:"replacement"`
+	Paths       []string `mapstructure:"paths"`
+}
+
+// MetricFilterRule helps unmarshalling `metric_filters` config param
+type MetricFilterRule struct {
+	Name        string   `mapstructure:"name"`
+	MetricRegex string   `mapstructure:"metric_regex"`
+	Action      string   `mapstructure:"action"`
+	Tags        []string `mapstructure:"tags"`
+}
+
 // Proxy represents the configuration for proxies in the agent
 type Proxy struct {
 	HTTP    string   `mapstructure:"http"`
@@ -193,6 +209,8 @@ func initConfig(config Config) {
 
 	// Yaml keys which values are stripped from flare
 	config.BindEnvAndSetDefault("flare_stripped_keys", []string{})
+	// Additional regex based scrubbing rules applied to the files of the flare
+	config.SetKnown("flare_scrubbing_rules")
 
 	// Agent GUI access port
 	config.BindEnvAndSetDefault("GUI_port", defaultGuiPort)
@@ -276,6 +294,8 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("statsd_forward_port", 0)
 	config.BindEnvAndSetDefault("statsd_metric_namespace", "")
 	config.BindEnvAndSetDefault("statsd_metric_namespace_blacklist", StandardStatsdPrefixes)
+	// Filters applied by the aggregator to the metrics of checks and DogStatsD
+	config.SetKnown("metric_filters")
 	// Autoconfig
 	config.BindEnvAndSetDefault("autoconf_template_dir", "/datadog/check_configs")
 	config.BindEnvAndSetDefault("exclude_pause_container", true)
@@ -419,6 +439,10 @@ func initConfig(config Config) {
 	config.BindEnv("logs_config.processing_rules")
 	// enforce the agent to use files to collect container logs on kubernetes environment
 	config.BindEnvAndSetDefault("logs_config.k8s_container_use_file", false)
+	// collect the container logs from the pod logs directory when the kubelet is not reachable
+	config.BindEnvAndSetDefault("logs_config.k8s_logs_dir_fallback", false)
+	// detect the start-of-record pattern of multi-line logs on sources without a multi_line rule
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line_detection", false)
 
 	// Internal Use Only: avoid modifying those configuration parameters, this could lead to unexpected results.
 	config.BindEnvAndSetDefault("logs_config.run_path", defaultRunPath)
@@ -549,6 +573,10 @@ func initConfig(config Config) {
 	config.SetKnown("apm_config.connection_limit")
 	config.SetKnown("apm_config.ignore_resources")
 	config.SetKnown("apm_config.replace_tags")
+	config.SetKnown("apm_config.filter_tags.require")
+	config.SetKnown("apm_config.filter_tags.reject")
+	config.SetKnown("apm_config.filter_tags_regex.require")
+	config.SetKnown("apm_config.filter_tags_regex.reject")
 	config.SetKnown("apm_config.obfuscation.elasticsearch.enabled")
 	config.SetKnown("apm_config.obfuscation.elasticsearch.keep_values")
 	config.SetKnown("apm_config.obfuscation.mongodb.enabled")
@@ -558,6 +586,10 @@ func initConfig(config Config) {
 	config.SetKnown("apm_config.obfuscation.remove_stack_traces")
 	config.SetKnown("apm_config.obfuscation.redis.enabled")
 	config.SetKnown("apm_config.obfuscation.memcached.enabled")
+	config.SetKnown("apm_config.obfuscation.graphql.enabled")
+	config.SetKnown("apm_config.obfuscation.message_queue.enabled")
+	config.SetKnown("apm_config.obfuscation.sql_dialects.enabled")
+	config.SetKnown("apm_config.obfuscation.credit_cards.enabled")
 	config.SetKnown("apm_config.extra_sample_rate")
 	config.SetKnown("apm_config.dd_agent_bin")
 	config.SetKnown("apm_config.max_events_per_second")
@@ -567,12 +599,33 @@ func initConfig(config Config) {
 	config.SetKnown("apm_config.service_writer.queue_size")
 	config.SetKnown("apm_config.stats_writer.connection_limit")
 	config.SetKnown("apm_config.stats_writer.queue_size")
+	config.SetKnown("apm_config.disk_spool.enabled")
+	config.SetKnown("apm_config.disk_spool.path")
+	config.SetKnown("apm_config.disk_spool.max_size")
+	config.SetKnown("apm_config.disk_spool.max_age_seconds")
 	config.SetKnown("apm_config.analyzed_rate_by_service.*")
 	config.SetKnown("apm_config.analyzed_spans.*")
 	config.SetKnown("apm_config.log_throttling")
 	config.SetKnown("apm_config.bucket_size_seconds")
+	config.SetKnown("apm_config.stats_sketches")
 	config.SetKnown("apm_config.receiver_timeout")
 	config.SetKnown("apm_config.watchdog_check_delay")
+	config.SetKnown("apm_config.sampling_rules")
+	config.SetKnown("apm_config.tail_sampling.enabled")
+	config.SetKnown("apm_config.tail_sampling.window_seconds")
+	config.SetKnown("apm_config.tail_sampling.max_traces")
+	config.SetKnown("apm_config.tail_sampling.max_memory")
+	config.SetKnown("apm_config.debug_traces.enabled")
+	config.SetKnown("apm_config.debug_traces.max_traces")
+	config.SetKnown("apm_config.debug_traces.max_bytes")
+	config.SetKnown("apm_config.peer_tags.enabled")
+	config.SetKnown("apm_config.peer_tags.extra_service_keys")
+	config.SetKnown("apm_config.peer_tags.extra_hostname_keys")
+	config.SetKnown("apm_config.trace_metrics.enabled")
+	config.SetKnown("apm_config.trace_metrics.tags")
+	config.SetKnown("apm_config.trace_metrics.max_resources_per_service")
+	config.SetKnown("apm_config.trace_metrics.dogstatsd_socket")
+	config.SetKnown("apm_config.tail_sampling.rules")
 
 	// inventories
 	config.BindEnvAndSetDefault("inventories_enabled", true)
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+)
+
+func init() {
+	diagnosis.RegisterStructured("File permissions", diagnosis.CategoryPermissions, func() []diagnosis.Result {
+		return []diagnosis.Result{
+			diagnoseConfigFile(Datadog.ConfigFileUsed()),
+			diagnoseRunPath(Datadog.GetString("run_path")),
+		}
+	})
+}
+
+// diagnoseConfigFile checks that the configuration file is readable and
+// not exposed to every user of the host, as it contains the API key
+func diagnoseConfigFile(path string) diagnosis.Result {
+	result := diagnosis.Result{Target: path}
+	if path == "" {
+		result.Status = diagnosis.StatusSkipped
+		result.Message = "No configuration file loaded"
+		return result
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		result.Status = diagnosis.StatusFail
+		result.Message = fmt.Sprintf("Unable to read the configuration file: %s", err)
+		result.Remediation = "Make sure the configuration file is readable by the user running the agent."
+		return result
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		result.Status = diagnosis.StatusFail
+		result.Message = fmt.Sprintf("Unable to stat the configuration file: %s", err)
+		return result
+	}
+	mode := info.Mode().Perm()
+	result.Details = map[string]interface{}{"mode": mode.String()}
+
+	// permission bits aren't meaningful on Windows
+	if runtime.GOOS != "windows" {
+		if mode&0002 != 0 {
+			result.Status = diagnosis.StatusWarning
+			result.Message = "The configuration file is world-writable"
+			result.Remediation = fmt.Sprintf("Run `chmod o-w %s`.", path)
+			return result
+		}
+		if mode&0004 != 0 {
+			result.Status = diagnosis.StatusWarning
+			result.Message = "The configuration file is world-readable, it contains the API key"
+			result.Remediation = fmt.Sprintf("Run `chmod o-r %s`.", path)
+			return result
+		}
+	}
+
+	result.Status = diagnosis.StatusPass
+	result.Message = "The configuration file is readable"
+	return result
+}
+
+// diagnoseRunPath checks that the run path, where the agent persists its
+// state, is a writable directory
+func diagnoseRunPath(path string) diagnosis.Result {
+	result := diagnosis.Result{Target: path}
+	remediation := fmt.Sprintf("Create the `%s` directory and give the user running the agent write access to it, or change the `run_path` setting.", path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		result.Status = diagnosis.StatusFail
+		result.Message = fmt.Sprintf("Unable to access the run path: %s", err)
+		result.Remediation = remediation
+		return result
+	}
+	if !info.IsDir() {
+		result.Status = diagnosis.StatusFail
+		result.Message = "The run path is not a directory"
+		result.Remediation = remediation
+		return result
+	}
+
+	f, err := ioutil.TempFile(path, "diagnose")
+	if err != nil {
+		result.Status = diagnosis.StatusFail
+		result.Message = fmt.Sprintf("The run path is not writable: %s", err)
+		result.Remediation = remediation
+		return result
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	result.Status = diagnosis.StatusPass
+	result.Message = "The run path is writable"
+	return result
+}
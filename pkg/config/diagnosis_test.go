@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build !windows
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+)
+
+func TestDiagnoseConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diagnose")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "datadog.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("api_key: foo\n"), 0640))
+
+	r := diagnoseConfigFile(path)
+	assert.Equal(t, diagnosis.StatusPass, r.Status)
+
+	require.NoError(t, os.Chmod(path, 0644))
+	r = diagnoseConfigFile(path)
+	assert.Equal(t, diagnosis.StatusWarning, r.Status)
+	assert.Contains(t, r.Message, "world-readable")
+
+	require.NoError(t, os.Chmod(path, 0646))
+	r = diagnoseConfigFile(path)
+	assert.Equal(t, diagnosis.StatusWarning, r.Status)
+	assert.Contains(t, r.Message, "world-writable")
+
+	r = diagnoseConfigFile(filepath.Join(dir, "missing.yaml"))
+	assert.Equal(t, diagnosis.StatusFail, r.Status)
+
+	r = diagnoseConfigFile("")
+	assert.Equal(t, diagnosis.StatusSkipped, r.Status)
+}
+
+func TestDiagnoseRunPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diagnose")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	r := diagnoseRunPath(dir)
+	assert.Equal(t, diagnosis.StatusPass, r.Status)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 0)
+
+	r = diagnoseRunPath(filepath.Join(dir, "missing"))
+	assert.Equal(t, diagnosis.StatusFail, r.Status)
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0600))
+	r = diagnoseRunPath(file)
+	assert.Equal(t, diagnosis.StatusFail, r.Status)
+	assert.Contains(t, r.Message, "not a directory")
+}
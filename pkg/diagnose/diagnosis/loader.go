@@ -7,19 +7,47 @@ package diagnosis
 
 import "github.com/DataDog/datadog-agent/pkg/util/log"
 
+// Categories of the built-in diagnosis
+const (
+	CategoryConnectivity  = "connectivity"
+	CategoryConfiguration = "configuration"
+	CategoryPermissions   = "permissions"
+)
+
 // Catalog holds available diagnosis for detection and usage
-type Catalog map[string]Diagnosis
+type Catalog map[string]Entry
+
+// Entry is a diagnosis registered in a Catalog
+type Entry struct {
+	Category string
+	Run      StructuredDiagnosis
+}
 
 // DefaultCatalog holds every compiled-in diagnosis
 var DefaultCatalog = make(Catalog)
 
 // Register a diagnosis that will be called on diagnose
 func Register(name string, d Diagnosis) {
+	RegisterStructured(name, CategoryConnectivity, func() []Result {
+		if err := d(); err != nil {
+			return []Result{{Status: StatusFail, Message: err.Error()}}
+		}
+		return []Result{{Status: StatusPass}}
+	})
+}
+
+// RegisterStructured registers a diagnosis returning structured results
+// that will be called on diagnose
+func RegisterStructured(name, category string, d StructuredDiagnosis) {
 	if _, ok := DefaultCatalog[name]; ok {
 		log.Warnf("Diagnosis %s already registered, overriding it", name)
 	}
-	DefaultCatalog[name] = d
+	DefaultCatalog[name] = Entry{Category: category, Run: d}
 }
 
 // Diagnosis should return an error to report its health
 type Diagnosis func() error
+
+// StructuredDiagnosis returns the results of a diagnosis, usually one per
+// diagnosed target (endpoint, file...)
+type StructuredDiagnosis func() []Result
@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package diagnosis
+
+// Status is the outcome of a diagnosis
+type Status string
+
+// Possible outcomes of a diagnosis, from the best to the worst
+const (
+	StatusPass    Status = "pass"
+	StatusSkipped Status = "skipped"
+	StatusWarning Status = "warning"
+	StatusFail    Status = "fail"
+)
+
+var statusSeverity = map[Status]int{
+	StatusPass:    0,
+	StatusSkipped: 1,
+	StatusWarning: 2,
+	StatusFail:    3,
+}
+
+// Result is the structured result of a diagnosis. Name and Category are
+// filled from the registered diagnosis when left empty.
+type Result struct {
+	Name        string                 `json:"name"`
+	Category    string                 `json:"category"`
+	Target      string                 `json:"target,omitempty"` // diagnosed endpoint, file... if any
+	Status      Status                 `json:"status"`
+	Message     string                 `json:"message,omitempty"`
+	Remediation string                 `json:"remediation,omitempty"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+}
+
+// WorstStatus returns the worst status of a list of results, StatusPass
+// if the list is empty
+func WorstStatus(results []Result) Status {
+	worst := StatusPass
+	for _, r := range results {
+		if statusSeverity[r.Status] > statusSeverity[worst] {
+			worst = r.Status
+		}
+	}
+	return worst
+}
@@ -6,6 +6,7 @@
 package diagnose
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"sort"
@@ -19,6 +20,13 @@ import (
 
 // RunAll runs all registered connectivity checks, output it in writer
 func RunAll(w io.Writer) error {
+	_, err := RunAllWithResults(w)
+	return err
+}
+
+// RunAllWithResults runs all registered diagnosis, outputs them in writer
+// and returns their results
+func RunAllWithResults(w io.Writer) ([]diagnosis.Result, error) {
 	if w != color.Output {
 		color.NoColor = true
 	}
@@ -26,26 +34,95 @@ func RunAll(w io.Writer) error {
 	// Use temporarily a custom logger to our Writer
 	customLogger, err := seelog.LoggerFromWriterWithMinLevelAndFormat(w, seelog.DebugLvl, "[%LEVEL] %FuncShort: %Msg - %Ns%n")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.RegisterAdditionalLogger("diagnose", customLogger)
 	defer log.UnregisterAdditionalLogger("diagnose")
 
+	var results []diagnosis.Result
+	for _, name := range sortedDiagnosis() {
+		fmt.Fprintln(w, fmt.Sprintf("=== Running %s diagnosis ===", color.BlueString(name)))
+		diagnosisResults := run(name, diagnosis.DefaultCatalog[name])
+		fmt.Fprintln(w, fmt.Sprintf("===> %s", statusString(diagnosis.WorstStatus(diagnosisResults))))
+		for _, r := range diagnosisResults {
+			printResult(w, r)
+		}
+		fmt.Fprintln(w)
+		results = append(results, diagnosisResults...)
+	}
+
+	return results, nil
+}
+
+// RunAllJSON runs all registered diagnosis and outputs their results as JSON in writer
+func RunAllJSON(w io.Writer) error {
+	results := Run()
+	out, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+// Run runs all registered diagnosis and returns their results, sorted by diagnosis name
+func Run() []diagnosis.Result {
+	results := []diagnosis.Result{}
+	for _, name := range sortedDiagnosis() {
+		results = append(results, run(name, diagnosis.DefaultCatalog[name])...)
+	}
+	return results
+}
+
+func sortedDiagnosis() []string {
 	var sortedDiagnosis []string
 	for name := range diagnosis.DefaultCatalog {
 		sortedDiagnosis = append(sortedDiagnosis, name)
 	}
 	sort.Strings(sortedDiagnosis)
+	return sortedDiagnosis
+}
 
-	for _, name := range sortedDiagnosis {
-		fmt.Fprintln(w, fmt.Sprintf("=== Running %s diagnosis ===", color.BlueString(name)))
-		err := diagnosis.DefaultCatalog[name]()
-		statusString := color.GreenString("PASS")
-		if err != nil {
-			statusString = color.RedString("FAIL")
+// run runs a diagnosis, filling the name and the category of its results
+func run(name string, entry diagnosis.Entry) []diagnosis.Result {
+	results := entry.Run()
+	if len(results) == 0 {
+		results = []diagnosis.Result{{Status: diagnosis.StatusPass}}
+	}
+	for i := range results {
+		if results[i].Name == "" {
+			results[i].Name = name
+		}
+		if results[i].Category == "" {
+			results[i].Category = entry.Category
 		}
-		fmt.Fprintln(w, fmt.Sprintf("===> %s\n", statusString))
 	}
+	return results
+}
+
+func statusString(status diagnosis.Status) string {
+	switch status {
+	case diagnosis.StatusFail:
+		return color.RedString("FAIL")
+	case diagnosis.StatusWarning:
+		return color.YellowString("WARNING")
+	case diagnosis.StatusSkipped:
+		return color.WhiteString("SKIPPED")
+	default:
+		return color.GreenString("PASS")
+	}
+}
 
-	return nil
+func printResult(w io.Writer, r diagnosis.Result) {
+	if r.Message == "" && r.Target == "" {
+		return
+	}
+	target := ""
+	if r.Target != "" {
+		target = r.Target + ": "
+	}
+	fmt.Fprintln(w, fmt.Sprintf("    [%s] %s%s", statusString(r.Status), target, r.Message))
+	if r.Remediation != "" && r.Status != diagnosis.StatusPass {
+		fmt.Fprintln(w, fmt.Sprintf("        Remediation: %s", r.Remediation))
+	}
 }
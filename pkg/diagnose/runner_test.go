@@ -7,12 +7,14 @@ package diagnose
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"testing"
 
 	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRunAll(t *testing.T) {
@@ -27,3 +29,54 @@ func TestRunAll(t *testing.T) {
 	assert.Contains(t, result, "=== Running failing diagnosis ===\n===> FAIL")
 	assert.Contains(t, result, "=== Running succeeding diagnosis ===\n===> PASS")
 }
+
+func TestRunAllStructured(t *testing.T) {
+	diagnosis.RegisterStructured("structured", diagnosis.CategoryConfiguration, func() []diagnosis.Result {
+		return []diagnosis.Result{
+			{Target: "first", Status: diagnosis.StatusPass, Message: "ok"},
+			{Target: "second", Status: diagnosis.StatusWarning, Message: "not ok", Remediation: "fix it"},
+		}
+	})
+	defer delete(diagnosis.DefaultCatalog, "structured")
+
+	w := &bytes.Buffer{}
+	results, err := RunAllWithResults(w)
+	require.NoError(t, err)
+
+	output := w.String()
+	assert.Contains(t, output, "=== Running structured diagnosis ===\n===> WARNING\n")
+	assert.Contains(t, output, "    [PASS] first: ok\n")
+	assert.Contains(t, output, "    [WARNING] second: not ok\n        Remediation: fix it\n")
+
+	var structured []diagnosis.Result
+	for _, r := range results {
+		if r.Name == "structured" {
+			structured = append(structured, r)
+		}
+	}
+	require.Len(t, structured, 2)
+	assert.Equal(t, diagnosis.CategoryConfiguration, structured[1].Category)
+	assert.Equal(t, diagnosis.StatusWarning, structured[1].Status)
+}
+
+func TestRunAllJSON(t *testing.T) {
+	diagnosis.Register("legacy failing", func() error { return errors.New("fail") })
+	defer delete(diagnosis.DefaultCatalog, "legacy failing")
+
+	w := &bytes.Buffer{}
+	require.NoError(t, RunAllJSON(w))
+
+	var results []diagnosis.Result
+	require.NoError(t, json.Unmarshal(w.Bytes(), &results))
+
+	found := false
+	for _, r := range results {
+		if r.Name == "legacy failing" {
+			found = true
+			assert.Equal(t, diagnosis.StatusFail, r.Status)
+			assert.Equal(t, diagnosis.CategoryConnectivity, r.Category)
+			assert.Equal(t, "fail", r.Message)
+		}
+	}
+	assert.True(t, found)
+}
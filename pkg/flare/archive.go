@@ -84,25 +84,43 @@ type filePermsInfo struct {
 // CreateArchive packages up the files
 func CreateArchive(local bool, distPath, pyChecksPath, logFilePath string) (string, error) {
 	zipFilePath := getArchivePath()
-	confSearchPaths := SearchPaths{
+	filePath, _, err := createArchive(zipFilePath, local, getConfSearchPaths(distPath, pyChecksPath), logFilePath)
+	return filePath, err
+}
+
+// CreatePreviewArchive packages up the files the same way CreateArchive does, and
+// reports which scrubbing rules matched in which files
+func CreatePreviewArchive(local bool, distPath, pyChecksPath, logFilePath string) (string, ScrubReport, error) {
+	zipFilePath := getArchivePath()
+	return createArchive(zipFilePath, local, getConfSearchPaths(distPath, pyChecksPath), logFilePath)
+}
+
+// Preview holds the path of a flare built by CreatePreviewArchive and the report
+// of its scrubbing, as returned by the agent API.
+type Preview struct {
+	Path   string      `json:"path"`
+	Report ScrubReport `json:"report"`
+}
+
+func getConfSearchPaths(distPath, pyChecksPath string) SearchPaths {
+	return SearchPaths{
 		"":        config.Datadog.GetString("confd_path"),
 		"dist":    filepath.Join(distPath, "conf.d"),
 		"checksd": pyChecksPath,
 	}
-	return createArchive(zipFilePath, local, confSearchPaths, logFilePath)
 }
 
-func createArchive(zipFilePath string, local bool, confSearchPaths SearchPaths, logFilePath string) (string, error) {
+func createArchive(zipFilePath string, local bool, confSearchPaths SearchPaths, logFilePath string) (string, ScrubReport, error) {
 	b := make([]byte, 10)
 	_, err := rand.Read(b)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	dirName := hex.EncodeToString([]byte(b))
 	tempDir, err := ioutil.TempDir("", dirName)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	defer os.RemoveAll(tempDir)
@@ -123,28 +141,28 @@ func createArchive(zipFilePath string, local bool, confSearchPaths SearchPaths,
 
 		err := ensureParentDirsExist(f)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 
 		w, err := newRedactingWriter(f, os.ModePerm, true)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 		defer w.Close()
 
 		_, err = w.Write([]byte{})
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 
 		// Can't reach the agent, mention it in those two files
 		err = writeStatusFile(tempDir, hostname, []byte("unable to get the status of the agent, is it running?"))
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 		err = writeConfigCheck(tempDir, hostname, []byte("unable to get loaded checks config, is the agent running?"))
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 	} else {
 		// Status informations are available, zip them up as the agent is running.
@@ -232,12 +250,18 @@ func createArchive(zipFilePath string, local bool, confSearchPaths SearchPaths,
 		log.Errorf("Could not write permissions.log file: %s", err)
 	}
 
+	// apply the configured scrubbing rules to every file of the flare
+	report, err := newScrubberFromConfig().scrubDir(filepath.Join(tempDir, hostname))
+	if err != nil {
+		return "", nil, err
+	}
+
 	err = archiver.Zip.Make(zipFilePath, []string{filepath.Join(tempDir, hostname)})
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return zipFilePath, nil
+	return zipFilePath, report, nil
 }
 
 func zipStatusFile(tempDir, hostname string) error {
@@ -439,10 +463,23 @@ func zipDiagnose(tempDir, hostname string) error {
 	var b bytes.Buffer
 
 	writer := bufio.NewWriter(&b)
-	diagnose.RunAll(writer)
+	results, _ := diagnose.RunAllWithResults(writer)
 	writer.Flush()
 
-	f := filepath.Join(tempDir, hostname, "diagnose.log")
+	err := writeRedacted(filepath.Join(tempDir, hostname, "diagnose.log"), b.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// the structured results are easier to process by support tooling
+	jsonResults, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeRedacted(filepath.Join(tempDir, hostname, "diagnose.json"), jsonResults)
+}
+
+func writeRedacted(f string, content []byte) error {
 	err := ensureParentDirsExist(f)
 	if err != nil {
 		return err
@@ -454,7 +491,7 @@ func zipDiagnose(tempDir, hostname string) error {
 	}
 	defer w.Close()
 
-	_, err = w.Write(b.Bytes())
+	_, err = w.Write(content)
 	return err
 }
 
@@ -135,6 +135,12 @@ func createDCAArchive(zipFilePath string, local bool, confSearchPaths SearchPath
 		log.Infof("Error while creating permissions.log infos file: %s", err)
 	}
 
+	// apply the configured scrubbing rules to every file of the flare
+	_, err = newScrubberFromConfig().scrubDir(filepath.Join(tempDir, hostname))
+	if err != nil {
+		return "", err
+	}
+
 	err = archiver.Zip.Make(zipFilePath, []string{filepath.Join(tempDir, hostname)})
 	if err != nil {
 		return "", err
@@ -0,0 +1,183 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package flare
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// ScrubReport lists, for every file of a flare, the number of matches of
+// each configured scrubbing rule. The paths are relative to the root of
+// the flare.
+type ScrubReport map[string]map[string]int
+
+// Files returns the sorted paths of the files matched by at least one rule
+func (r ScrubReport) Files() []string {
+	files := make([]string, 0, len(r))
+	for f := range r {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Rules returns the sorted names of the rules that matched a file
+func (r ScrubReport) Rules(file string) []string {
+	rules := make([]string, 0, len(r[file]))
+	for name := range r[file] {
+		rules = append(rules, name)
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+func (r ScrubReport) add(file, rule string, matches int) {
+	if _, ok := r[file]; !ok {
+		r[file] = make(map[string]int)
+	}
+	r[file][rule] += matches
+}
+
+type scrubbingRule struct {
+	name        string
+	regex       *regexp.Regexp
+	replacement []byte
+	paths       []string
+}
+
+// scrubber applies the configured scrubbing rules to the files of a flare,
+// on top of the default scrubbing done by the RedactingWriter.
+type scrubber struct {
+	rules []scrubbingRule
+}
+
+// newScrubberFromConfig builds a scrubber from the `flare_scrubbing_rules`
+// setting. Invalid rules are logged and ignored, so that a typo doesn't
+// prevent sending a flare.
+func newScrubberFromConfig() *scrubber {
+	var rules []config.FlareScrubbingRule
+	if err := config.Datadog.UnmarshalKey("flare_scrubbing_rules", &rules); err != nil {
+		log.Errorf("Unable to parse flare_scrubbing_rules: %s", err)
+		return &scrubber{}
+	}
+	s, errs := newScrubber(rules)
+	for _, err := range errs {
+		log.Errorf("Ignoring flare scrubbing rule: %s", err)
+	}
+	return s
+}
+
+func newScrubber(rules []config.FlareScrubbingRule) (*scrubber, []error) {
+	s := &scrubber{}
+	var errs []error
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule_%d", i)
+		}
+		if rule.Regex == "" {
+			errs = append(errs, fmt.Errorf("%s: no regex defined", name))
+			continue
+		}
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid regex: %s", name, err))
+			continue
+		}
+		invalidPath := false
+		for _, p := range rule.Paths {
+			if _, err := filepath.Match(p, ""); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid path glob %q: %s", name, p, err))
+				invalidPath = true
+			}
+		}
+		if invalidPath {
+			continue
+		}
+		s.rules = append(s.rules, scrubbingRule{
+			name:        name,
+			regex:       re,
+			replacement: []byte(rule.Replacement),
+			paths:       rule.Paths,
+		})
+	}
+	return s, errs
+}
+
+// appliesTo returns whether a rule should be applied to a file, given its
+// slash-separated path relative to the root of the flare. Globs without a
+// slash are matched against the file name.
+func (r scrubbingRule) appliesTo(relPath string) bool {
+	if len(r.paths) == 0 {
+		return true
+	}
+	for _, p := range r.paths {
+		target := relPath
+		if !strings.Contains(p, "/") {
+			target = filepath.Base(relPath)
+		}
+		if ok, _ := filepath.Match(p, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// scrub applies the rules to a content and records their matches
+func (s *scrubber) scrub(relPath string, content []byte, report ScrubReport) []byte {
+	for _, rule := range s.rules {
+		if !rule.appliesTo(relPath) {
+			continue
+		}
+		matches := rule.regex.FindAllIndex(content, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		report.add(relPath, rule.name, len(matches))
+		content = rule.regex.ReplaceAll(content, rule.replacement)
+	}
+	return content
+}
+
+// scrubDir applies the rules to every file under root, in place
+func (s *scrubber) scrubDir(root string) (ScrubReport, error) {
+	report := make(ScrubReport)
+	if len(s.rules) == 0 {
+		return report, nil
+	}
+
+	err := filepath.Walk(root, func(src string, f os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("unable to scrub %s: %s", src, err)
+		}
+		if f.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(root, src)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("unable to scrub %s: %s", src, err)
+		}
+		scrubbed := s.scrub(filepath.ToSlash(relPath), content, report)
+		if len(report[filepath.ToSlash(relPath)]) == 0 {
+			return nil
+		}
+		return ioutil.WriteFile(src, scrubbed, f.Mode())
+	})
+	return report, err
+}
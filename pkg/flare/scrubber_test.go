@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package flare
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestNewScrubberInvalidRules(t *testing.T) {
+	s, errs := newScrubber([]config.FlareScrubbingRule{
+		{Name: "valid", Regex: "secret"},
+		{Name: "no_regex"},
+		{Name: "bad_regex", Regex: "(secret"},
+		{Name: "bad_glob", Regex: "secret", Paths: []string{"logs/["}},
+	})
+	assert.Len(t, errs, 3)
+	require.Len(t, s.rules, 1)
+	assert.Equal(t, "valid", s.rules[0].name)
+}
+
+func TestScrubbingRuleAppliesTo(t *testing.T) {
+	s, errs := newScrubber([]config.FlareScrubbingRule{
+		{Regex: "a"},
+		{Regex: "a", Paths: []string{"logs/*.log"}},
+		{Regex: "a", Paths: []string{"*.log"}},
+	})
+	require.Len(t, errs, 0)
+	require.Len(t, s.rules, 3)
+
+	assert.Equal(t, "rule_0", s.rules[0].name)
+	assert.True(t, s.rules[0].appliesTo("expvar/forwarder"))
+
+	assert.True(t, s.rules[1].appliesTo("logs/agent.log"))
+	assert.False(t, s.rules[1].appliesTo("config-check.log"))
+	assert.False(t, s.rules[1].appliesTo("logs/agent.log.1"))
+
+	assert.True(t, s.rules[2].appliesTo("config-check.log"))
+	assert.True(t, s.rules[2].appliesTo("logs/agent.log"))
+	assert.False(t, s.rules[2].appliesTo("expvar/forwarder"))
+}
+
+func TestScrubDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestScrubDir")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "logs"), os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "logs", "agent.log"), []byte("token itk_abcd on db1.corp.example.com\nitk_efgh"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "status.log"), []byte("connected to db2.corp.example.com"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "health.yaml"), []byte("healthy: []"), 0644))
+
+	s, errs := newScrubber([]config.FlareScrubbingRule{
+		{Name: "tokens", Regex: `itk_[a-z]+`, Replacement: "itk_********"},
+		{Name: "hosts", Regex: `[a-z0-9-]+(\.corp\.example\.com)`, Replacement: "********$1", Paths: []string{"logs/*"}},
+	})
+	require.Len(t, errs, 0)
+
+	report, err := s.scrubDir(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"logs/agent.log"}, report.Files())
+	assert.Equal(t, []string{"hosts", "tokens"}, report.Rules("logs/agent.log"))
+	assert.Equal(t, 2, report["logs/agent.log"]["tokens"])
+	assert.Equal(t, 1, report["logs/agent.log"]["hosts"])
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "logs", "agent.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "token itk_******** on ********.corp.example.com\nitk_********", string(content))
+
+	// the hosts rule doesn't apply outside of the logs folder
+	content, err = ioutil.ReadFile(filepath.Join(dir, "status.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "connected to db2.corp.example.com", string(content))
+}
+
+func TestScrubDirError(t *testing.T) {
+	s, errs := newScrubber([]config.FlareScrubbingRule{
+		{Name: "tokens", Regex: `itk_[a-z]+`, Replacement: "itk_********"},
+	})
+	require.Len(t, errs, 0)
+
+	_, err := s.scrubDir(filepath.Join(os.TempDir(), "TestScrubDirError-missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,191 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package forwarder
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+const (
+	diagnosisTimeout = 10 * time.Second
+
+	// clock skews over this threshold make the agent metrics look shifted in time
+	clockSkewWarningThreshold = time.Minute
+	// the intake drops points timestamped more than 10 minutes in the future
+	clockSkewFailThreshold = 10 * time.Minute
+)
+
+func init() {
+	diagnosis.RegisterStructured("API key validity", diagnosis.CategoryConnectivity, diagnoseAPIKeys)
+	diagnosis.RegisterStructured("Clock skew", diagnosis.CategoryConfiguration, diagnoseClockSkew)
+}
+
+// validateResponse holds the outcome of a call to the API key validation endpoint
+type validateResponse struct {
+	statusCode int
+	skew       time.Duration // local time minus intake time, only set if hasDate
+	hasDate    bool
+}
+
+// queryValidateEndpoint calls the API key validation endpoint of a domain,
+// and computes the clock skew with the intake from the `Date` response header
+func queryValidateEndpoint(domain, apiKey string) (*validateResponse, error) {
+	client := &http.Client{
+		Transport: httputils.CreateHTTPTransport(),
+		Timeout:   diagnosisTimeout,
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s%s?api_key=%s", domain, v1ValidateEndpoint, apiKey), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(useragentHTTPHeaderKey, fmt.Sprintf("datadog-agent/%s", version.AgentVersion))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s", httputils.SanitizeURL(err.Error()))
+	}
+	defer resp.Body.Close()
+	end := time.Now()
+
+	r := &validateResponse{statusCode: resp.StatusCode}
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		// compare with the local time at the middle of the round trip
+		r.skew = start.Add(end.Sub(start) / 2).Sub(date)
+		r.hasDate = true
+	}
+	return r, nil
+}
+
+func diagnoseAPIKeys() []diagnosis.Result {
+	keysPerDomains, err := config.GetMultipleEndpoints()
+	if err != nil {
+		return []diagnosis.Result{{
+			Status:      diagnosis.StatusFail,
+			Message:     fmt.Sprintf("Could not read the endpoints configuration: %s", err),
+			Remediation: "Check the `dd_url`, `site` and `additional_endpoints` settings of the agent configuration.",
+		}}
+	}
+	return diagnoseAPIKeysPerDomains(keysPerDomains)
+}
+
+func diagnoseAPIKeysPerDomains(keysPerDomains map[string][]string) []diagnosis.Result {
+	var domains []string
+	for domain := range keysPerDomains {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	var results []diagnosis.Result
+	for _, domain := range domains {
+		for _, apiKey := range keysPerDomains[domain] {
+			target := fmt.Sprintf("%s (%s)", domain, obfuscateAPIKey(apiKey))
+			if apiKey == "" {
+				results = append(results, diagnosis.Result{
+					Target:      domain,
+					Status:      diagnosis.StatusFail,
+					Message:     "No API key configured",
+					Remediation: "Set the `api_key` option of the agent configuration or the DD_API_KEY environment variable.",
+				})
+				continue
+			}
+
+			resp, err := queryValidateEndpoint(domain, apiKey)
+			switch {
+			case err != nil:
+				results = append(results, diagnosis.Result{
+					Target:      target,
+					Status:      diagnosis.StatusFail,
+					Message:     fmt.Sprintf("Unable to reach the API key validation endpoint: %s", err),
+					Remediation: "Check the network connectivity and the proxy settings of the host, the `Proxy settings` diagnosis may help.",
+				})
+			case resp.statusCode == http.StatusOK:
+				results = append(results, diagnosis.Result{
+					Target:  target,
+					Status:  diagnosis.StatusPass,
+					Message: "API key valid",
+				})
+			case resp.statusCode == http.StatusForbidden:
+				results = append(results, diagnosis.Result{
+					Target:      target,
+					Status:      diagnosis.StatusFail,
+					Message:     "API key invalid",
+					Remediation: "Check that the API key is valid for the organization and the site of this endpoint.",
+				})
+			default:
+				results = append(results, diagnosis.Result{
+					Target:  target,
+					Status:  diagnosis.StatusWarning,
+					Message: fmt.Sprintf("Unexpected response code from the API key validation endpoint: %d", resp.statusCode),
+					Details: map[string]interface{}{"status_code": resp.statusCode},
+				})
+			}
+		}
+	}
+	return results
+}
+
+func diagnoseClockSkew() []diagnosis.Result {
+	domain := config.GetMainInfraEndpoint()
+	return diagnoseClockSkewWithIntake(domain, config.Datadog.GetString("api_key"))
+}
+
+func diagnoseClockSkewWithIntake(domain, apiKey string) []diagnosis.Result {
+	resp, err := queryValidateEndpoint(domain, apiKey)
+	if err != nil {
+		return []diagnosis.Result{{
+			Target:  domain,
+			Status:  diagnosis.StatusSkipped,
+			Message: fmt.Sprintf("Unable to reach the intake: %s", err),
+		}}
+	}
+	if !resp.hasDate {
+		return []diagnosis.Result{{
+			Target:  domain,
+			Status:  diagnosis.StatusSkipped,
+			Message: "The intake response has no valid Date header",
+		}}
+	}
+
+	skew := resp.skew
+	if skew < 0 {
+		skew = -skew
+	}
+	result := diagnosis.Result{
+		Target:      domain,
+		Status:      diagnosis.StatusPass,
+		Message:     fmt.Sprintf("The host clock is %s", describeSkew(resp.skew)),
+		Remediation: "Synchronize the host clock, for instance with NTP.",
+		Details:     map[string]interface{}{"skew_seconds": resp.skew.Seconds()},
+	}
+	if skew > clockSkewFailThreshold {
+		result.Status = diagnosis.StatusFail
+	} else if skew > clockSkewWarningThreshold {
+		result.Status = diagnosis.StatusWarning
+	}
+	return []diagnosis.Result{result}
+}
+
+func describeSkew(skew time.Duration) string {
+	skew = skew.Round(time.Second)
+	switch {
+	case skew > 0:
+		return fmt.Sprintf("%s ahead of the intake", skew)
+	case skew < 0:
+		return fmt.Sprintf("%s behind the intake", -skew)
+	default:
+		return "in sync with the intake"
+	}
+}
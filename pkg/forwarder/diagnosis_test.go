@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package forwarder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+)
+
+func TestDiagnoseAPIKeys(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.Form.Get("api_key") {
+		case "valid_key":
+			w.WriteHeader(http.StatusOK)
+		case "invalid_key":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	results := diagnoseAPIKeysPerDomains(map[string][]string{
+		ts.URL:                  {"valid_key", "invalid_key", "other_key", ""},
+		"http://localhost:1234": {"unreachable"}, // sorted after the test server 127.0.0.1 address
+	})
+	require.Len(t, results, 5)
+
+	assert.Equal(t, ts.URL+" (API key ending with d_key)", results[0].Target)
+	assert.Equal(t, diagnosis.StatusPass, results[0].Status)
+
+	assert.Equal(t, diagnosis.StatusFail, results[1].Status)
+	assert.Equal(t, "API key invalid", results[1].Message)
+
+	assert.Equal(t, diagnosis.StatusWarning, results[2].Status)
+	assert.Equal(t, http.StatusInternalServerError, results[2].Details["status_code"])
+
+	assert.Equal(t, ts.URL, results[3].Target)
+	assert.Equal(t, diagnosis.StatusFail, results[3].Status)
+	assert.NotEmpty(t, results[3].Remediation)
+
+	assert.Equal(t, "http://localhost:1234 (API key ending with hable)", results[4].Target)
+	assert.Equal(t, diagnosis.StatusFail, results[4].Status)
+	assert.NotContains(t, results[4].Message, "unreachable")
+}
+
+func TestDiagnoseClockSkew(t *testing.T) {
+	var intakeTime time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", intakeTime.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		offset time.Duration
+		status diagnosis.Status
+	}{
+		{0, diagnosis.StatusPass},
+		{5 * time.Minute, diagnosis.StatusWarning},
+		{-5 * time.Minute, diagnosis.StatusWarning},
+		{time.Hour, diagnosis.StatusFail},
+	} {
+		intakeTime = time.Now().Add(tc.offset)
+		results := diagnoseClockSkewWithIntake(ts.URL, "key")
+		require.Len(t, results, 1)
+		assert.Equal(t, tc.status, results[0].Status, "offset %s", tc.offset)
+		assert.InDelta(t, -tc.offset.Seconds(), results[0].Details["skew_seconds"], 2)
+	}
+
+	ts.Close()
+	results := diagnoseClockSkewWithIntake(ts.URL, "key")
+	require.Len(t, results, 1)
+	assert.Equal(t, diagnosis.StatusSkipped, results[0].Status)
+}
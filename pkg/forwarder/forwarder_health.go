@@ -107,11 +107,15 @@ func (fh *forwarderHealth) healthCheckLoop() {
 }
 
 func (fh *forwarderHealth) setAPIKeyStatus(apiKey string, domain string, status expvar.Var) {
+	apiKeyStatus.Set(obfuscateAPIKey(apiKey), status)
+}
+
+// obfuscateAPIKey returns a description of an API key that can be displayed safely
+func obfuscateAPIKey(apiKey string) string {
 	if len(apiKey) > 5 {
 		apiKey = apiKey[len(apiKey)-5:]
 	}
-	obfuscatedKey := fmt.Sprintf("API key ending with %s", apiKey)
-	apiKeyStatus.Set(obfuscatedKey, status)
+	return fmt.Sprintf("API key ending with %s", apiKey)
 }
 
 func (fh *forwarderHealth) validateAPIKey(apiKey, domain string) (bool, error) {
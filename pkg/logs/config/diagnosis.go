@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/proxy"
+
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+)
+
+const diagnosisTimeout = 10 * time.Second
+
+func init() {
+	diagnosis.RegisterStructured("Logs endpoints reachability", diagnosis.CategoryConnectivity, diagnoseEndpoints)
+}
+
+// diagnoseEndpoints checks that the main logs endpoint can be reached both
+// over TCP and HTTP. Failures of the transport not in use are only reported
+// as warnings, to hint at switching transport.
+func diagnoseEndpoints() []diagnosis.Result {
+	if !coreConfig.Datadog.GetBool("logs_enabled") && !coreConfig.Datadog.GetBool("log_enabled") {
+		return []diagnosis.Result{{
+			Status:  diagnosis.StatusSkipped,
+			Message: "Logs collection is disabled",
+		}}
+	}
+	useHTTP := coreConfig.Datadog.GetBool("logs_config.use_http")
+
+	var results []diagnosis.Result
+	if endpoints, err := buildTCPEndpoints(); err != nil {
+		results = append(results, diagnosis.Result{
+			Status:      diagnosis.StatusFail,
+			Message:     fmt.Sprintf("Invalid TCP endpoint configuration: %s", err),
+			Remediation: "Check the `logs_config.logs_dd_url` setting, it must respect the format '<HOST>:<PORT>'.",
+		})
+	} else {
+		results = append(results, inUse(diagnoseTCPEndpoint(endpoints.Main), !useHTTP))
+	}
+
+	if endpoints, err := buildHTTPEndpoints(); err != nil {
+		results = append(results, diagnosis.Result{
+			Status:      diagnosis.StatusFail,
+			Message:     fmt.Sprintf("Invalid HTTP endpoint configuration: %s", err),
+			Remediation: "Check the `logs_config.logs_dd_url` setting, it must respect the format '<HOST>:<PORT>'.",
+		})
+	} else {
+		results = append(results, inUse(diagnoseHTTPEndpoint(endpoints.Main), useHTTP))
+	}
+
+	return results
+}
+
+// inUse records whether the transport of a result is the one configured,
+// and downgrades failures of the other transport to warnings
+func inUse(r diagnosis.Result, used bool) diagnosis.Result {
+	if r.Details == nil {
+		r.Details = make(map[string]interface{})
+	}
+	r.Details["in_use"] = used
+	if !used && r.Status == diagnosis.StatusFail {
+		r.Status = diagnosis.StatusWarning
+	}
+	return r
+}
+
+func diagnoseTCPEndpoint(endpoint Endpoint) diagnosis.Result {
+	address := net.JoinHostPort(endpoint.Host, strconv.Itoa(endpoint.Port))
+	result := diagnosis.Result{
+		Target: fmt.Sprintf("tcp://%s", address),
+		Details: map[string]interface{}{
+			"ssl":   endpoint.UseSSL,
+			"proxy": endpoint.ProxyAddress,
+		},
+	}
+
+	var conn net.Conn
+	var err error
+	if endpoint.ProxyAddress != "" {
+		var dialer proxy.Dialer
+		dialer, err = proxy.SOCKS5("tcp", endpoint.ProxyAddress, nil, &net.Dialer{Timeout: diagnosisTimeout})
+		if err == nil {
+			conn, err = dialer.Dial("tcp", address)
+		}
+	} else {
+		conn, err = net.DialTimeout("tcp", address, diagnosisTimeout)
+	}
+	if err != nil {
+		result.Status = diagnosis.StatusFail
+		result.Message = fmt.Sprintf("Unable to connect: %s", err)
+		result.Remediation = "Check that outbound traffic to this host and port is allowed, or set `logs_config.use_http: true` to send logs over HTTPS."
+		return result
+	}
+	defer conn.Close()
+
+	if endpoint.UseSSL {
+		sslConn := tls.Client(conn, &tls.Config{ServerName: endpoint.Host})
+		sslConn.SetDeadline(time.Now().Add(diagnosisTimeout))
+		if err := sslConn.Handshake(); err != nil {
+			result.Status = diagnosis.StatusFail
+			result.Message = fmt.Sprintf("SSL handshake failed: %s", err)
+			result.Remediation = "Check that no network device intercepts the TLS traffic to this host."
+			return result
+		}
+	}
+
+	result.Status = diagnosis.StatusPass
+	result.Message = "Connection successful"
+	return result
+}
+
+func diagnoseHTTPEndpoint(endpoint Endpoint) diagnosis.Result {
+	scheme := "http"
+	if endpoint.UseSSL {
+		scheme = "https"
+	}
+	address := endpoint.Host
+	if endpoint.Port != 0 {
+		address = net.JoinHostPort(endpoint.Host, strconv.Itoa(endpoint.Port))
+	}
+	result := diagnosis.Result{
+		Target: fmt.Sprintf("%s://%s", scheme, address),
+	}
+
+	client := &http.Client{
+		Timeout:   diagnosisTimeout,
+		Transport: httputils.CreateHTTPTransport(),
+	}
+	// an empty batch of logs
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s://%s/v1/input/%s", scheme, address, endpoint.APIKey), bytes.NewReader([]byte("[]")))
+	if err != nil {
+		result.Status = diagnosis.StatusFail
+		result.Message = fmt.Sprintf("Invalid endpoint: %s", httputils.SanitizeURL(err.Error()))
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Status = diagnosis.StatusFail
+		result.Message = fmt.Sprintf("Unable to send a request: %s", sanitizeAPIKey(err.Error(), endpoint.APIKey))
+		result.Remediation = "Check the network connectivity and the proxy settings of the host, the `Proxy settings` diagnosis may help."
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.Details = map[string]interface{}{"status_code": resp.StatusCode}
+	switch {
+	case resp.StatusCode == http.StatusForbidden:
+		result.Status = diagnosis.StatusFail
+		result.Message = "The intake rejected the API key"
+		result.Remediation = "Check the `api_key` or `logs_config.api_key` settings."
+	case resp.StatusCode >= 400:
+		result.Status = diagnosis.StatusWarning
+		result.Message = fmt.Sprintf("The intake is reachable but answered with status code %d", resp.StatusCode)
+	default:
+		result.Status = diagnosis.StatusPass
+		result.Message = "Connection successful"
+	}
+	return result
+}
+
+// sanitizeAPIKey removes the API key from a message, logs URLs carry it in their path
+func sanitizeAPIKey(message, apiKey string) string {
+	if len(apiKey) <= 5 {
+		return message
+	}
+	return string(bytes.Replace([]byte(message), []byte(apiKey), []byte("*************************"+apiKey[len(apiKey)-5:]), -1))
+}
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+)
+
+func TestDiagnoseTCPEndpoint(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+
+	r := diagnoseTCPEndpoint(Endpoint{Host: "127.0.0.1", Port: port})
+	assert.Equal(t, diagnosis.StatusPass, r.Status)
+	assert.Equal(t, "tcp://127.0.0.1:"+strconv.Itoa(port), r.Target)
+
+	l.Close()
+	r = diagnoseTCPEndpoint(Endpoint{Host: "127.0.0.1", Port: port})
+	assert.Equal(t, diagnosis.StatusFail, r.Status)
+	assert.NotEmpty(t, r.Remediation)
+}
+
+func TestDiagnoseHTTPEndpoint(t *testing.T) {
+	var path string
+	statusCode := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.WriteHeader(statusCode)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	host, portString, err := net.SplitHostPort(u.Host)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portString)
+	require.NoError(t, err)
+	endpoint := Endpoint{APIKey: "1234567890", Host: host, Port: port}
+
+	r := diagnoseHTTPEndpoint(endpoint)
+	assert.Equal(t, diagnosis.StatusPass, r.Status)
+	assert.Equal(t, "/v1/input/1234567890", path)
+	assert.Equal(t, ts.URL, r.Target)
+
+	statusCode = http.StatusForbidden
+	r = diagnoseHTTPEndpoint(endpoint)
+	assert.Equal(t, diagnosis.StatusFail, r.Status)
+
+	statusCode = http.StatusServiceUnavailable
+	r = diagnoseHTTPEndpoint(endpoint)
+	assert.Equal(t, diagnosis.StatusWarning, r.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, r.Details["status_code"])
+}
+
+func TestDiagnoseEndpointInUse(t *testing.T) {
+	failure := diagnosis.Result{Status: diagnosis.StatusFail}
+
+	r := inUse(failure, true)
+	assert.Equal(t, diagnosis.StatusFail, r.Status)
+	assert.Equal(t, true, r.Details["in_use"])
+
+	r = inUse(failure, false)
+	assert.Equal(t, diagnosis.StatusWarning, r.Status)
+	assert.Equal(t, false, r.Details["in_use"])
+}
+
+func TestSanitizeAPIKey(t *testing.T) {
+	assert.Equal(t, "POST https://host/v1/input/*************************67890: EOF", sanitizeAPIKey("POST https://host/v1/input/1234567890: EOF", "1234567890"))
+}
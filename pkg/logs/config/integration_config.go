@@ -7,6 +7,8 @@ package config
 
 import (
 	"fmt"
+
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 )
 
 // Logs source types
@@ -27,8 +29,12 @@ type LogsConfig struct {
 	Port int    // Network
 	Path string // File, Journald
 
-	IncludeUnits []string `mapstructure:"include_units" json:"include_units"` // Journald
-	ExcludeUnits []string `mapstructure:"exclude_units" json:"exclude_units"` // Journald
+	IncludeUnits    []string          `mapstructure:"include_units" json:"include_units"`       // Journald
+	ExcludeUnits    []string          `mapstructure:"exclude_units" json:"exclude_units"`       // Journald
+	IncludeMatches  []string          `mapstructure:"include_matches" json:"include_matches"`   // Journald
+	ExcludeMatches  []string          `mapstructure:"exclude_matches" json:"exclude_matches"`   // Journald
+	FieldAttributes map[string]string `mapstructure:"field_attributes" json:"field_attributes"` // Journald
+	ConfigID        string            `mapstructure:"config_id" json:"config_id"`               // Journald
 
 	Image      string // Docker
 	Label      string // Docker
@@ -42,7 +48,19 @@ type LogsConfig struct {
 	Source          string
 	SourceCategory  string
 	Tags            []string
-	ProcessingRules []*ProcessingRule `mapstructure:"log_processing_rules" json:"log_processing_rules"`
+	ProcessingRules []*ProcessingRule  `mapstructure:"log_processing_rules" json:"log_processing_rules"`
+	Throttling      *ThrottlingConfig  `mapstructure:"throttling" json:"throttling"`
+	AutoMultiLine   *bool              `mapstructure:"auto_multi_line_detection" json:"auto_multi_line_detection"`
+	JSONParsing     *JSONParsingConfig `mapstructure:"json_parsing" json:"json_parsing"`
+}
+
+// AutoMultiLineEnabled returns true if the start-of-record pattern of the multi-line logs
+// must be detected, the setting of the source overrides the global one.
+func (c *LogsConfig) AutoMultiLineEnabled() bool {
+	if c.AutoMultiLine != nil {
+		return *c.AutoMultiLine
+	}
+	return coreConfig.Datadog.GetBool("logs_config.auto_multi_line_detection")
 }
 
 // Validate returns an error if the config is misconfigured
@@ -60,6 +78,16 @@ func (c *LogsConfig) Validate() error {
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
 	}
+	if c.Throttling != nil {
+		if err := c.Throttling.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.JSONParsing != nil {
+		if err := c.JSONParsing.Validate(); err != nil {
+			return err
+		}
+	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
 		return err
@@ -20,6 +20,8 @@ func TestValidateShouldSucceedWithValidConfigs(t *testing.T) {
 		{Type: UDPType, Port: 5678},
 		{Type: DockerType},
 		{Type: JournaldType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch, Pattern: ".*"}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: SampleAtMatch, Pattern: ".*", SampleRate: 10}}},
+		{Type: DockerType, Throttling: &ThrottlingConfig{MaxMessagesPerSecond: 100, MessagesBurst: 500}},
 	}
 
 	for _, config := range validConfigs {
@@ -41,6 +43,8 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Type: ExcludeAtMatch, Pattern: ".*"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Type: ExcludeAtMatch}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Pattern: ".*"}}},
+		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: SampleAtMatch, Pattern: ".*"}}},
+		{Type: DockerType, Throttling: &ThrottlingConfig{MaxBytesPerSecond: -1}},
 	}
 
 	for _, config := range invalidConfigs {
@@ -0,0 +1,37 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+)
+
+// JSONParsingConfig enables the parsing of the JSON logs of a source, the
+// attributes are referenced by their path, e.g. `http.status_code`.
+type JSONParsingConfig struct {
+	StatusAttribute    string            `mapstructure:"status_attribute" json:"status_attribute"`
+	TimestampAttribute string            `mapstructure:"timestamp_attribute" json:"timestamp_attribute"`
+	ServiceAttribute   string            `mapstructure:"service_attribute" json:"service_attribute"`
+	HostAttribute      string            `mapstructure:"host_attribute" json:"host_attribute"`
+	TagAttributes      []string          `mapstructure:"tag_attributes" json:"tag_attributes"`
+	DropAttributes     []string          `mapstructure:"drop_attributes" json:"drop_attributes"`
+	RenameAttributes   map[string]string `mapstructure:"rename_attributes" json:"rename_attributes"`
+}
+
+// Validate returns an error if the JSON parsing is misconfigured.
+func (c *JSONParsingConfig) Validate() error {
+	for _, attribute := range append(c.TagAttributes, c.DropAttributes...) {
+		if attribute == "" {
+			return fmt.Errorf("json parsing attributes can't be empty")
+		}
+	}
+	for from, to := range c.RenameAttributes {
+		if from == "" || to == "" {
+			return fmt.Errorf("json parsing can't rename attribute `%s` to `%s`", from, to)
+		}
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	SampleAtMatch  = "sample_at_match"
 )
 
 // ProcessingRule defines an exclusion or a masking rule to
@@ -25,6 +26,8 @@ type ProcessingRule struct {
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	SampleRate         int      `mapstructure:"sample_rate" json:"sample_rate"`
+	Attributes         []string // restricts a mask_sequences rule to some attributes of the JSON logs
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
@@ -44,12 +47,20 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		switch rule.Type {
 		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
 			break
+		case SampleAtMatch:
+			if rule.SampleRate < 1 {
+				return fmt.Errorf("sample_rate must be at least 1 for processing rule `%s`", rule.Name)
+			}
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
 			return fmt.Errorf("type %s is not supported for processing rule `%s`", rule.Type, rule.Name)
 		}
 
+		if len(rule.Attributes) > 0 && rule.Type != MaskSequences {
+			return fmt.Errorf("attributes are only supported by mask_sequences, not by processing rule `%s`", rule.Name)
+		}
+
 		if rule.Pattern == "" {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
@@ -69,7 +80,7 @@ func CompileProcessingRules(rules []*ProcessingRule) error {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, SampleAtMatch:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
@@ -34,11 +34,13 @@ type LogSource struct {
 	// that reads log lines for this source. E.g, a sourceType == containerd and Config.Type == file means that
 	// the agent is tailing a file to read logs of a containerd container
 	sourceType SourceType
+	// throttler is shared by all the pipelines handling the messages of this source
+	throttler *Throttler
 }
 
 // NewLogSource creates a new log source.
 func NewLogSource(name string, config *LogsConfig) *LogSource {
-	return &LogSource{
+	source := &LogSource{
 		Name:     name,
 		Config:   config,
 		Status:   NewLogStatus(),
@@ -46,6 +48,10 @@ func NewLogSource(name string, config *LogsConfig) *LogSource {
 		lock:     &sync.Mutex{},
 		Messages: NewMessages(),
 	}
+	if config != nil {
+		source.throttler = NewThrottler(config.Throttling)
+	}
+	return source
 }
 
 // AddInput registers an input as being handled by this source.
@@ -86,3 +92,9 @@ func (s *LogSource) GetSourceType() SourceType {
 	defer s.lock.Unlock()
 	return s.sourceType
 }
+
+// GetThrottler returns the throttler enforcing the throttling and the sampling
+// rules of this source, it is nil when the source has no configuration.
+func (s *LogSource) GetThrottler() *Throttler {
+	return s.throttler
+}
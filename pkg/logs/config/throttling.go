@@ -0,0 +1,218 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultSummaryInterval is the minimum interval between two summaries of the
+// messages dropped by the throttling of a source.
+const defaultSummaryInterval = 60 * time.Second
+
+// ThrottlingConfig limits the number of messages and bytes per second a source
+// can send, the burst is the number of messages or bytes that can be sent at
+// once after an idle period. A zero value disables the corresponding limit.
+type ThrottlingConfig struct {
+	MaxMessagesPerSecond float64 `mapstructure:"max_messages_per_second" json:"max_messages_per_second"`
+	MaxBytesPerSecond    float64 `mapstructure:"max_bytes_per_second" json:"max_bytes_per_second"`
+	MessagesBurst        int     `mapstructure:"messages_burst" json:"messages_burst"`
+	BytesBurst           int     `mapstructure:"bytes_burst" json:"bytes_burst"`
+	SummaryInterval      int     `mapstructure:"summary_interval" json:"summary_interval"` // in seconds
+}
+
+// Validate returns an error if the throttling is misconfigured.
+func (c *ThrottlingConfig) Validate() error {
+	switch {
+	case c.MaxMessagesPerSecond < 0 || c.MaxBytesPerSecond < 0:
+		return fmt.Errorf("throttling rates must be positive")
+	case c.MessagesBurst < 0 || c.BytesBurst < 0:
+		return fmt.Errorf("throttling bursts must be positive")
+	case c.SummaryInterval < 0:
+		return fmt.Errorf("throttling summary interval must be positive")
+	}
+	return nil
+}
+
+// tokenBucket allows a sustained rate of events with bursts up to its capacity.
+type tokenBucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	capacity := float64(burst)
+	if capacity < rate {
+		// the bucket must at least hold one second worth of events
+		capacity = rate
+	}
+	return &tokenBucket{
+		rate:     rate,
+		capacity: capacity,
+		tokens:   capacity,
+		last:     now,
+	}
+}
+
+// refill adds the tokens earned since the last refill.
+func (b *tokenBucket) refill(now time.Time) {
+	if b == nil {
+		return
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.last = now
+	}
+}
+
+// cost returns the number of tokens needed for n events, events bigger than
+// the capacity only need a full bucket.
+func (b *tokenBucket) cost(n int) float64 {
+	if float64(n) > b.capacity {
+		return b.capacity
+	}
+	return float64(n)
+}
+
+func (b *tokenBucket) has(n int) bool {
+	return b == nil || b.tokens >= b.cost(n)
+}
+
+func (b *tokenBucket) take(n int) {
+	if b != nil {
+		b.tokens -= b.cost(n)
+	}
+}
+
+// Throttler enforces the throttling and the sampling rules of a source,
+// the messages of a source can be handled by several pipelines so it is
+// designed to be thread safe.
+type Throttler struct {
+	lock            sync.Mutex
+	messages        *tokenBucket
+	bytes           *tokenBucket
+	summaryInterval time.Duration
+	lastSummary     time.Time
+	// number of messages and bytes dropped since the last summary
+	pendingMessages int64
+	pendingBytes    int64
+	throttled       int64
+	sampled         int64
+	samplingCounts  map[*ProcessingRule]int64
+	now             func() time.Time
+}
+
+// NewThrottler returns a throttler enforcing the given configuration,
+// a nil configuration only enables the sampling.
+func NewThrottler(config *ThrottlingConfig) *Throttler {
+	return newThrottler(config, time.Now)
+}
+
+func newThrottler(config *ThrottlingConfig, now func() time.Time) *Throttler {
+	t := &Throttler{
+		summaryInterval: defaultSummaryInterval,
+		samplingCounts:  make(map[*ProcessingRule]int64),
+		now:             now,
+	}
+	t.lastSummary = now()
+	if config != nil {
+		t.messages = newTokenBucket(config.MaxMessagesPerSecond, config.MessagesBurst, t.lastSummary)
+		t.bytes = newTokenBucket(config.MaxBytesPerSecond, config.BytesBurst, t.lastSummary)
+		if config.SummaryInterval > 0 {
+			t.summaryInterval = time.Duration(config.SummaryInterval) * time.Second
+		}
+	}
+	return t
+}
+
+// Allow returns true if a message of the given size can be sent,
+// false if it must be dropped.
+func (t *Throttler) Allow(size int) bool {
+	if t == nil || (t.messages == nil && t.bytes == nil) {
+		return true
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	now := t.now()
+	t.messages.refill(now)
+	t.bytes.refill(now)
+	if !t.messages.has(1) || !t.bytes.has(size) {
+		t.throttled++
+		t.pendingMessages++
+		t.pendingBytes += int64(size)
+		return false
+	}
+	t.messages.take(1)
+	t.bytes.take(size)
+	return true
+}
+
+// Sample returns true for the first message out of every SampleRate messages
+// matching the rule, false if the message must be dropped.
+func (t *Throttler) Sample(rule *ProcessingRule) bool {
+	if t == nil || rule.SampleRate <= 1 {
+		return true
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	count := t.samplingCounts[rule]
+	t.samplingCounts[rule] = count + 1
+	if count%int64(rule.SampleRate) == 0 {
+		return true
+	}
+	t.sampled++
+	return false
+}
+
+// Summary returns a line stating how many messages were dropped by the
+// throttling when the summary interval has elapsed since the last one,
+// it returns false when there is nothing to report yet.
+func (t *Throttler) Summary() (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	now := t.now()
+	if t.pendingMessages == 0 || now.Sub(t.lastSummary) < t.summaryInterval {
+		return "", false
+	}
+	summary := fmt.Sprintf("Throttling suppressed %d log lines (%d bytes) in the last %s", t.pendingMessages, t.pendingBytes, now.Sub(t.lastSummary).Round(time.Second))
+	t.pendingMessages = 0
+	t.pendingBytes = 0
+	t.lastSummary = now
+	return summary, true
+}
+
+// Throttled returns the total number of messages dropped by the throttling.
+func (t *Throttler) Throttled() int64 {
+	if t == nil {
+		return 0
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.throttled
+}
+
+// Sampled returns the total number of messages dropped by the sampling rules.
+func (t *Throttler) Sampled() int64 {
+	if t == nil {
+		return 0
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.sampled
+}
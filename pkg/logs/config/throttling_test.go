@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestThrottlerWithoutLimits(t *testing.T) {
+	throttler := NewThrottler(nil)
+	for i := 0; i < 1000; i++ {
+		assert.True(t, throttler.Allow(1000))
+	}
+	assert.Equal(t, int64(0), throttler.Throttled())
+
+	var nilThrottler *Throttler
+	assert.True(t, nilThrottler.Allow(10))
+	assert.True(t, nilThrottler.Sample(&ProcessingRule{SampleRate: 10}))
+}
+
+func TestThrottlerMessagesRate(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	throttler := newThrottler(&ThrottlingConfig{MaxMessagesPerSecond: 2, MessagesBurst: 5}, clock.Now)
+
+	// the burst is allowed at once
+	for i := 0; i < 5; i++ {
+		assert.True(t, throttler.Allow(10))
+	}
+	assert.False(t, throttler.Allow(10))
+
+	// then the rate is enforced
+	clock.Add(time.Second)
+	assert.True(t, throttler.Allow(10))
+	assert.True(t, throttler.Allow(10))
+	assert.False(t, throttler.Allow(10))
+	assert.Equal(t, int64(2), throttler.Throttled())
+}
+
+func TestThrottlerBytesRate(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	throttler := newThrottler(&ThrottlingConfig{MaxMessagesPerSecond: 100, MaxBytesPerSecond: 100}, clock.Now)
+
+	assert.True(t, throttler.Allow(60))
+	assert.False(t, throttler.Allow(60))
+	// a message bigger than the burst only needs a full bucket
+	clock.Add(time.Second)
+	assert.True(t, throttler.Allow(500))
+	assert.False(t, throttler.Allow(1))
+	assert.Equal(t, int64(2), throttler.Throttled())
+}
+
+func TestThrottlerSummary(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	throttler := newThrottler(&ThrottlingConfig{MaxMessagesPerSecond: 1, SummaryInterval: 10}, clock.Now)
+
+	assert.True(t, throttler.Allow(10))
+	assert.False(t, throttler.Allow(10))
+	assert.False(t, throttler.Allow(20))
+
+	_, ok := throttler.Summary()
+	assert.False(t, ok)
+
+	clock.Add(10 * time.Second)
+	summary, ok := throttler.Summary()
+	assert.True(t, ok)
+	assert.Equal(t, "Throttling suppressed 2 log lines (30 bytes) in the last 10s", summary)
+
+	// nothing was dropped since the last summary
+	clock.Add(10 * time.Second)
+	_, ok = throttler.Summary()
+	assert.False(t, ok)
+	assert.Equal(t, int64(2), throttler.Throttled())
+}
+
+func TestThrottlerSample(t *testing.T) {
+	throttler := NewThrottler(nil)
+	rule := &ProcessingRule{Type: SampleAtMatch, SampleRate: 3}
+	other := &ProcessingRule{Type: SampleAtMatch, SampleRate: 2}
+
+	var kept []bool
+	for i := 0; i < 7; i++ {
+		kept = append(kept, throttler.Sample(rule))
+	}
+	assert.Equal(t, []bool{true, false, false, true, false, false, true}, kept)
+
+	// the rules are sampled independently
+	assert.True(t, throttler.Sample(other))
+	assert.False(t, throttler.Sample(other))
+	assert.Equal(t, int64(5), throttler.Sampled())
+}
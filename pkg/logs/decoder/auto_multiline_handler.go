@@ -0,0 +1,207 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package decoder
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/parser"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// defaultAutoMultiLineSampleSize is the number of lines
+	// used to detect the start-of-record pattern of a source.
+	defaultAutoMultiLineSampleSize = 500
+	// defaultAutoMultiLineDetectionTimeout is the time after which the detection
+	// runs on the lines collected so far.
+	defaultAutoMultiLineDetectionTimeout = 5 * time.Second
+	// autoMultiLineMatchThreshold is the minimal share of the lines starting
+	// a record the detected pattern must match to be used.
+	autoMultiLineMatchThreshold = 0.75
+	// autoMultiLineMinStartRatio is the minimal share of all the sample lines
+	// the detected pattern must match, so that a few matching lines are not
+	// enough to aggregate all the others.
+	autoMultiLineMinStartRatio = 0.02
+	// autoMultiLineStatusKey is the key of the detection result in the status of the source.
+	autoMultiLineStatusKey = "AutoMultiLine"
+)
+
+// autoMultiLinePatterns are the common prefixes of the first line of a log
+// record, tried in order so that the most specific ones win the ties.
+var autoMultiLinePatterns = []*regexp.Regexp{
+	// 2019-01-21T13:41:59.123Z, 2019-01-21 13:41:59,123
+	regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}`),
+	// [2019-01-21 13:41:59]
+	regexp.MustCompile(`^\[\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}`),
+	// 2019/01/21 13:41:59
+	regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}`),
+	// Jan 21 13:41:59
+	regexp.MustCompile(`^[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}`),
+	// 21/Jan/2019:13:41:59
+	regexp.MustCompile(`^\[?\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2}`),
+	// I0121 13:41:59.123456
+	regexp.MustCompile(`^[IWEF]\d{4} \d{2}:\d{2}:\d{2}`),
+	// 13:41:59.123
+	regexp.MustCompile(`^\d{2}:\d{2}:\d{2}[.,]\d{3}`),
+	// ERROR, [WARN], INFO:
+	regexp.MustCompile(`^\[?(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|CRITICAL|FATAL|SEVERE)\b`),
+}
+
+// AutoMultiLineHandler collects the first lines of a source to detect
+// the pattern starting its records, then hands the lines over to
+// a MultiLineHandler using this pattern, or to a SingleLineHandler
+// when no pattern is detected with enough confidence.
+type AutoMultiLineHandler struct {
+	lineChan         chan []byte
+	outputChan       chan *Output
+	parser           parser.Parser
+	lineLimit        int
+	source           *config.LogSource
+	sampleSize       int
+	detectionTimeout time.Duration
+	flushTimeout     time.Duration
+	samples          [][]byte
+	handler          LineHandler
+}
+
+// NewAutoMultiLineHandler returns a new AutoMultiLineHandler.
+func NewAutoMultiLineHandler(outputChan chan *Output, parser parser.Parser, lineLimit int, source *config.LogSource, sampleSize int, detectionTimeout time.Duration, flushTimeout time.Duration) *AutoMultiLineHandler {
+	return &AutoMultiLineHandler{
+		lineChan:         make(chan []byte),
+		outputChan:       outputChan,
+		parser:           parser,
+		lineLimit:        lineLimit,
+		source:           source,
+		sampleSize:       sampleSize,
+		detectionTimeout: detectionTimeout,
+		flushTimeout:     flushTimeout,
+	}
+}
+
+// Handle forward lines to lineChan to process them.
+func (h *AutoMultiLineHandler) Handle(content []byte) {
+	h.lineChan <- content
+}
+
+// Stop stops the handler.
+func (h *AutoMultiLineHandler) Stop() {
+	close(h.lineChan)
+}
+
+// Start starts the handler.
+func (h *AutoMultiLineHandler) Start() {
+	go h.run()
+}
+
+// run collects the sample lines until there are enough of them or the detection
+// times out, then forwards all the lines to the handler picked by the detection.
+func (h *AutoMultiLineHandler) run() {
+	detectionTimer := time.NewTimer(h.detectionTimeout)
+	defer func() {
+		detectionTimer.Stop()
+		if h.handler == nil {
+			// the source stopped before the end of the detection
+			h.detect()
+		}
+		// the output channel is closed by the handler
+		h.handler.Stop()
+	}()
+	for h.handler == nil {
+		select {
+		case line, isOpen := <-h.lineChan:
+			if !isOpen {
+				return
+			}
+			h.samples = append(h.samples, line)
+			if len(h.samples) >= h.sampleSize {
+				h.detect()
+			}
+		case <-detectionTimer.C:
+			if len(h.samples) > 0 {
+				h.detect()
+			} else {
+				// detect on the first lines received instead
+				detectionTimer.Reset(h.detectionTimeout)
+			}
+		}
+	}
+	for line := range h.lineChan {
+		h.handler.Handle(line)
+	}
+}
+
+// detect picks the handler of the lines and forwards it the sample lines.
+func (h *AutoMultiLineHandler) detect() {
+	re, confidence := detectMultiLinePattern(h.samples, h.parser)
+	switch {
+	case len(h.samples) == 0:
+		// nothing to detect on, the source stopped without sending any line
+		h.handler = NewSingleLineHandler(h.outputChan, h.parser, h.lineLimit)
+	case re != nil:
+		log.Debugf("Auto multi-line detection picked pattern %s with a confidence of %.2f on %d lines for source %s", re, confidence, len(h.samples), h.source.Name)
+		h.setStatus(fmt.Sprintf("Auto multi-line detection: using pattern %s", re))
+		h.handler = NewMultiLineHandler(h.outputChan, re, h.flushTimeout, h.parser, h.lineLimit)
+	default:
+		log.Debugf("Auto multi-line detection found no pattern with enough confidence on %d lines for source %s", len(h.samples), h.source.Name)
+		h.setStatus("Auto multi-line detection: no pattern detected, lines are sent one by one")
+		h.handler = NewSingleLineHandler(h.outputChan, h.parser, h.lineLimit)
+	}
+	h.handler.Start()
+	for _, line := range h.samples {
+		h.handler.Handle(line)
+	}
+	h.samples = nil
+}
+
+func (h *AutoMultiLineHandler) setStatus(message string) {
+	if h.source != nil && h.source.Messages != nil {
+		h.source.Messages.AddMessage(autoMultiLineStatusKey, message)
+	}
+}
+
+// detectMultiLinePattern scores the patterns on the lines and returns
+// the one matching most of the lines that look like the start of a record,
+// or nil if none of them reaches the confidence threshold or if it matches
+// too few of the lines.
+func detectMultiLinePattern(lines [][]byte, parser parser.Parser) (*regexp.Regexp, float64) {
+	scores := make([]int, len(autoMultiLinePatterns))
+	starts := 0
+	for _, line := range lines {
+		content, _, _, err := parser.Parse(line)
+		if err != nil {
+			content = line
+		}
+		matched := false
+		for i, re := range autoMultiLinePatterns {
+			if re.Match(content) {
+				scores[i]++
+				matched = true
+			}
+		}
+		if matched {
+			starts++
+		}
+	}
+	if starts == 0 {
+		return nil, 0
+	}
+
+	best := 0
+	for i, score := range scores {
+		if score > scores[best] {
+			best = i
+		}
+	}
+	confidence := float64(scores[best]) / float64(starts)
+	if confidence < autoMultiLineMatchThreshold || float64(scores[best]) < autoMultiLineMinStartRatio*float64(len(lines)) {
+		return nil, confidence
+	}
+	return autoMultiLinePatterns[best], confidence
+}
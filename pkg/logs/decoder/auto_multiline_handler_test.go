@@ -0,0 +1,150 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package decoder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/parser"
+)
+
+var javaStackTrace = []string{
+	"2019-01-21 13:41:59,123 ERROR [main] Application - request failed",
+	"java.lang.NullPointerException: null",
+	"    at com.example.Handler.handle(Handler.java:42)",
+	"    at com.example.Server.run(Server.java:12)",
+	"2019-01-21 13:42:00,456 INFO [main] Application - request served",
+}
+
+func toLines(lines []string) [][]byte {
+	var result [][]byte
+	for _, line := range lines {
+		result = append(result, []byte(line))
+	}
+	return result
+}
+
+func TestDetectMultiLinePattern(t *testing.T) {
+	re, confidence := detectMultiLinePattern(toLines(javaStackTrace), parser.NoopParser)
+	assert.Equal(t, autoMultiLinePatterns[0], re)
+	assert.Equal(t, 1.0, confidence)
+
+	re, _ = detectMultiLinePattern(toLines([]string{
+		"[2019-01-21 13:41:59] production.ERROR: Undefined variable",
+		"#0 /var/www/app.php(12): handle()",
+		"[2019-01-21 13:42:01] production.INFO: done",
+	}), parser.NoopParser)
+	assert.Equal(t, autoMultiLinePatterns[1], re)
+
+	re, _ = detectMultiLinePattern(toLines([]string{
+		"ERROR: connection refused",
+		"Traceback (most recent call last):",
+		"  File \"app.py\", line 3, in <module>",
+		"INFO: retrying",
+	}), parser.NoopParser)
+	assert.Equal(t, autoMultiLinePatterns[len(autoMultiLinePatterns)-1], re)
+
+	// no line looks like the start of a record
+	re, confidence = detectMultiLinePattern(toLines([]string{"hello", "world"}), parser.NoopParser)
+	assert.Nil(t, re)
+	assert.Equal(t, 0.0, confidence)
+
+	// the records don't share a common prefix
+	re, confidence = detectMultiLinePattern(toLines([]string{
+		"2019-01-21 13:41:59 first",
+		"Jan 21 13:41:59 second",
+		"2019/01/21 13:41:59 third",
+		"I0121 13:41:59.123456 fourth",
+	}), parser.NoopParser)
+	assert.Nil(t, re)
+	assert.Equal(t, 0.25, confidence)
+
+	// a single line looks like the start of a record among many
+	lines := [][]byte{[]byte("INFO starting")}
+	for i := 0; i < 499; i++ {
+		lines = append(lines, []byte("hello world"))
+	}
+	re, _ = detectMultiLinePattern(lines, parser.NoopParser)
+	assert.Nil(t, re)
+
+	// enough lines start a record
+	for i := 0; i < 9; i++ {
+		lines[(i+1)*50] = []byte("INFO processing")
+	}
+	re, confidence = detectMultiLinePattern(lines, parser.NoopParser)
+	assert.Equal(t, autoMultiLinePatterns[len(autoMultiLinePatterns)-1], re)
+	assert.Equal(t, 1.0, confidence)
+}
+
+func TestAutoMultiLineHandlerAggregatesLines(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	outputChan := make(chan *Output, 10)
+	h := NewAutoMultiLineHandler(outputChan, parser.NoopParser, 1000, source, len(javaStackTrace), time.Minute, 10*time.Millisecond)
+	h.Start()
+
+	for _, line := range javaStackTrace {
+		h.Handle([]byte(line))
+	}
+
+	output := <-outputChan
+	assert.Equal(t, "2019-01-21 13:41:59,123 ERROR [main] Application - request failed\\njava.lang.NullPointerException: null\\n    at com.example.Handler.handle(Handler.java:42)\\n    at com.example.Server.run(Server.java:12)", string(output.Content))
+	output = <-outputChan
+	assert.Equal(t, javaStackTrace[4], string(output.Content))
+	assert.Equal(t, []string{"Auto multi-line detection: using pattern " + autoMultiLinePatterns[0].String()}, source.Messages.GetMessages())
+
+	// the following lines use the same pattern
+	h.Handle([]byte("2019-01-21 13:42:01,789 ERROR [main] Application - request failed"))
+	h.Handle([]byte("java.lang.IllegalStateException: closed"))
+	h.Stop()
+
+	output = <-outputChan
+	assert.Equal(t, "2019-01-21 13:42:01,789 ERROR [main] Application - request failed\\njava.lang.IllegalStateException: closed", string(output.Content))
+	_, isOpen := <-outputChan
+	assert.False(t, isOpen)
+}
+
+func TestAutoMultiLineHandlerFallsBackToSingleLine(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	outputChan := make(chan *Output, 10)
+	h := NewAutoMultiLineHandler(outputChan, parser.NoopParser, 1000, source, 100, 10*time.Millisecond, 10*time.Millisecond)
+	h.Start()
+
+	// the detection times out before the sample is complete
+	h.Handle([]byte("hello"))
+	h.Handle([]byte("world"))
+
+	output := <-outputChan
+	assert.Equal(t, "hello", string(output.Content))
+	output = <-outputChan
+	assert.Equal(t, "world", string(output.Content))
+	assert.Equal(t, []string{"Auto multi-line detection: no pattern detected, lines are sent one by one"}, source.Messages.GetMessages())
+
+	h.Handle([]byte("foo"))
+	output = <-outputChan
+	assert.Equal(t, "foo", string(output.Content))
+	h.Stop()
+}
+
+func TestAutoMultiLineHandlerStopsBeforeDetection(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	outputChan := make(chan *Output, 10)
+	h := NewAutoMultiLineHandler(outputChan, parser.NoopParser, 1000, source, 100, time.Minute, 10*time.Millisecond)
+	h.Start()
+
+	h.Handle([]byte(javaStackTrace[0]))
+	h.Handle([]byte(javaStackTrace[1]))
+	h.Stop()
+
+	// the lines collected are still sent
+	output := <-outputChan
+	assert.Equal(t, javaStackTrace[0]+"\\n"+javaStackTrace[1], string(output.Content))
+	_, isOpen := <-outputChan
+	assert.False(t, isOpen)
+}
@@ -72,6 +72,9 @@ func NewDecoderWithEndLineMatcher(source *config.LogSource, parser parser.Parser
 			lineHandler = NewMultiLineHandler(outputChan, rule.Regex, defaultFlushTimeout, parser, lineLimit)
 		}
 	}
+	if lineHandler == nil && source.Config.AutoMultiLineEnabled() {
+		lineHandler = NewAutoMultiLineHandler(outputChan, parser, lineLimit, source, defaultAutoMultiLineSampleSize, defaultAutoMultiLineDetectionTimeout, defaultFlushTimeout)
+	}
 	if lineHandler == nil {
 		lineHandler = NewSingleLineHandler(outputChan, parser, lineLimit)
 	}
@@ -45,12 +45,14 @@ func (l *Launcher) run() {
 	for {
 		select {
 		case source := <-l.sources:
-			identifier := source.Config.Path
+			tailer := NewTailer(source, l.pipelineProvider.NextPipelineChan())
+			identifier := tailer.Identifier()
 			if _, exists := l.tailers[identifier]; exists {
-				// set up only one tailer per journal
+				// set up only one tailer per journal and config_id
+				log.Warnf("A journald source already tails %s, set a different config_id to tail it with other filters", identifier)
 				continue
 			}
-			tailer, err := l.setupTailer(source)
+			err := l.startTailer(tailer)
 			if err != nil {
 				log.Warn("Could not set up journald tailer: ", err)
 			} else {
@@ -73,14 +75,9 @@ func (l *Launcher) Stop() {
 	stopper.Stop()
 }
 
-// setupTailer configures and starts a new tailer,
-// returns the tailer or an error.
-func (l *Launcher) setupTailer(source *config.LogSource) (*Tailer, error) {
-	tailer := NewTailer(source, l.pipelineProvider.NextPipelineChan())
+// startTailer starts a new tailer from the cursor of its journal and config_id,
+// returns an error if the tailer could not be started.
+func (l *Launcher) startTailer(tailer *Tailer) error {
 	cursor := l.registry.GetOffset(tailer.Identifier())
-	err := tailer.Start(cursor)
-	if err != nil {
-		return nil, err
-	}
-	return tailer, nil
+	return tailer.Start(cursor)
 }
@@ -11,6 +11,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/coreos/go-systemd/sdjournal"
@@ -28,7 +30,7 @@ type Tailer struct {
 	source     *config.LogSource
 	outputChan chan *message.Message
 	journal    *sdjournal.Journal
-	blacklist  map[string]bool
+	exclusions map[string]map[string]bool
 	stop       chan struct{}
 	done       chan struct{}
 }
@@ -95,15 +97,84 @@ func (t *Tailer) setup() error {
 		}
 	}
 
-	t.blacklist = make(map[string]bool)
+	for _, includeMatch := range config.IncludeMatches {
+		// the matches of the same field are OR'ed, the matches of different fields are AND'ed.
+		field, values, err := parseMatch(includeMatch)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			match := field + "=" + value
+			if err := t.journal.AddMatch(match); err != nil {
+				return fmt.Errorf("could not add filter %s: %s", match, err)
+			}
+		}
+	}
+
+	t.exclusions = make(map[string]map[string]bool)
 	for _, unit := range config.ExcludeUnits {
 		// add filters to drop all the logs related to units to exclude.
-		t.blacklist[unit] = true
+		t.addExclusion(sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT, unit)
+	}
+	for _, excludeMatch := range config.ExcludeMatches {
+		// add filters to drop all the logs matching any of the exclusions.
+		field, values, err := parseMatch(excludeMatch)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			t.addExclusion(field, value)
+		}
+	}
+
+	for field, attribute := range config.FieldAttributes {
+		if attribute == messageAttribute || attribute == journaldAttribute {
+			return fmt.Errorf("could not forward the journal field %s as %s: the attribute is reserved", field, attribute)
+		}
 	}
 
 	return nil
 }
 
+// addExclusion drops the entries having the given value for the field.
+func (t *Tailer) addExclusion(field, value string) {
+	if _, exists := t.exclusions[field]; !exists {
+		t.exclusions[field] = make(map[string]bool)
+	}
+	t.exclusions[field][value] = true
+}
+
+// parseMatch parses a journal match, either FIELD=value or a range of priorities
+// such as PRIORITY<=4, and returns the field with the values it matches.
+func parseMatch(match string) (string, []string, error) {
+	for _, operator := range []string{"<=", ">="} {
+		i := strings.Index(match, operator)
+		if i == -1 {
+			continue
+		}
+		field := match[:i]
+		if field != sdjournal.SD_JOURNAL_FIELD_PRIORITY {
+			return "", nil, fmt.Errorf("invalid journal match %s: only %s supports %s", match, sdjournal.SD_JOURNAL_FIELD_PRIORITY, operator)
+		}
+		bound, err := strconv.Atoi(match[i+len(operator):])
+		if err != nil || bound < 0 || bound > 7 {
+			return "", nil, fmt.Errorf("invalid journal match %s: the priority must be between 0 and 7", match)
+		}
+		var values []string
+		for priority := 0; priority <= 7; priority++ {
+			if (operator == "<=" && priority <= bound) || (operator == ">=" && priority >= bound) {
+				values = append(values, strconv.Itoa(priority))
+			}
+		}
+		return field, values, nil
+	}
+	i := strings.Index(match, "=")
+	if i < 1 {
+		return "", nil, fmt.Errorf("invalid journal match %s: expected FIELD=value", match)
+	}
+	return match[:i], []string{match[i+1:]}, nil
+}
+
 // seek seeks to the cursor if it is not empty or the end of the journal,
 // returns an error if the operation failed.
 func (t *Tailer) seek(cursor string) error {
@@ -160,13 +231,15 @@ func (t *Tailer) tail() {
 // shouldDrop returns true if the entry should be dropped,
 // returns false otherwise.
 func (t *Tailer) shouldDrop(entry *sdjournal.JournalEntry) bool {
-	unit, exists := entry.Fields[sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT]
-	if !exists {
-		return false
-	}
-	if _, blacklisted := t.blacklist[unit]; blacklisted {
-		// drop the entry
-		return true
+	for field, values := range t.exclusions {
+		value, exists := entry.Fields[field]
+		if !exists {
+			continue
+		}
+		if _, excluded := values[value]; excluded {
+			// drop the entry
+			return true
+		}
 	}
 	return false
 }
@@ -178,18 +251,28 @@ func (t *Tailer) toMessage(entry *sdjournal.JournalEntry) *message.Message {
 	return message.NewMessage(t.getContent(entry), t.getOrigin(entry), t.getStatus(entry))
 }
 
+// messageAttribute and journaldAttribute are the attributes holding the message
+// and the fields of the journal entry.
+const (
+	messageAttribute  = "message"
+	journaldAttribute = "journald"
+)
+
 // getContent returns all the fields of the entry as a json-string,
-// remapping "MESSAGE" into "message" and bundling all the other keys in a "journald" attribute.
+// remapping "MESSAGE" into "message", the fields of the field_attributes setting
+// into their attribute and bundling all the other keys in a "journald" attribute.
 // ex:
 // * journal-entry:
 //  {
 //    "MESSAGE": "foo",
 //    "_SYSTEMD_UNIT": "foo",
+//    "_PID": "12",
 //    ...
 //  }
-// * message-content:
+// * message-content, with field_attributes: {"_PID": "pid"}:
 //  {
 //    "message": "foo",
+//    "pid": "12",
 //    "journald": {
 //      "_SYSTEMD_UNIT": "foo",
 //      ...
@@ -197,17 +280,27 @@ func (t *Tailer) toMessage(entry *sdjournal.JournalEntry) *message.Message {
 //  }
 func (t *Tailer) getContent(entry *sdjournal.JournalEntry) []byte {
 	payload := make(map[string]interface{})
-	fields := entry.Fields
+	// copy the fields so that the origin and the status can still be computed from the entry
+	fields := make(map[string]string, len(entry.Fields))
+	for field, value := range entry.Fields {
+		fields[field] = value
+	}
 	if message, exists := fields[sdjournal.SD_JOURNAL_FIELD_MESSAGE]; exists {
-		payload["message"] = message
+		payload[messageAttribute] = message
 		delete(fields, sdjournal.SD_JOURNAL_FIELD_MESSAGE)
 	}
-	payload["journald"] = fields
+	for field, attribute := range t.source.Config.FieldAttributes {
+		if value, exists := fields[field]; exists {
+			payload[attribute] = value
+			delete(fields, field)
+		}
+	}
+	payload[journaldAttribute] = fields
 
 	content, err := json.Marshal(payload)
 	if err != nil {
 		// ensure the message has some content if the json encoding failed
-		value, _ := entry.Fields[sdjournal.SD_JOURNAL_FIELD_MESSAGE]
+		value, _ := payload[messageAttribute].(string)
 		content = []byte(value)
 	}
 
@@ -287,8 +380,12 @@ func (t *Tailer) getStatus(entry *sdjournal.JournalEntry) string {
 // it's used to override the source of the message and as a fingerprint to store the journal cursor.
 const journaldIntegration = "journald"
 
-// Identifier returns the unique identifier of the current journal being tailed.
+// Identifier returns the unique identifier of the current journal being tailed,
+// the config_id allows to tail the same journal from several sources.
 func (t *Tailer) Identifier() string {
+	if t.source.Config.ConfigID != "" {
+		return journaldIntegration + ":" + t.journalPath() + ":" + t.source.Config.ConfigID
+	}
 	return journaldIntegration + ":" + t.journalPath()
 }
 
@@ -30,6 +30,11 @@ func TestIdentifier(t *testing.T) {
 	source = config.NewLogSource("", &config.LogsConfig{Path: "any_path"})
 	tailer = NewTailer(source, nil)
 	assert.Equal(t, "journald:any_path", tailer.Identifier())
+
+	// expect identifier to include the config id
+	source = config.NewLogSource("", &config.LogsConfig{ConfigID: "errors"})
+	tailer = NewTailer(source, nil)
+	assert.Equal(t, "journald:default:errors", tailer.Identifier())
 }
 
 func TestShouldDropEntry(t *testing.T) {
@@ -60,6 +65,85 @@ func TestShouldDropEntry(t *testing.T) {
 		}))
 }
 
+func TestShouldDropEntryWithExcludeMatches(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{
+		ExcludeUnits:   []string{"foo"},
+		ExcludeMatches: []string{"_SYSTEMD_USER_UNIT=bar", "PRIORITY>=6"},
+	})
+	tailer := NewTailer(source, nil)
+	err := tailer.setup()
+	assert.Nil(t, err)
+
+	assert.True(t, tailer.shouldDrop(
+		&sdjournal.JournalEntry{
+			Fields: map[string]string{
+				sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT: "foo",
+				sdjournal.SD_JOURNAL_FIELD_PRIORITY:     "3",
+			},
+		}))
+
+	assert.True(t, tailer.shouldDrop(
+		&sdjournal.JournalEntry{
+			Fields: map[string]string{
+				"_SYSTEMD_USER_UNIT":                "bar",
+				sdjournal.SD_JOURNAL_FIELD_PRIORITY: "3",
+			},
+		}))
+
+	assert.True(t, tailer.shouldDrop(
+		&sdjournal.JournalEntry{
+			Fields: map[string]string{
+				sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT: "boo",
+				sdjournal.SD_JOURNAL_FIELD_PRIORITY:     "7",
+			},
+		}))
+
+	assert.False(t, tailer.shouldDrop(
+		&sdjournal.JournalEntry{
+			Fields: map[string]string{
+				sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT: "boo",
+				"_SYSTEMD_USER_UNIT":                    "baz",
+				sdjournal.SD_JOURNAL_FIELD_PRIORITY:     "5",
+			},
+		}))
+}
+
+func TestParseMatch(t *testing.T) {
+	field, values, err := parseMatch("SYSLOG_IDENTIFIER=sshd")
+	assert.Nil(t, err)
+	assert.Equal(t, "SYSLOG_IDENTIFIER", field)
+	assert.Equal(t, []string{"sshd"}, values)
+
+	// the value may contain an equal sign
+	field, values, err = parseMatch("MESSAGE=a=b")
+	assert.Nil(t, err)
+	assert.Equal(t, "MESSAGE", field)
+	assert.Equal(t, []string{"a=b"}, values)
+
+	field, values, err = parseMatch("PRIORITY<=4")
+	assert.Nil(t, err)
+	assert.Equal(t, "PRIORITY", field)
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, values)
+
+	field, values, err = parseMatch("PRIORITY>=6")
+	assert.Nil(t, err)
+	assert.Equal(t, "PRIORITY", field)
+	assert.Equal(t, []string{"6", "7"}, values)
+
+	for _, match := range []string{"", "PRIORITY", "=foo", "_PID<=12", "PRIORITY<=8", "PRIORITY>=err"} {
+		_, _, err = parseMatch(match)
+		assert.NotNil(t, err, match)
+	}
+}
+
+func TestSetupWithReservedFieldAttribute(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{
+		FieldAttributes: map[string]string{"_PID": "message"},
+	})
+	tailer := NewTailer(source, nil)
+	assert.NotNil(t, tailer.setup())
+}
+
 func TestApplicationName(t *testing.T) {
 	source := config.NewLogSource("", &config.LogsConfig{})
 	tailer := NewTailer(source, nil)
@@ -119,6 +203,55 @@ func TestContent(t *testing.T) {
 		}))
 }
 
+func TestContentWithFieldAttributes(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{
+		FieldAttributes: map[string]string{
+			"_PID":               "pid",
+			"_SYSTEMD_USER_UNIT": "user_unit",
+			"CODE_FUNC":          "function",
+		},
+	})
+	tailer := NewTailer(source, nil)
+
+	assert.Equal(t, []byte(`{"journald":{"_A":"foo.service"},"message":"bar","pid":"12","user_unit":"baz.service"}`), tailer.getContent(
+		&sdjournal.JournalEntry{
+			Fields: map[string]string{
+				sdjournal.SD_JOURNAL_FIELD_MESSAGE: "bar",
+				"_A":                               "foo.service",
+				"_PID":                             "12",
+				"_SYSTEMD_USER_UNIT":               "baz.service",
+			},
+		}))
+}
+
+func TestToMessageWithFieldAttributes(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{
+		FieldAttributes: map[string]string{
+			sdjournal.SD_JOURNAL_FIELD_PRIORITY:          "level",
+			sdjournal.SD_JOURNAL_FIELD_SYSLOG_IDENTIFIER: "app",
+		},
+	})
+	tailer := NewTailer(source, nil)
+	err := tailer.setup()
+	assert.Nil(t, err)
+
+	entry := &sdjournal.JournalEntry{
+		Fields: map[string]string{
+			sdjournal.SD_JOURNAL_FIELD_MESSAGE:           "bar",
+			sdjournal.SD_JOURNAL_FIELD_PRIORITY:          "3",
+			sdjournal.SD_JOURNAL_FIELD_SYSLOG_IDENTIFIER: "foo",
+		},
+	}
+	msg := tailer.toMessage(entry)
+
+	assert.Equal(t, []byte(`{"app":"foo","journald":{},"level":"3","message":"bar"}`), msg.Content)
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, "foo", msg.Origin.Service())
+	assert.Equal(t, "foo", msg.Origin.Source())
+	// the journal entry is left untouched
+	assert.Len(t, entry.Fields, 3)
+}
+
 func TestSeverity(t *testing.T) {
 	source := config.NewLogSource("", &config.LogsConfig{})
 	tailer := NewTailer(source, nil)
@@ -9,9 +9,12 @@ package kubernetes
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/tagger"
 	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
@@ -28,6 +31,12 @@ const (
 
 var collectAllDisabledError = fmt.Errorf("%s disabled", config.ContainerCollectAll)
 
+// For testing purpose
+var logsDirScanPeriod = 10 * time.Second
+
+// kubeletHealthPath is the path of the kubelet health endpoint.
+const kubeletHealthPath = "/healthz"
+
 // Launcher looks for new and deleted pods to create or delete one logs-source per container.
 type Launcher struct {
 	sources            *config.LogSources
@@ -37,6 +46,17 @@ type Launcher struct {
 	addedServices      chan *service.Service
 	removedServices    chan *service.Service
 	collectAll         bool
+	// scanLogsDir is true when the pods can't be retrieved from the kubelet,
+	// the sources are then created from the content of the logs directory
+	scanLogsDir bool
+	// logsDirFallback is true when the logs directory is scanned while the kubelet can't be reached
+	logsDirFallback bool
+	// sourcesByDirectory holds the sources created from the logs directory by path
+	sourcesByDirectory map[string]*config.LogSource
+	// pendingServices holds the services received while the kubelet couldn't be reached
+	pendingServices map[string]*service.Service
+	// checkKubelet returns the kubelet client if the kubelet can be reached
+	checkKubelet func() (*kubelet.KubeUtil, error)
 }
 
 // NewLauncher returns a new launcher.
@@ -44,17 +64,25 @@ func NewLauncher(sources *config.LogSources, services *service.Services, collect
 	if !isIntegrationAvailable() {
 		return nil, fmt.Errorf("%s not found", basePath)
 	}
-	kubeutil, err := kubelet.GetKubeUtil()
-	if err != nil {
-		return nil, err
-	}
 	launcher := &Launcher{
 		sources:            sources,
 		sourcesByContainer: make(map[string]*config.LogSource),
 		stopped:            make(chan struct{}),
-		kubeutil:           kubeutil,
 		collectAll:         collectAll,
+		logsDirFallback:    coreConfig.Datadog.GetBool("logs_config.k8s_logs_dir_fallback"),
+		sourcesByDirectory: make(map[string]*config.LogSource),
+		pendingServices:    make(map[string]*service.Service),
+		checkKubelet:       checkKubelet,
+	}
+	kubeutil, err := kubelet.GetKubeUtil()
+	if err != nil {
+		if !launcher.logsDirFallback {
+			return nil, err
+		}
+		log.Warnf("Could not reach the kubelet, the container logs will be collected from %s: %v", basePath, err)
+		launcher.scanLogsDir = true
 	}
+	launcher.kubeutil = kubeutil
 	err = launcher.setup()
 	if err != nil {
 		return nil, err
@@ -64,6 +92,22 @@ func NewLauncher(sources *config.LogSources, services *service.Services, collect
 	return launcher, nil
 }
 
+// checkKubelet returns the kubelet client if the kubelet answers on its health endpoint.
+func checkKubelet() (*kubelet.KubeUtil, error) {
+	kubeutil, err := kubelet.GetKubeUtil()
+	if err != nil {
+		return nil, err
+	}
+	_, code, err := kubeutil.QueryKubelet(kubeletHealthPath)
+	if err != nil {
+		return nil, err
+	}
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d on %s", code, kubeletHealthPath)
+	}
+	return kubeutil, nil
+}
+
 func isIntegrationAvailable() bool {
 	if _, err := os.Stat(basePath); err != nil {
 		return false
@@ -93,9 +137,21 @@ func (l *Launcher) Stop() {
 
 // run handles new and deleted pods,
 // the kubernetes launcher consumes new and deleted services pushed by the autodiscovery
+// or scans the logs directory when the kubelet is not reachable
 func (l *Launcher) run() {
+	var scan <-chan time.Time
+	if l.logsDirFallback {
+		ticker := time.NewTicker(logsDirScanPeriod)
+		defer ticker.Stop()
+		scan = ticker.C
+	}
+	if l.scanLogsDir {
+		l.scanLogsDirectory(basePath)
+	}
 	for {
 		select {
+		case <-scan:
+			l.updateFallback(basePath)
 		case service := <-l.addedServices:
 			l.addSource(service)
 		case service := <-l.removedServices:
@@ -107,6 +163,34 @@ func (l *Launcher) run() {
 	}
 }
 
+// updateFallback checks whether the kubelet can be reached, it switches to the logs
+// directory when the kubelet stops answering and back to the kubelet when it answers again.
+func (l *Launcher) updateFallback(basePath string) {
+	kubeutil, err := l.checkKubelet()
+	switch {
+	case err != nil && !l.scanLogsDir:
+		log.Warnf("Could not reach the kubelet, the container logs will be collected from %s: %v", basePath, err)
+		l.scanLogsDir = true
+	case err == nil && l.scanLogsDir:
+		log.Infof("The kubelet can be reached again, the container logs will be collected from its pods")
+		l.scanLogsDir = false
+		l.kubeutil = kubeutil
+		// the sources of the services replace the ones of the logs directory
+		for path, source := range l.sourcesByDirectory {
+			delete(l.sourcesByDirectory, path)
+			l.sources.RemoveSource(source)
+		}
+		pending := l.pendingServices
+		l.pendingServices = make(map[string]*service.Service)
+		for _, svc := range pending {
+			l.addSource(svc)
+		}
+	}
+	if l.scanLogsDir {
+		l.scanLogsDirectory(basePath)
+	}
+}
+
 // addSource creates a new log-source from a service by resolving the
 // pod linked to the entityID of the service
 func (l *Launcher) addSource(svc *service.Service) {
@@ -117,6 +201,12 @@ func (l *Launcher) addSource(svc *service.Service) {
 		return
 	}
 
+	if l.scanLogsDir {
+		// the pod of the container can't be retrieved until the kubelet answers again
+		l.pendingServices[svc.GetEntityID()] = svc
+		return
+	}
+
 	pod, err := l.kubeutil.GetPodForEntityID(svc.GetEntityID())
 	if err != nil {
 		log.Warnf("Could not add source for container %v: %v", svc.Identifier, err)
@@ -149,6 +239,7 @@ func (l *Launcher) addSource(svc *service.Service) {
 // removeSource removes a new log-source from a service
 func (l *Launcher) removeSource(service *service.Service) {
 	containerID := service.GetEntityID()
+	delete(l.pendingServices, containerID)
 	if source, exists := l.sourcesByContainer[containerID]; exists {
 		delete(l.sourcesByContainer, containerID)
 		l.sources.RemoveSource(source)
@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build kubelet
+
+package kubernetes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// noLogsWrittenError is returned for the containers which haven't written any log yet,
+// their directory is scanned again until the format of their logs can be detected.
+var noLogsWrittenError = fmt.Errorf("no logs written yet")
+
+// containerDirectory is the directory of the logs of a container,
+// since Kubernetes v1.14 it is `/var/log/pods/{pod_namespace}_{pod_name}_{pod_uid}/{container_name}`.
+type containerDirectory struct {
+	path          string
+	namespace     string
+	podName       string
+	podUID        string
+	containerName string
+}
+
+// parseContainerDirectory returns the pod and the container of a directory,
+// namespaces and pod names can't contain underscores so the pod directory
+// is made of exactly three parts.
+func parseContainerDirectory(path string) (*containerDirectory, error) {
+	podDirectory := filepath.Base(filepath.Dir(path))
+	parts := strings.Split(podDirectory, "_")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("%s is not a pod logs directory", podDirectory)
+	}
+	return &containerDirectory{
+		path:          path,
+		namespace:     parts[0],
+		podName:       parts[1],
+		podUID:        parts[2],
+		containerName: filepath.Base(path),
+	}, nil
+}
+
+// scanLogsDirectory creates a source for every new container directory
+// and removes the sources of the directories that don't exist anymore,
+// the directories of the containers collected through the kubelet are skipped.
+func (l *Launcher) scanLogsDirectory(basePath string) {
+	paths, err := filepath.Glob(filepath.Join(basePath, "*", "*"))
+	if err != nil {
+		log.Warnf("Could not list the container logs directories of %s: %v", basePath, err)
+		return
+	}
+
+	collected := make(map[string]bool)
+	for _, source := range l.sourcesByContainer {
+		collected[filepath.Dir(source.Config.Path)] = true
+	}
+
+	found := make(map[string]bool)
+	for _, path := range paths {
+		if info, err := os.Stat(path); err != nil || !info.IsDir() || collected[path] {
+			continue
+		}
+		directory, err := parseContainerDirectory(path)
+		if err != nil {
+			// the pod directories of Kubernetes v1.13 and before only contain
+			// the UID of the pod, they can't be collected without the kubelet
+			log.Debug(err)
+			continue
+		}
+		found[path] = true
+		if _, exists := l.sourcesByDirectory[path]; exists {
+			continue
+		}
+		source, err := l.getSourceFromDirectory(directory)
+		if err != nil {
+			if err != collectAllDisabledError && err != noLogsWrittenError {
+				log.Warnf("Invalid configuration for directory %s: %v", path, err)
+			}
+			continue
+		}
+		l.sourcesByDirectory[path] = source
+		l.sources.AddSource(source)
+	}
+
+	for path, source := range l.sourcesByDirectory {
+		if !found[path] {
+			delete(l.sourcesByDirectory, path)
+			l.sources.RemoveSource(source)
+		}
+	}
+}
+
+// getSourceFromDirectory returns a new source for the container of a directory, the pod annotations
+// aren't available without the kubelet so the container logs are only collected with collectAll.
+func (l *Launcher) getSourceFromDirectory(directory *containerDirectory) (*config.LogSource, error) {
+	if !l.collectAll {
+		return nil, collectAllDisabledError
+	}
+	sourceType, detected := detectSourceType(directory.path)
+	if !detected {
+		return nil, noLogsWrittenError
+	}
+	cfg := &config.LogsConfig{
+		Type:    config.FileType,
+		Path:    filepath.Join(directory.path, anyLogFile),
+		Source:  kubernetesIntegration,
+		Service: kubernetesIntegration,
+		Tags: []string{
+			"kube_namespace:" + directory.namespace,
+			"pod_name:" + directory.podName,
+			"kube_container_name:" + directory.containerName,
+		},
+		// the container ID is unknown, the tags of the pod are added
+		// as soon as the tagger can collect them
+		Identifier: kubelet.PodUIDToTaggerEntityName(directory.podUID),
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	source := config.NewLogSource(fmt.Sprintf("%s/%s/%s", directory.namespace, directory.podName, directory.containerName), cfg)
+	source.SetSourceType(sourceType)
+	return source, nil
+}
+
+// detectSourceType returns the format of the logs of a container: the docker runtime
+// writes JSON lines whereas the CRI runtimes write lines prefixed by a timestamp.
+// It returns false when all the log files of the container are still empty.
+func detectSourceType(path string) (config.SourceType, bool) {
+	files, _ := filepath.Glob(filepath.Join(path, anyLogFile))
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+		firstByte := make([]byte, 1)
+		n, _ := f.Read(firstByte)
+		f.Close()
+		if n == 1 {
+			if firstByte[0] == '{' {
+				return config.DockerSourceType, true
+			}
+			return config.KubernetesSourceType, true
+		}
+	}
+	return "", false
+}
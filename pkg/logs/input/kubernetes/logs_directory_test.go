@@ -0,0 +1,193 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build kubelet
+
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/service"
+)
+
+func TestParseContainerDirectory(t *testing.T) {
+	directory, err := parseContainerDirectory("/var/log/pods/default_nginx-6db489d4b7-x2xbh_3b9e4f70-6a2c-4d18-8e53-0c2d7a9f4b02/nginx")
+	require.NoError(t, err)
+	assert.Equal(t, "default", directory.namespace)
+	assert.Equal(t, "nginx-6db489d4b7-x2xbh", directory.podName)
+	assert.Equal(t, "3b9e4f70-6a2c-4d18-8e53-0c2d7a9f4b02", directory.podUID)
+	assert.Equal(t, "nginx", directory.containerName)
+
+	// directory of Kubernetes v1.13 and before
+	_, err = parseContainerDirectory("/var/log/pods/3b9e4f70-6a2c-4d18-8e53-0c2d7a9f4b02/nginx")
+	assert.Error(t, err)
+}
+
+func createContainerLogs(t *testing.T, basePath, podDirectory, container, content string) string {
+	path := filepath.Join(basePath, podDirectory, container)
+	require.NoError(t, os.MkdirAll(path, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "0.log"), []byte(content), 0644))
+	return path
+}
+
+func TestScanLogsDirectory(t *testing.T) {
+	basePath, err := ioutil.TempDir("", "pods")
+	require.NoError(t, err)
+	defer os.RemoveAll(basePath)
+
+	criPath := createContainerLogs(t, basePath, "default_web_uid1", "nginx", "2019-01-21T13:41:59.123456789Z stdout F hello\n")
+	dockerPath := createContainerLogs(t, basePath, "batch_report_uid2", "report", `{"log":"hello\n","stream":"stdout","time":"2019-01-21T13:41:59.123456789Z"}`+"\n")
+	createContainerLogs(t, basePath, "uid3", "legacy", "hello\n")
+
+	sources := config.NewLogSources()
+	launcher := &Launcher{
+		sources:            sources,
+		sourcesByContainer: make(map[string]*config.LogSource),
+		sourcesByDirectory: make(map[string]*config.LogSource),
+		collectAll:         true,
+		scanLogsDir:        true,
+	}
+
+	launcher.scanLogsDirectory(basePath)
+	assert.Len(t, launcher.sourcesByDirectory, 2)
+
+	source := launcher.sourcesByDirectory[criPath]
+	require.NotNil(t, source)
+	assert.Equal(t, "default/web/nginx", source.Name)
+	assert.Equal(t, filepath.Join(criPath, "*.log"), source.Config.Path)
+	assert.Equal(t, "kubernetes_pod_uid://uid1", source.Config.Identifier)
+	assert.Equal(t, []string{"kube_namespace:default", "pod_name:web", "kube_container_name:nginx"}, source.Config.Tags)
+	assert.Equal(t, config.KubernetesSourceType, source.GetSourceType())
+
+	source = launcher.sourcesByDirectory[dockerPath]
+	require.NotNil(t, source)
+	assert.Equal(t, "batch/report/report", source.Name)
+	assert.Equal(t, config.DockerSourceType, source.GetSourceType())
+
+	// the sources are only added once
+	launcher.scanLogsDirectory(basePath)
+	assert.Len(t, launcher.sourcesByDirectory, 2)
+	assert.Len(t, sources.GetSources(), 2)
+
+	// the sources of the deleted pods are removed
+	require.NoError(t, os.RemoveAll(filepath.Dir(dockerPath)))
+	launcher.scanLogsDirectory(basePath)
+	assert.Len(t, launcher.sourcesByDirectory, 1)
+	assert.Len(t, sources.GetSources(), 1)
+}
+
+func TestScanLogsDirectoryWaitsForLogs(t *testing.T) {
+	basePath, err := ioutil.TempDir("", "pods")
+	require.NoError(t, err)
+	defer os.RemoveAll(basePath)
+
+	path := createContainerLogs(t, basePath, "default_web_uid1", "nginx", "")
+
+	sources := config.NewLogSources()
+	launcher := &Launcher{
+		sources:            sources,
+		sourcesByContainer: make(map[string]*config.LogSource),
+		sourcesByDirectory: make(map[string]*config.LogSource),
+		collectAll:         true,
+		scanLogsDir:        true,
+	}
+
+	// the format of the logs can't be detected until the container writes some
+	launcher.scanLogsDirectory(basePath)
+	assert.Len(t, launcher.sourcesByDirectory, 0)
+	assert.Len(t, sources.GetSources(), 0)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "0.log"), []byte(`{"log":"hello\n","stream":"stdout","time":"2019-01-21T13:41:59.123456789Z"}`+"\n"), 0644))
+	launcher.scanLogsDirectory(basePath)
+	source := launcher.sourcesByDirectory[path]
+	require.NotNil(t, source)
+	assert.Equal(t, config.DockerSourceType, source.GetSourceType())
+	assert.Len(t, sources.GetSources(), 1)
+}
+
+func TestScanLogsDirectoryWithoutCollectAll(t *testing.T) {
+	basePath, err := ioutil.TempDir("", "pods")
+	require.NoError(t, err)
+	defer os.RemoveAll(basePath)
+	createContainerLogs(t, basePath, "default_web_uid1", "nginx", "")
+
+	launcher := &Launcher{
+		sources:            config.NewLogSources(),
+		sourcesByContainer: make(map[string]*config.LogSource),
+		sourcesByDirectory: make(map[string]*config.LogSource),
+		scanLogsDir:        true,
+	}
+	launcher.scanLogsDirectory(basePath)
+	assert.Len(t, launcher.sourcesByDirectory, 0)
+}
+
+func TestKubeletFallback(t *testing.T) {
+	basePath, err := ioutil.TempDir("", "pods")
+	require.NoError(t, err)
+	defer os.RemoveAll(basePath)
+
+	servicePath := createContainerLogs(t, basePath, "default_web_uid1", "nginx", "")
+	directoryPath := createContainerLogs(t, basePath, "batch_report_uid2", "report", "2019-01-21T13:41:59.123456789Z stdout F hello\n")
+
+	// the container of the web pod is collected through the kubelet
+	sources := config.NewLogSources()
+	serviceSource := config.NewLogSource("default/web/nginx", &config.LogsConfig{Type: config.FileType, Path: filepath.Join(servicePath, "*.log")})
+	sources.AddSource(serviceSource)
+
+	var kubeletErr error
+	launcher := &Launcher{
+		sources:            sources,
+		sourcesByContainer: map[string]*config.LogSource{"docker://c1": serviceSource},
+		sourcesByDirectory: make(map[string]*config.LogSource),
+		pendingServices:    make(map[string]*service.Service),
+		collectAll:         true,
+		logsDirFallback:    true,
+		checkKubelet: func() (*kubelet.KubeUtil, error) {
+			if kubeletErr != nil {
+				return nil, kubeletErr
+			}
+			return &kubelet.KubeUtil{}, nil
+		},
+	}
+
+	// nothing changes while the kubelet answers
+	launcher.updateFallback(basePath)
+	assert.False(t, launcher.scanLogsDir)
+	assert.Len(t, launcher.sourcesByDirectory, 0)
+
+	// the logs directory is scanned when the kubelet stops answering,
+	// the containers already collected are not tailed twice
+	kubeletErr = fmt.Errorf("connection refused")
+	launcher.updateFallback(basePath)
+	assert.True(t, launcher.scanLogsDir)
+	assert.Len(t, launcher.sourcesByDirectory, 1)
+	assert.NotNil(t, launcher.sourcesByDirectory[directoryPath])
+	assert.Len(t, sources.GetSources(), 2)
+
+	// the services received meanwhile wait for the kubelet
+	svc := service.NewService("docker", "c2", service.After)
+	launcher.addSource(svc)
+	assert.Len(t, launcher.pendingServices, 1)
+	launcher.removeSource(svc)
+	assert.Len(t, launcher.pendingServices, 0)
+
+	// the sources of the logs directory are removed once the kubelet answers again
+	kubeletErr = nil
+	launcher.updateFallback(basePath)
+	assert.False(t, launcher.scanLogsDir)
+	assert.Len(t, launcher.sourcesByDirectory, 0)
+	assert.Equal(t, []*config.LogSource{serviceSource}, sources.GetSources())
+}
@@ -5,13 +5,21 @@
 
 package message
 
-import "github.com/DataDog/datadog-agent/pkg/logs/config"
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
 
 // Message represents a log line sent to datadog, with its metadata
 type Message struct {
 	Content []byte
 	Origin  *Origin
 	status  string
+	// Timestamp and Hostname override the time of the processing
+	// and the hostname of the agent when set
+	Timestamp time.Time
+	Hostname  string
 }
 
 // NewMessageWithSource constructs message with content, status and log source.
@@ -36,3 +44,8 @@ func (m *Message) GetStatus() string {
 	}
 	return m.status
 }
+
+// SetStatus sets the status of the message.
+func (m *Message) SetStatus(status string) {
+	m.status = status
+}
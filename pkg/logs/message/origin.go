@@ -88,6 +88,12 @@ func (o *Origin) SetTags(tags []string) {
 	o.tags = tags
 }
 
+// AddTags adds some tags to the tags of the origin.
+func (o *Origin) AddTags(tags []string) {
+	// the tags of the origin can be shared with other origins
+	o.tags = append(append([]string{}, o.tags...), tags...)
+}
+
 // SetSource sets the source of the origin.
 func (o *Origin) SetSource(source string) {
 	o.source = source
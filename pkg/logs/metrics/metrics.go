@@ -24,6 +24,10 @@ var (
 	DestinationLogsDropped = expvar.Map{}
 	// BytesSent is the total number of sent bytes before encoding if any
 	BytesSent = expvar.Int{}
+	// LogsThrottled is the total number of logs dropped by the throttling of their source
+	LogsThrottled = expvar.Int{}
+	// LogsSampled is the total number of logs dropped by a sampling rule
+	LogsSampled = expvar.Int{}
 	// EncodedBytesSent is the total number of sent bytes after encoding if any
 	EncodedBytesSent = expvar.Int{}
 	// TODO: Add LogsCollected for the total number of collected logs.
@@ -38,4 +42,6 @@ func init() {
 	LogsExpvars.Set("DestinationLogsDropped", &DestinationLogsDropped)
 	LogsExpvars.Set("BytesSent", &BytesSent)
 	LogsExpvars.Set("EncodedBytesSent", &EncodedBytesSent)
+	LogsExpvars.Set("LogsThrottled", &LogsThrottled)
+	LogsExpvars.Set("LogsSampled", &LogsSampled)
 }
@@ -0,0 +1,291 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// timestampLayouts are the layouts of the string timestamps.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05,999",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// statuses maps the common names of the log levels to the message statuses.
+var statuses = map[string]string{
+	"emerg":       message.StatusEmergency,
+	"emergency":   message.StatusEmergency,
+	"alert":       message.StatusAlert,
+	"crit":        message.StatusCritical,
+	"critical":    message.StatusCritical,
+	"fatal":       message.StatusCritical,
+	"panic":       message.StatusCritical,
+	"err":         message.StatusError,
+	"error":       message.StatusError,
+	"warn":        message.StatusWarning,
+	"warning":     message.StatusWarning,
+	"notice":      message.StatusNotice,
+	"info":        message.StatusInfo,
+	"information": message.StatusInfo,
+	"debug":       message.StatusDebug,
+	"trace":       message.StatusDebug,
+}
+
+// syslogStatuses maps the syslog severities to the message statuses.
+var syslogStatuses = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// JSONLog is a JSON log parsed and remapped according to a JSONParsingConfig,
+// the promoted fields are empty when the attributes are missing or invalid.
+type JSONLog struct {
+	Content   []byte
+	Status    string
+	Timestamp time.Time
+	Service   string
+	Hostname  string
+	Tags      []string
+}
+
+// ParseJSON parses a JSON object and remaps its attributes, the mask_sequences
+// rules restricted to some attributes are applied on them only.
+// It returns an error when the content is not a valid JSON object.
+func ParseJSON(content []byte, c *config.JSONParsingConfig, rules []*config.ProcessingRule) (*JSONLog, error) {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, fmt.Errorf("content is not a JSON object")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	// keep the precision of the large integers
+	decoder.UseNumber()
+	var attributes map[string]interface{}
+	if err := decoder.Decode(&attributes); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("content contains more than one JSON value")
+	}
+
+	for _, rule := range rules {
+		if rule.Type != config.MaskSequences {
+			continue
+		}
+		for _, path := range rule.Attributes {
+			if value, found := getAttribute(attributes, path); found {
+				setAttribute(attributes, path, maskValue(value, rule))
+			}
+		}
+	}
+
+	parsed := &JSONLog{}
+	if value, found := getAttribute(attributes, c.StatusAttribute); found {
+		parsed.Status = toStatus(value)
+	}
+	if value, found := getAttribute(attributes, c.TimestampAttribute); found {
+		parsed.Timestamp = toTimestamp(value)
+	}
+	if value, found := getAttribute(attributes, c.ServiceAttribute); found {
+		parsed.Service = toString(value)
+	}
+	if value, found := getAttribute(attributes, c.HostAttribute); found {
+		parsed.Hostname = toString(value)
+	}
+	for _, path := range c.TagAttributes {
+		value, found := getAttribute(attributes, path)
+		if !found {
+			continue
+		}
+		if values, isArray := value.([]interface{}); isArray {
+			for _, v := range values {
+				parsed.Tags = appendTag(parsed.Tags, path, v)
+			}
+		} else {
+			parsed.Tags = appendTag(parsed.Tags, path, value)
+		}
+	}
+
+	for _, path := range c.DropAttributes {
+		deleteAttribute(attributes, path)
+	}
+	for from, to := range c.RenameAttributes {
+		if value, found := getAttribute(attributes, from); found {
+			deleteAttribute(attributes, from)
+			setAttribute(attributes, to, value)
+		}
+	}
+
+	// don't escape the HTML characters unlike json.Marshal
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(attributes); err != nil {
+		return nil, err
+	}
+	parsed.Content = bytes.TrimSuffix(buffer.Bytes(), []byte("\n"))
+	return parsed, nil
+}
+
+// getAttribute returns the value of an attribute given its path.
+func getAttribute(attributes map[string]interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return nil, false
+	}
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, isObject := attributes[key].(map[string]interface{})
+		if !isObject {
+			return nil, false
+		}
+		attributes = child
+	}
+	value, found := attributes[keys[len(keys)-1]]
+	return value, found
+}
+
+// setAttribute sets the value of an attribute given its path,
+// creating the intermediate objects if needed.
+func setAttribute(attributes map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, isObject := attributes[key].(map[string]interface{})
+		if !isObject {
+			child = make(map[string]interface{})
+			attributes[key] = child
+		}
+		attributes = child
+	}
+	attributes[keys[len(keys)-1]] = value
+}
+
+// deleteAttribute removes an attribute given its path.
+func deleteAttribute(attributes map[string]interface{}, path string) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, isObject := attributes[key].(map[string]interface{})
+		if !isObject {
+			return
+		}
+		attributes = child
+	}
+	delete(attributes, keys[len(keys)-1])
+}
+
+// maskValue applies a mask_sequences rule on all the strings of a value.
+func maskValue(value interface{}, rule *config.ProcessingRule) interface{} {
+	switch v := value.(type) {
+	case string:
+		return string(rule.Regex.ReplaceAllLiteral([]byte(v), rule.Placeholder))
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = maskValue(child, rule)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = maskValue(child, rule)
+		}
+	}
+	return value
+}
+
+func appendTag(tags []string, path string, value interface{}) []string {
+	if s := toString(value); s != "" {
+		return append(tags, path+":"+s)
+	}
+	return tags
+}
+
+// toString returns the string representation of a scalar value,
+// or an empty string for objects, arrays and null.
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// toStatus returns the status matching a level name or a syslog severity.
+func toStatus(value interface{}) string {
+	s := strings.ToLower(strings.TrimSpace(toString(value)))
+	if status, found := statuses[s]; found {
+		return status
+	}
+	if severity, err := strconv.Atoi(s); err == nil && severity >= 0 && severity < len(syslogStatuses) {
+		return syslogStatuses[severity]
+	}
+	return ""
+}
+
+// toTimestamp returns the time of a string timestamp or of a number of seconds,
+// milliseconds, microseconds or nanoseconds since the epoch.
+func toTimestamp(value interface{}) time.Time {
+	s := strings.TrimSpace(toString(value))
+	if epoch, err := strconv.ParseFloat(s, 64); err == nil {
+		return fromEpoch(epoch)
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// fromEpoch converts a timestamp in seconds, milliseconds, microseconds or
+// nanoseconds since the epoch, guessing the unit from its magnitude. The integer
+// and fractional parts are converted separately so that it can't overflow.
+func fromEpoch(epoch float64) time.Time {
+	if !(epoch > 0) || epoch >= math.MaxInt64 {
+		// negative, NaN or out of range
+		return time.Time{}
+	}
+	var perSecond int64
+	switch {
+	case epoch < 1e11:
+		// seconds
+		perSecond = 1
+	case epoch < 1e14:
+		// milliseconds
+		perSecond = 1e3
+	case epoch < 1e17:
+		// microseconds
+		perSecond = 1e6
+	default:
+		// nanoseconds
+		perSecond = 1e9
+	}
+	whole, frac := math.Modf(epoch)
+	units := int64(whole)
+	nsPerUnit := 1e9 / perSecond
+	nsec := (units%perSecond)*nsPerUnit + int64(frac*float64(nsPerUnit))
+	return time.Unix(units/perSecond, nsec)
+}
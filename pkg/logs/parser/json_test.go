@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package parser
+
+import (
+	"math"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestParseJSONPromotesAttributes(t *testing.T) {
+	c := &config.JSONParsingConfig{
+		StatusAttribute:    "level",
+		TimestampAttribute: "ts",
+		ServiceAttribute:   "app.name",
+		HostAttribute:      "host",
+		TagAttributes:      []string{"env", "teams", "missing"},
+	}
+	parsed, err := ParseJSON([]byte(`{"level":"WARNING","ts":"2019-01-21T13:41:59.123Z","app":{"name":"billing"},"host":"web-1","env":"prod","teams":["a","b"],"msg":"<slow> & late"}`), c, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, message.StatusWarning, parsed.Status)
+	assert.Equal(t, time.Date(2019, 1, 21, 13, 41, 59, 123000000, time.UTC), parsed.Timestamp)
+	assert.Equal(t, "billing", parsed.Service)
+	assert.Equal(t, "web-1", parsed.Hostname)
+	assert.Equal(t, []string{"env:prod", "teams:a", "teams:b"}, parsed.Tags)
+	// the promoted attributes are kept
+	assert.Equal(t, `{"app":{"name":"billing"},"env":"prod","host":"web-1","level":"WARNING","msg":"<slow> & late","teams":["a","b"],"ts":"2019-01-21T13:41:59.123Z"}`, string(parsed.Content))
+}
+
+func TestParseJSONDropsAndRenamesAttributes(t *testing.T) {
+	c := &config.JSONParsingConfig{
+		DropAttributes:   []string{"password", "http.headers"},
+		RenameAttributes: map[string]string{"msg": "message", "http.code": "http.status_code"},
+	}
+	parsed, err := ParseJSON([]byte(`{"msg":"login","password":"hunter2","http":{"code":401,"headers":{"a":"b"}},"id":12345678901234567890}`), c, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"http":{"status_code":401},"id":12345678901234567890,"message":"login"}`, string(parsed.Content))
+}
+
+func TestParseJSONMasksAttributes(t *testing.T) {
+	rule := &config.ProcessingRule{
+		Type:        config.MaskSequences,
+		Regex:       regexp.MustCompile(`\d{4}`),
+		Placeholder: []byte("[masked]"),
+		Attributes:  []string{"card", "user"},
+	}
+	parsed, err := ParseJSON([]byte(`{"card":"1234 5678","user":{"pin":"0000","age":42},"order":"1234"}`), &config.JSONParsingConfig{}, []*config.ProcessingRule{rule})
+	require.NoError(t, err)
+	assert.Equal(t, `{"card":"[masked] [masked]","order":"1234","user":{"age":42,"pin":"[masked]"}}`, string(parsed.Content))
+}
+
+func TestParseJSONRejectsInvalidContent(t *testing.T) {
+	for _, content := range []string{"", "hello", `["a"]`, `{"a":`, `{"a":1} {"b":2}`} {
+		_, err := ParseJSON([]byte(content), &config.JSONParsingConfig{}, nil)
+		assert.Error(t, err, content)
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	assert.Equal(t, message.StatusError, toStatus("ERR"))
+	assert.Equal(t, message.StatusCritical, toStatus("fatal"))
+	assert.Equal(t, message.StatusDebug, toStatus("trace"))
+	assert.Equal(t, message.StatusNotice, toStatus("5"))
+	assert.Equal(t, "", toStatus("verbose"))
+	assert.Equal(t, "", toStatus("8"))
+}
+
+func TestToTimestamp(t *testing.T) {
+	expected := time.Date(2019, 1, 21, 13, 41, 59, 0, time.UTC)
+	assert.True(t, expected.Equal(toTimestamp("1548078119")))
+	assert.True(t, expected.Equal(toTimestamp("1548078119000")))
+	assert.True(t, expected.Equal(toTimestamp("1548078119000000")))
+	assert.True(t, expected.Equal(toTimestamp("1548078119000000000")))
+	assert.True(t, expected.Equal(toTimestamp("2019-01-21 13:41:59")))
+	assert.True(t, expected.Equal(toTimestamp("2019-01-21T14:41:59+01:00")))
+	assert.True(t, toTimestamp("yesterday").IsZero())
+}
+
+func TestFromEpoch(t *testing.T) {
+	// the largest values of each unit don't overflow
+	assert.True(t, time.Unix(99999999999, 0).Equal(fromEpoch(99999999999)))
+	assert.True(t, time.Unix(99999999999, 999000000).Equal(fromEpoch(99999999999999)))
+	assert.True(t, time.Unix(99999999999, 999984000).Equal(fromEpoch(99999999999999984)))
+	assert.True(t, time.Unix(0, 9223372036854774784).Equal(fromEpoch(9223372036854774784)))
+
+	// the smallest values of each unit
+	assert.True(t, time.Unix(1e8, 0).Equal(fromEpoch(1e11)))
+	assert.True(t, time.Unix(1e8, 0).Equal(fromEpoch(1e14)))
+	assert.True(t, time.Unix(1e8, 0).Equal(fromEpoch(1e17)))
+
+	// the fractional part is kept
+	assert.True(t, time.Unix(1548078119, 500000000).Equal(fromEpoch(1548078119.5)))
+	assert.True(t, time.Unix(1548078119, 123500000).Equal(fromEpoch(1548078119123.5)))
+
+	// invalid values
+	assert.True(t, fromEpoch(0).IsZero())
+	assert.True(t, fromEpoch(-1).IsZero())
+	assert.True(t, fromEpoch(math.MaxInt64).IsZero())
+	assert.True(t, fromEpoch(1e30).IsZero())
+	assert.True(t, fromEpoch(math.Inf(1)).IsZero())
+	assert.True(t, fromEpoch(math.NaN()).IsZero())
+}
@@ -6,6 +6,7 @@
 package processor
 
 import (
+	"time"
 	"unicode"
 	"unicode/utf8"
 
@@ -45,3 +46,21 @@ func getHostname() string {
 	}
 	return hostname
 }
+
+// getMessageHostname returns the hostname of the message if set,
+// the name of the host otherwise.
+func getMessageHostname(msg *message.Message) string {
+	if msg.Hostname != "" {
+		return msg.Hostname
+	}
+	return getHostname()
+}
+
+// getMessageTimestamp returns the timestamp of the message if set,
+// the current time otherwise.
+func getMessageTimestamp(msg *message.Message) time.Time {
+	if !msg.Timestamp.IsZero() {
+		return msg.Timestamp.UTC()
+	}
+	return time.Now().UTC()
+}
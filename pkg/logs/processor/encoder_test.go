@@ -227,3 +227,30 @@ func TestEncoderToValidUTF8(t *testing.T) {
 	assert.Equal(t, "a���z", toValidUtf8([]byte("a\xed\xa0\x80z")))
 	assert.Equal(t, "a����z", toValidUtf8([]byte("a\xf0\x8f\xbf\xbfz")))
 }
+
+func TestEncodersUseMessageTimestampAndHostname(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	msg := newMessage([]byte("message"), source, "")
+	msg.Timestamp = time.Date(2019, 1, 21, 13, 41, 59, 0, time.UTC)
+	msg.Hostname = "web-1"
+
+	raw, err := RawEncoder.Encode(msg, []byte("message"))
+	assert.Nil(t, err)
+	parts := strings.Fields(string(raw))
+	assert.Equal(t, "2019-01-21T13:41:59", parts[1][:len("2019-01-21T13:41:59")])
+	assert.Equal(t, "web-1", parts[2])
+
+	encoded, err := JSONEncoder.Encode(msg, []byte("message"))
+	assert.Nil(t, err)
+	var payload jsonPayload
+	assert.Nil(t, json.Unmarshal(encoded, &payload))
+	assert.Equal(t, msg.Timestamp.UnixNano()/nanoToMillis, payload.Timestamp)
+	assert.Equal(t, "web-1", payload.Hostname)
+
+	encoded, err = ProtoEncoder.Encode(msg, []byte("message"))
+	assert.Nil(t, err)
+	log := &pb.Log{}
+	assert.Nil(t, log.Unmarshal(encoded))
+	assert.Equal(t, msg.Timestamp.UnixNano(), log.Timestamp)
+	assert.Equal(t, "web-1", log.Hostname)
+}
@@ -7,7 +7,6 @@ package processor
 
 import (
 	"encoding/json"
-	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
@@ -36,8 +35,8 @@ func (j *jsonEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte,
 	return json.Marshal(jsonPayload{
 		Message:   toValidUtf8(redactedMsg),
 		Status:    msg.GetStatus(),
-		Timestamp: time.Now().UTC().UnixNano() / nanoToMillis,
-		Hostname:  getHostname(),
+		Timestamp: getMessageTimestamp(msg).UnixNano() / nanoToMillis,
+		Hostname:  getMessageHostname(msg),
 		Service:   msg.Origin.Service(),
 		Source:    msg.Origin.Source(),
 		Tags:      msg.Origin.TagsToString(),
@@ -6,13 +6,20 @@
 package processor
 
 import (
+	"time"
+
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/parser"
 )
 
+// summaryCheckInterval is the interval at which the processor checks whether the
+// summaries of the messages dropped by the throttling are due.
+var summaryCheckInterval = time.Second
+
 // A Processor updates messages from an inputChan and pushes
 // in an outputChan.
 type Processor struct {
@@ -21,16 +28,19 @@ type Processor struct {
 	processingRules []*config.ProcessingRule
 	encoder         Encoder
 	done            chan struct{}
+	// sources which dropped messages that haven't been reported yet
+	throttledSources map[*config.LogSource]struct{}
 }
 
 // New returns an initialized Processor.
 func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder) *Processor {
 	return &Processor{
-		inputChan:       inputChan,
-		outputChan:      outputChan,
-		processingRules: processingRules,
-		encoder:         encoder,
-		done:            make(chan struct{}),
+		inputChan:        inputChan,
+		outputChan:       outputChan,
+		processingRules:  processingRules,
+		encoder:          encoder,
+		done:             make(chan struct{}),
+		throttledSources: make(map[*config.LogSource]struct{}),
 	}
 }
 
@@ -46,26 +56,71 @@ func (p *Processor) Stop() {
 	<-p.done
 }
 
-// run starts the processing of the inputChan
+// run starts the processing of the inputChan, the summaries of the throttled
+// sources are sent on a ticker so that they don't wait for the next message
 func (p *Processor) run() {
 	defer func() {
 		p.done <- struct{}{}
 	}()
-	for msg := range p.inputChan {
-		metrics.LogsDecoded.Add(1)
-		if shouldProcess, redactedMsg := p.applyRedactingRules(msg); shouldProcess {
-			metrics.LogsProcessed.Add(1)
-
-			// Encode the message to its final format
-			content, err := p.encoder.Encode(msg, redactedMsg)
-			if err != nil {
-				log.Error("unable to encode msg ", err)
-				continue
+	ticker := time.NewTicker(summaryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg, ok := <-p.inputChan:
+			if !ok {
+				return
 			}
-			msg.Content = content
-			p.outputChan <- msg
+			p.processMessage(msg)
+		case <-ticker.C:
+			p.sendSummaries()
+		}
+	}
+}
+
+// processMessage parses the JSON content of a message, applies the processing
+// rules and the throttling of its source to it and forwards it
+func (p *Processor) processMessage(msg *message.Message) {
+	metrics.LogsDecoded.Add(1)
+	if jsonParsing := msg.Origin.LogSource.Config.JSONParsing; jsonParsing != nil {
+		// the processing rules apply to the remapped content
+		p.applyJSONParsing(msg, jsonParsing)
+	}
+	if shouldProcess, redactedMsg := p.applyRedactingRules(msg); shouldProcess {
+		if !msg.Origin.LogSource.GetThrottler().Allow(len(redactedMsg)) {
+			metrics.LogsThrottled.Add(1)
+			p.throttledSources[msg.Origin.LogSource] = struct{}{}
+			return
 		}
+		metrics.LogsProcessed.Add(1)
+		p.process(msg, redactedMsg)
+	}
+}
+
+// sendSummaries sends the summaries of the throttled sources which are due
+func (p *Processor) sendSummaries() {
+	for source := range p.throttledSources {
+		if summary, ok := source.GetThrottler().Summary(); ok {
+			p.process(p.newSummaryMessage(source, summary), []byte(summary))
+			delete(p.throttledSources, source)
+		}
+	}
+}
+
+// process encodes the message to its final format and forwards it
+func (p *Processor) process(msg *message.Message, redactedMsg []byte) {
+	content, err := p.encoder.Encode(msg, redactedMsg)
+	if err != nil {
+		log.Error("unable to encode msg ", err)
+		return
 	}
+	msg.Content = content
+	p.outputChan <- msg
+}
+
+// newSummaryMessage returns a message reporting the lines suppressed by the
+// throttling of a source, it has no identifier so it is ignored by the auditor
+func (p *Processor) newSummaryMessage(source *config.LogSource, summary string) *message.Message {
+	return message.NewMessage([]byte(summary), message.NewOrigin(source), message.StatusWarning)
 }
 
 // applyRedactingRules returns given a message if we should process it or not,
@@ -83,9 +138,45 @@ func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 			if !rule.Regex.Match(content) {
 				return false, nil
 			}
+		case config.SampleAtMatch:
+			if rule.Regex.Match(content) && !msg.Origin.LogSource.GetThrottler().Sample(rule) {
+				metrics.LogsSampled.Add(1)
+				return false, nil
+			}
 		case config.MaskSequences:
+			if len(rule.Attributes) > 0 {
+				// only applied on the attributes of the JSON logs by the JSON parsing
+				continue
+			}
 			content = rule.Regex.ReplaceAllLiteral(content, rule.Placeholder)
 		}
 	}
 	return true, content
 }
+
+// applyJSONParsing parses the JSON content of a message, promotes its attributes
+// to the fields of the message and replaces its content with the remapped one.
+// The message is left unchanged if its content is not valid JSON.
+func (p *Processor) applyJSONParsing(msg *message.Message, c *config.JSONParsingConfig) {
+	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
+	parsed, err := parser.ParseJSON(msg.Content, c, rules)
+	if err != nil {
+		return
+	}
+	if parsed.Status != "" {
+		msg.SetStatus(parsed.Status)
+	}
+	if !parsed.Timestamp.IsZero() {
+		msg.Timestamp = parsed.Timestamp
+	}
+	if parsed.Hostname != "" {
+		msg.Hostname = parsed.Hostname
+	}
+	if parsed.Service != "" {
+		msg.Origin.SetService(parsed.Service)
+	}
+	if len(parsed.Tags) > 0 {
+		msg.Origin.AddTags(parsed.Tags)
+	}
+	msg.Content = parsed.Content
+}
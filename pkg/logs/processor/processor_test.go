@@ -8,6 +8,7 @@ package processor
 import (
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -115,6 +116,132 @@ func TestMask(t *testing.T) {
 	assert.Equal(t, []byte("The credit card [masked_credit_card] was used to buy some time"), redactedMessage)
 }
 
+func TestSampling(t *testing.T) {
+	p := &Processor{}
+
+	rule := newProcessingRule("sample_at_match", "", "debug")
+	rule.SampleRate = 2
+	source := config.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}})
+
+	var kept int
+	for i := 0; i < 10; i++ {
+		if shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("debug message"), source, "")); shouldProcess {
+			kept++
+		}
+		// lines not matching are always kept
+		shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("error message"), source, ""))
+		assert.True(t, shouldProcess)
+	}
+	assert.Equal(t, 5, kept)
+	assert.Equal(t, int64(5), source.GetThrottler().Sampled())
+}
+
+func TestThrottling(t *testing.T) {
+	inputChan := make(chan *message.Message, 10)
+	outputChan := make(chan *message.Message, 10)
+	p := New(inputChan, outputChan, nil, RawEncoder)
+	p.Start()
+
+	source := config.NewLogSource("", &config.LogsConfig{Throttling: &config.ThrottlingConfig{MaxMessagesPerSecond: 2}})
+	for i := 0; i < 5; i++ {
+		inputChan <- newMessage([]byte("hello"), source, "")
+	}
+	p.Stop()
+
+	assert.Equal(t, 2, len(outputChan))
+	assert.Equal(t, int64(3), source.GetThrottler().Throttled())
+}
+
+func TestThrottlingSummary(t *testing.T) {
+	defer func(interval time.Duration) { summaryCheckInterval = interval }(summaryCheckInterval)
+	summaryCheckInterval = 10 * time.Millisecond
+
+	inputChan := make(chan *message.Message, 10)
+	outputChan := make(chan *message.Message, 10)
+	p := New(inputChan, outputChan, nil, RawEncoder)
+	p.Start()
+	defer p.Stop()
+
+	source := config.NewLogSource("", &config.LogsConfig{Throttling: &config.ThrottlingConfig{MaxMessagesPerSecond: 2, SummaryInterval: 1}})
+	for i := 0; i < 5; i++ {
+		inputChan <- newMessage([]byte("hello"), source, "")
+	}
+	for i := 0; i < 2; i++ {
+		assert.Contains(t, string((<-outputChan).Content), "hello")
+	}
+
+	// the summary is sent without waiting for another message of the source
+	select {
+	case msg := <-outputChan:
+		assert.Contains(t, string(msg.Content), "Throttling suppressed 3 log lines (15 bytes) in the last 1s")
+		assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the summary was not sent")
+	}
+}
+
+func TestJSONParsing(t *testing.T) {
+	p := &Processor{}
+
+	mask := newProcessingRule("mask_sequences", "[masked]", "\\d{4}")
+	mask.Attributes = []string{"card"}
+	source := config.NewLogSource("", &config.LogsConfig{
+		ProcessingRules: []*config.ProcessingRule{mask},
+		JSONParsing: &config.JSONParsingConfig{
+			StatusAttribute:    "level",
+			TimestampAttribute: "time",
+			ServiceAttribute:   "service",
+			HostAttribute:      "host",
+			TagAttributes:      []string{"env"},
+			DropAttributes:     []string{"level"},
+		},
+	})
+
+	msg := newMessage([]byte(`{"level":"error","time":1548078119,"service":"billing","host":"web-1","env":"prod","card":"1234","order":"5678"}`), source, "")
+	p.applyJSONParsing(msg, source.Config.JSONParsing)
+	shouldProcess, redactedMessage := p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, `{"card":"[masked]","env":"prod","host":"web-1","order":"5678","service":"billing","time":1548078119}`, string(redactedMessage))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, int64(1548078119), msg.Timestamp.Unix())
+	assert.Equal(t, "web-1", msg.Hostname)
+	assert.Equal(t, "billing", msg.Origin.Service())
+	assert.Equal(t, []string{"env:prod"}, msg.Origin.Tags())
+
+	// invalid JSON is passed through unchanged
+	msg = newMessage([]byte(`level=error card=1234`), source, "")
+	p.applyJSONParsing(msg, source.Config.JSONParsing)
+	shouldProcess, redactedMessage = p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, "level=error card=1234", string(redactedMessage))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.True(t, msg.Timestamp.IsZero())
+}
+
+func TestJSONParsingBeforeProcessingRules(t *testing.T) {
+	inputChan := make(chan *message.Message, 10)
+	outputChan := make(chan *message.Message, 10)
+	p := New(inputChan, outputChan, nil, JSONEncoder)
+	p.Start()
+
+	source := config.NewLogSource("", &config.LogsConfig{
+		ProcessingRules: []*config.ProcessingRule{newProcessingRule("exclude_at_match", "", `"level":"debug"`)},
+		JSONParsing: &config.JSONParsingConfig{
+			RenameAttributes: map[string]string{"lvl": "level"},
+		},
+	})
+
+	// the exclusion matches the remapped content
+	inputChan <- newMessage([]byte(`{"lvl":"debug","msg":"hello"}`), source, "")
+	inputChan <- newMessage([]byte(`{"lvl":"error","msg":"world"}`), source, "")
+	p.Stop()
+
+	if assert.Equal(t, 1, len(outputChan)) {
+		msg := <-outputChan
+		assert.Contains(t, string(msg.Content), "world")
+	}
+}
+
 func TestTruncate(t *testing.T) {
 	p := &Processor{}
 
@@ -6,8 +6,6 @@
 package processor
 
 import (
-	"time"
-
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/pb"
 )
@@ -23,8 +21,8 @@ func (p *protoEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte,
 	return (&pb.Log{
 		Message:   toValidUtf8(redactedMsg),
 		Status:    msg.GetStatus(),
-		Timestamp: time.Now().UTC().UnixNano(),
-		Hostname:  getHostname(),
+		Timestamp: getMessageTimestamp(msg).UnixNano(),
+		Hostname:  getMessageHostname(msg),
 		Service:   msg.Origin.Service(),
 		Source:    msg.Origin.Source(),
 		Tags:      msg.Origin.Tags(),
@@ -7,7 +7,6 @@ package processor
 
 import (
 	"regexp"
-	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -35,10 +34,10 @@ func (r *rawEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte, e
 		extraContent = append(extraContent, ' ')
 
 		// Timestamp
-		extraContent = time.Now().UTC().AppendFormat(extraContent, config.DateFormat)
+		extraContent = getMessageTimestamp(msg).AppendFormat(extraContent, config.DateFormat)
 		extraContent = append(extraContent, ' ')
 
-		extraContent = append(extraContent, []byte(getHostname())...)
+		extraContent = append(extraContent, []byte(getMessageHostname(msg))...)
 		extraContent = append(extraContent, ' ')
 
 		// Service
@@ -112,6 +112,8 @@ func (b *Builder) getIntegrations() []Integration {
 				Status:        b.toString(source.Status),
 				Inputs:        source.GetInputs(),
 				Messages:      source.Messages.GetMessages(),
+				Throttled:     source.GetThrottler().Throttled(),
+				Sampled:       source.GetThrottler().Sampled(),
 			})
 		}
 		integrations = append(integrations, Integration{
@@ -163,6 +165,9 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 	case config.JournaldType:
 		dictionary["IncludeUnits"] = strings.Join(c.IncludeUnits, ", ")
 		dictionary["ExcludeUnits"] = strings.Join(c.ExcludeUnits, ", ")
+		dictionary["IncludeMatches"] = strings.Join(c.IncludeMatches, ", ")
+		dictionary["ExcludeMatches"] = strings.Join(c.ExcludeMatches, ", ")
+		dictionary["ConfigID"] = c.ConfigID
 	case config.WindowsEventType:
 		dictionary["ChannelPath"] = c.ChannelPath
 		dictionary["Query"] = c.Query
@@ -182,5 +187,7 @@ func (b *Builder) getMetricsStatus() map[string]int64 {
 	metrics["LogsSent"] = b.logsExpVars.Get("LogsSent").(*expvar.Int).Value()
 	metrics["BytesSent"] = b.logsExpVars.Get("BytesSent").(*expvar.Int).Value()
 	metrics["EncodedBytesSent"] = b.logsExpVars.Get("EncodedBytesSent").(*expvar.Int).Value()
+	metrics["LogsThrottled"] = b.logsExpVars.Get("LogsThrottled").(*expvar.Int).Value()
+	metrics["LogsSampled"] = b.logsExpVars.Get("LogsSampled").(*expvar.Int).Value()
 	return metrics
 }
@@ -26,6 +26,8 @@ type Source struct {
 	Status        string                 `json:"status"`
 	Inputs        []string               `json:"inputs"`
 	Messages      []string               `json:"messages"`
+	Throttled     int64                  `json:"throttled"`
+	Sampled       int64                  `json:"sampled"`
 }
 
 // Integration provides some information about a logs integration.
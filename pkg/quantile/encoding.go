@@ -0,0 +1,119 @@
+package quantile
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// encodingVersion is the version of the binary encoding of a sketch, written as
+// its first byte. It must be bumped whenever the layout below changes.
+const encodingVersion = 1
+
+var errTruncated = errors.New("quantile: truncated sketch encoding")
+
+// MarshalBinary encodes the sketch in a compact, versioned binary format:
+//   version (byte)
+//   count (uvarint)
+//   basic summary: cnt (varint), min, max, sum, avg (float64)
+//   number of bins (uvarint)
+//   bins: key delta with the previous bin (varint), n (uvarint)
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+4*binary.MaxVarintLen64+4*8+len(s.bins)*2*binary.MaxVarintLen16)
+	var tmp [binary.MaxVarintLen64]byte
+
+	putUvarint := func(v uint64) { buf = append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...) }
+	putVarint := func(v int64) { buf = append(buf, tmp[:binary.PutVarint(tmp[:], v)]...) }
+	putFloat64 := func(v float64) {
+		binary.LittleEndian.PutUint64(tmp[:8], math.Float64bits(v))
+		buf = append(buf, tmp[:8]...)
+	}
+
+	buf = append(buf, encodingVersion)
+	putUvarint(uint64(s.count))
+	putVarint(s.Basic.Cnt)
+	putFloat64(s.Basic.Min)
+	putFloat64(s.Basic.Max)
+	putFloat64(s.Basic.Sum)
+	putFloat64(s.Basic.Avg)
+	putUvarint(uint64(len(s.bins)))
+	var last Key
+	for _, b := range s.bins {
+		putVarint(int64(b.k - last))
+		putUvarint(uint64(b.n))
+		last = b.k
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a sketch encoded by MarshalBinary into s.
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errTruncated
+	}
+	if v := data[0]; v != encodingVersion {
+		return fmt.Errorf("quantile: unsupported sketch encoding version %d", v)
+	}
+	data = data[1:]
+
+	var err error
+	uvarint := func() uint64 {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			err = errTruncated
+			return 0
+		}
+		data = data[n:]
+		return v
+	}
+	varint := func() int64 {
+		v, n := binary.Varint(data)
+		if n <= 0 {
+			err = errTruncated
+			return 0
+		}
+		data = data[n:]
+		return v
+	}
+	float64v := func() float64 {
+		if len(data) < 8 {
+			err = errTruncated
+			return 0
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(data))
+		data = data[8:]
+		return v
+	}
+
+	var dst Sketch
+	dst.count = int(uvarint())
+	dst.Basic.Cnt = varint()
+	dst.Basic.Min = float64v()
+	dst.Basic.Max = float64v()
+	dst.Basic.Sum = float64v()
+	dst.Basic.Avg = float64v()
+	nbins := uvarint()
+	if err != nil {
+		return err
+	}
+	if nbins > uint64(len(data)) {
+		// each bin takes at least two bytes
+		return errTruncated
+	}
+	dst.bins = make(binList, 0, nbins)
+	var k int64
+	for i := uint64(0); i < nbins; i++ {
+		k += varint()
+		n := uvarint()
+		if err != nil {
+			return err
+		}
+		if k < uvneginf || k > uvinf || n > maxBinWidth {
+			return fmt.Errorf("quantile: invalid bin (k=%d, n=%d)", k, n)
+		}
+		dst.bins = append(dst.bins, bin{k: Key(k), n: uint16(n)})
+	}
+	*s = dst
+	return nil
+}
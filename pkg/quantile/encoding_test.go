@@ -0,0 +1,38 @@
+package quantile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSketchEncoding(t *testing.T) {
+	c := Default()
+	for _, s := range []*Sketch{
+		{},
+		arange(t, c, 1),
+		arange(t, c, -100, 100, 3),
+		arange(t, c, 0, 1e5, 7),
+	} {
+		data, err := s.MarshalBinary()
+		require.NoError(t, err)
+		require.EqualValues(t, encodingVersion, data[0])
+
+		var decoded Sketch
+		require.NoError(t, decoded.UnmarshalBinary(data))
+		require.True(t, s.Equals(&decoded), "%s != %s", s, &decoded)
+		require.Equal(t, s.Quantile(c, .99), decoded.Quantile(c, .99))
+	}
+}
+
+func TestSketchEncodingErrors(t *testing.T) {
+	data, err := arange(t, Default(), 100).MarshalBinary()
+	require.NoError(t, err)
+
+	var s Sketch
+	require.Error(t, s.UnmarshalBinary(nil))
+	require.Error(t, s.UnmarshalBinary(append([]byte{encodingVersion + 1}, data[1:]...)))
+	for _, n := range []int{1, 10, len(data) - 1} {
+		require.Error(t, s.UnmarshalBinary(data[:n]), n)
+	}
+}
@@ -35,13 +35,18 @@ type Agent struct {
 	Receiver           *api.HTTPReceiver
 	Concentrator       *stats.Concentrator
 	Blacklister        *filters.Blacklister
+	TagFilter          *filters.TagFilter
 	Replacer           *filters.Replacer
+	PeerTagger         *filters.PeerTagger // nil unless enabled
 	ScoreSampler       *Sampler
 	ErrorsScoreSampler *Sampler
 	PrioritySampler    *Sampler
+	RuleSampler        *RuleSampler
 	EventProcessor     *event.Processor
+	TailBuffer         *TailBuffer
 	TraceWriter        *writer.TraceWriter
 	StatsWriter        *writer.StatsWriter
+	TraceMetricsWriter *writer.TraceMetricsWriter // nil unless enabled
 
 	// obfuscator is used to obfuscate sensitive data from various span
 	// tags based on their type.
@@ -64,24 +69,37 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 	in := make(chan *api.Trace, 5000)
 	out := make(chan *writer.SampledSpans, 1000)
 	statsChan := make(chan []stats.Bucket)
+	bucketsChan := statsChan
+	var traceMetricsWriter *writer.TraceMetricsWriter
+	if conf.TraceMetrics != nil {
+		// the buckets go through the trace metrics writer before the stats writer
+		bucketsChan = make(chan []stats.Bucket)
+		traceMetricsWriter = writer.NewTraceMetricsWriter(conf, bucketsChan, statsChan)
+	}
 
-	return &Agent{
+	agnt := &Agent{
 		Receiver:           api.NewHTTPReceiver(conf, dynConf, in),
-		Concentrator:       stats.NewConcentrator(conf.ExtraAggregators, conf.BucketInterval.Nanoseconds(), statsChan),
+		Concentrator:       stats.NewConcentrator(conf.ExtraAggregators, conf.BucketInterval.Nanoseconds(), conf.StatsSketches, bucketsChan),
 		Blacklister:        filters.NewBlacklister(conf.Ignore["resource"]),
+		TagFilter:          filters.NewTagFilter(conf.RequireTags, conf.RejectTags),
 		Replacer:           filters.NewReplacer(conf.ReplaceTags),
+		PeerTagger:         newPeerTagger(conf.PeerTags),
 		ScoreSampler:       NewScoreSampler(conf),
 		ErrorsScoreSampler: NewErrorsSampler(conf),
 		PrioritySampler:    NewPrioritySampler(conf, dynConf),
+		RuleSampler:        NewRuleSampler(conf),
 		EventProcessor:     newEventProcessor(conf),
 		TraceWriter:        writer.NewTraceWriter(conf, out),
 		StatsWriter:        writer.NewStatsWriter(conf, statsChan),
+		TraceMetricsWriter: traceMetricsWriter,
 		obfuscator:         newObfuscator(conf.Obfuscation),
 		In:                 in,
 		Out:                out,
 		conf:               conf,
 		ctx:                ctx,
 	}
+	agnt.TailBuffer = NewTailBuffer(conf, agnt.sample)
+	return agnt
 }
 
 // Run starts routers routines and individual pieces then stop them when the exit order is received
@@ -97,8 +115,18 @@ func (a *Agent) Run() {
 		starter.Start()
 	}
 
+	if a.RuleSampler != nil {
+		a.RuleSampler.Start()
+	}
+	if a.TailBuffer != nil {
+		a.TailBuffer.Start()
+	}
+
 	go a.TraceWriter.Run()
 	go a.StatsWriter.Run()
+	if a.TraceMetricsWriter != nil {
+		go a.TraceMetricsWriter.Run()
+	}
 
 	for i := 0; i < runtime.NumCPU(); i++ {
 		go a.work()
@@ -129,11 +157,22 @@ func (a *Agent) loop() {
 				log.Error(err)
 			}
 			a.Concentrator.Stop()
+			if a.TailBuffer != nil {
+				// release the buffered traces before the writer stops
+				a.TailBuffer.Stop()
+			}
 			a.TraceWriter.Stop()
+			if a.TraceMetricsWriter != nil {
+				// pass the last buckets on before the stats writer stops
+				a.TraceMetricsWriter.Stop()
+			}
 			a.StatsWriter.Stop()
 			a.ScoreSampler.Stop()
 			a.ErrorsScoreSampler.Stop()
 			a.PrioritySampler.Stop()
+			if a.RuleSampler != nil {
+				a.RuleSampler.Stop()
+			}
 			a.EventProcessor.Stop()
 			return
 		}
@@ -178,6 +217,23 @@ func (a *Agent) Process(t *api.Trace) {
 		log.Debugf("Trace rejected by blacklister. root: %v", root)
 		atomic.AddInt64(&ts.TracesFiltered, 1)
 		atomic.AddInt64(&ts.SpansFiltered, int64(len(t.Spans)))
+		a.recordDecision(t.Debug, api.DecisionFiltered, "blacklisted")
+		return
+	}
+	if !a.TagFilter.Required(root) {
+		log.Debugf("Trace rejected as it lacks a required tag. root: %v", root)
+		atomic.AddInt64(&ts.TracesFilteredRequireTags, 1)
+		atomic.AddInt64(&ts.TracesFiltered, 1)
+		atomic.AddInt64(&ts.SpansFiltered, int64(len(t.Spans)))
+		a.recordDecision(t.Debug, api.DecisionFiltered, "missing a required tag")
+		return
+	}
+	if a.TagFilter.Rejected(root) {
+		log.Debugf("Trace rejected as it has a rejected tag. root: %v", root)
+		atomic.AddInt64(&ts.TracesFilteredRejectTags, 1)
+		atomic.AddInt64(&ts.TracesFiltered, 1)
+		atomic.AddInt64(&ts.SpansFiltered, int64(len(t.Spans)))
+		a.recordDecision(t.Debug, api.DecisionFiltered, "has a rejected tag")
 		return
 	}
 
@@ -187,6 +243,10 @@ func (a *Agent) Process(t *api.Trace) {
 		Truncate(span)
 	}
 	a.Replacer.Replace(t.Spans)
+	if a.PeerTagger != nil {
+		a.PeerTagger.Tag(t.Spans)
+	}
+	t.Debug.SetProcessed(t.Spans)
 
 	{
 		// this section sets up any necessary tags on the root:
@@ -221,6 +281,7 @@ func (a *Agent) Process(t *api.Trace) {
 		Root:          root,
 		Env:           a.conf.DefaultEnv,
 		Sublayers:     sublayers,
+		Debug:         t.Debug,
 	}
 	if tenv := traceutil.GetEnv(t.Spans); tenv != "" {
 		// this trace has a user defined env.
@@ -228,7 +289,14 @@ func (a *Agent) Process(t *api.Trace) {
 	}
 
 	if priority >= 0 {
-		a.sample(ts, pt)
+		if a.TailBuffer != nil {
+			// the trace is sampled once all its spans are received
+			a.TailBuffer.Add(ts, pt)
+		} else {
+			a.sample(ts, pt)
+		}
+	} else {
+		a.recordDecision(pt.Debug, api.DecisionRejected, "")
 	}
 
 	a.Concentrator.In <- &stats.Input{
@@ -255,18 +323,43 @@ func (a *Agent) sample(ts *info.TagStats, pt ProcessedTrace) {
 	atomic.AddInt64(&ts.EventsExtracted, int64(numExtracted))
 	atomic.AddInt64(&ts.EventsSampled, int64(len(events)))
 
+	if rec := pt.Debug; rec != nil {
+		rec.SampleRate = rate
+		rec.EventsExtracted = numExtracted
+		rec.EventsSampled = len(events)
+		decision := api.DecisionNotSampled
+		if sampled {
+			decision = api.DecisionSampled
+		}
+		a.recordDecision(rec, decision, "")
+	}
+
 	if !ss.Empty() {
 		a.Out <- &ss
 	}
 }
 
+// recordDecision writes the final decision taken on the trace of rec and adds
+// it to the debug recorder. It is a no-op when rec is nil.
+func (a *Agent) recordDecision(rec *api.DebugRecord, decision, reason string) {
+	if rec == nil {
+		return
+	}
+	rec.Decision = decision
+	rec.Reason = reason
+	a.Receiver.Debug.Add(rec)
+}
+
 // runSamplers runs all the agent's samplers on pt and returns the sampling decision
 // along with the sampling rate.
 func (a *Agent) runSamplers(pt ProcessedTrace) (sampled bool, rate float64) {
+	// the priority and score samplers see every trace, even the ones matching a rule,
+	// so that their counts and the rates returned to the tracers remain accurate
 	var sampledPriority, sampledScore bool
 	var ratePriority, rateScore float64
 
-	if _, ok := pt.GetSamplingPriority(); ok {
+	priority, hasPriority := pt.GetSamplingPriority()
+	if hasPriority {
 		sampledPriority, ratePriority = a.PrioritySampler.Add(pt)
 	}
 
@@ -276,7 +369,25 @@ func (a *Agent) runSamplers(pt ProcessedTrace) (sampled bool, rate float64) {
 		sampledScore, rateScore = a.ScoreSampler.Add(pt)
 	}
 
-	return sampledScore || sampledPriority, sampler.CombineRates(ratePriority, rateScore)
+	sampled, rate = sampledScore || sampledPriority, sampler.CombineRates(ratePriority, rateScore)
+	if priority >= sampler.PriorityUserKeep {
+		// the traces explicitly kept by the user are never downsampled by the rules
+		return sampled, rate
+	}
+
+	if a.TailBuffer != nil {
+		// the tail sampling rules take precedence over the samplers
+		if matched, ruleSampled, ruleRate := a.TailBuffer.ApplyRules(pt); matched {
+			return ruleSampled, ruleRate
+		}
+	}
+	if a.RuleSampler != nil {
+		// the sampling rules take precedence over the signature samplers
+		if matched, ruleSampled, ruleRate := a.RuleSampler.Add(pt); matched {
+			return ruleSampled, ruleRate
+		}
+	}
+	return sampled, rate
 }
 
 func traceContainsError(trace pb.Trace) bool {
@@ -301,6 +412,13 @@ func newEventProcessor(conf *config.AgentConfig) *event.Processor {
 	return event.NewProcessor(extractors, conf.MaxEPS)
 }
 
+func newPeerTagger(cfg *config.PeerTagsConfig) *filters.PeerTagger {
+	if cfg == nil {
+		return nil
+	}
+	return filters.NewPeerTagger(cfg.ExtraServiceKeys, cfg.ExtraHostnameKeys)
+}
+
 func newObfuscator(cfg *config.ObfuscationConfig) *obfuscate.Obfuscator {
 	if cfg == nil {
 		return obfuscate.NewObfuscator(nil)
@@ -319,5 +437,9 @@ func newObfuscator(cfg *config.ObfuscationConfig) *obfuscate.Obfuscator {
 		RemoveStackTraces: cfg.RemoveStackTraces,
 		Redis:             cfg.Redis.Enabled,
 		Memcached:         cfg.Memcached.Enabled,
+		GraphQL:           cfg.GraphQL.Enabled,
+		MessageQueue:      cfg.MessageQueue.Enabled,
+		SQLDialects:       cfg.SQLDialects.Enabled,
+		CreditCards:       cfg.CreditCards.Enabled,
 	})
 }
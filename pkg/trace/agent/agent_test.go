@@ -104,6 +104,38 @@ func TestProcess(t *testing.T) {
 		assert.Equal("SELECT name FROM people WHERE age = ? AND extra = ?", span.Meta["sql.query"])
 	})
 
+	t.Run("PeerTagger", func(t *testing.T) {
+		// Ensures that the peer tags are inferred after the replacer ran.
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.PeerTags = &config.PeerTagsConfig{Enabled: true}
+		cfg.ReplaceTags = []*config.ReplaceRule{{
+			Name: "out.host",
+			Re:   regexp.MustCompile("[0-9]+"),
+			Repl: "?",
+		}}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		now := time.Now()
+		span := &pb.Span{
+			Resource: "SELECT name FROM people",
+			Type:     "sql",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+			Meta:     map[string]string{"db.instance": "people", "out.host": "db-42"},
+		}
+		agnt.Process(&api.Trace{
+			Spans:  pb.Trace{span},
+			Source: &info.Tags{},
+		})
+
+		assert := assert.New(t)
+		assert.Equal("people", span.Meta["peer.service"])
+		assert.Equal("db-?", span.Meta["peer.hostname"])
+	})
+
 	t.Run("Blacklister", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -144,6 +176,51 @@ func TestProcess(t *testing.T) {
 		assert.EqualValues(2, stats.SpansFiltered)
 	})
 
+	t.Run("TagFilter", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.RequireTags = []*config.TagRule{{Key: "env", Value: "prod"}}
+		cfg.RejectTags = []*config.TagRule{{Key: "http.url", Re: regexp.MustCompile("healthz")}}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		now := time.Now()
+		newSpan := func(meta map[string]string) *pb.Span {
+			return &pb.Span{
+				Resource: "GET /",
+				Start:    now.Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+				Meta:     meta,
+			}
+		}
+
+		stats := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+		assert := assert.New(t)
+
+		agnt.Process(&api.Trace{
+			Spans:  pb.Trace{newSpan(map[string]string{"env": "prod", "http.url": "/users"})},
+			Source: &info.Tags{},
+		})
+		assert.EqualValues(0, stats.TracesFiltered)
+
+		agnt.Process(&api.Trace{
+			Spans:  pb.Trace{newSpan(map[string]string{"env": "staging"}), newSpan(nil)},
+			Source: &info.Tags{},
+		})
+		assert.EqualValues(1, stats.TracesFilteredRequireTags)
+		assert.EqualValues(0, stats.TracesFilteredRejectTags)
+
+		agnt.Process(&api.Trace{
+			Spans:  pb.Trace{newSpan(map[string]string{"env": "prod", "http.url": "/healthz"})},
+			Source: &info.Tags{},
+		})
+		assert.EqualValues(1, stats.TracesFilteredRequireTags)
+		assert.EqualValues(1, stats.TracesFilteredRejectTags)
+		assert.EqualValues(2, stats.TracesFiltered)
+		assert.EqualValues(3, stats.SpansFiltered)
+	})
+
 	t.Run("ContainerTags", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -215,6 +292,90 @@ func TestProcess(t *testing.T) {
 		assert.EqualValues(t, 4, stats.TracesPriority1)
 		assert.EqualValues(t, 5, stats.TracesPriority2)
 	})
+
+	t.Run("DebugTraces", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.Ignore["resource"] = []string{"^INSERT.*"}
+		cfg.DebugTraces = &config.DebugTracesConfig{Enabled: true, MaxTraces: 10, MaxBytes: 1 << 20}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		now := time.Now()
+		process := func(resource string, priority sampler.SamplingPriority) {
+			span := &pb.Span{
+				Resource: resource,
+				Type:     "sql",
+				Start:    now.Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+				Metrics:  map[string]float64{},
+			}
+			sampler.SetSamplingPriority(span, priority)
+			agnt.Process(&api.Trace{
+				Spans:  pb.Trace{span},
+				Source: &info.Tags{},
+				Debug:  &api.DebugRecord{Trace: pb.Trace{span}},
+			})
+		}
+		process("INSERT INTO db VALUES (1, 2, 3)", sampler.PriorityUserKeep)
+		process("SELECT name FROM people", sampler.PriorityUserDrop)
+		process("SELECT name FROM people", sampler.PriorityUserKeep)
+
+		records := agnt.Receiver.Debug.Records()
+		assert := assert.New(t)
+		if assert.Len(records, 3) {
+			assert.Equal(api.DecisionFiltered, records[0].Decision)
+			assert.Equal("blacklisted", records[0].Reason)
+			assert.Len(records[0].Processed, 0)
+			assert.Equal(api.DecisionRejected, records[1].Decision)
+			assert.Equal(api.DecisionSampled, records[2].Decision)
+			assert.EqualValues(1, records[2].SampleRate)
+			assert.Len(records[2].Processed, 1)
+		}
+	})
+
+	t.Run("DebugTracesTailSampling", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.DebugTraces = &config.DebugTracesConfig{Enabled: true, MaxTraces: 10, MaxBytes: 1 << 20}
+		cfg.TailSampling = &config.TailSamplingConfig{Enabled: true, WindowSeconds: 10}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		now := time.Now()
+		process := func(spanID, parentID uint64) {
+			span := &pb.Span{
+				TraceID:  7,
+				SpanID:   spanID,
+				ParentID: parentID,
+				Resource: "SELECT name FROM people",
+				Type:     "sql",
+				Start:    now.Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+				Metrics:  map[string]float64{},
+			}
+			sampler.SetSamplingPriority(span, sampler.PriorityUserKeep)
+			agnt.Process(&api.Trace{
+				Spans:  pb.Trace{span},
+				Source: &info.Tags{},
+				Debug:  &api.DebugRecord{Trace: pb.Trace{span}},
+			})
+		}
+		// the trace is buffered until its root span is received
+		process(2, 1)
+		assert.Len(t, agnt.Receiver.Debug.Records(), 0)
+		process(1, 0)
+
+		records := agnt.Receiver.Debug.Records()
+		assert := assert.New(t)
+		if assert.Len(records, 1) {
+			assert.Equal(api.DecisionSampled, records[0].Decision)
+			assert.Len(records[0].Trace, 2)
+			assert.Len(records[0].Processed, 2)
+		}
+	})
 }
 
 func TestSampling(t *testing.T) {
@@ -350,6 +511,81 @@ func TestSampling(t *testing.T) {
 	}
 }
 
+func TestSamplingRules(t *testing.T) {
+	rate := func(r float64) *float64 { return &r }
+	cfg := config.New()
+	cfg.SamplingRules = []*config.SamplingRule{
+		{Service: "checkout", TagsRe: map[string]*regexp.Regexp{"http.status_code": regexp.MustCompile("^5")}, Rate: rate(1)},
+		{ResourceRe: regexp.MustCompile("^GET /health"), Rate: rate(0)},
+	}
+	a := &Agent{
+		ScoreSampler:       newMockSampler(false, 0.5),
+		ErrorsScoreSampler: newMockSampler(false, 0.5),
+		PrioritySampler:    newMockSampler(false, 0.5),
+		RuleSampler:        NewRuleSampler(cfg),
+	}
+
+	for name, tt := range map[string]struct {
+		root        *pb.Span
+		wantSampled bool
+		wantRate    float64
+	}{
+		"checkout error": {
+			root:        &pb.Span{Service: "checkout", Meta: map[string]string{"http.status_code": "503"}},
+			wantSampled: true,
+			wantRate:    1,
+		},
+		"checkout success": {
+			root:     &pb.Span{Service: "checkout", Meta: map[string]string{"http.status_code": "200"}},
+			wantRate: 0.5,
+		},
+		"health": {
+			root:     &pb.Span{Service: "web", Resource: "GET /health"},
+			wantRate: 0,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pt := ProcessedTrace{Trace: pb.Trace{tt.root}, Root: tt.root}
+			sampled, rate := a.runSamplers(pt)
+			assert.Equal(t, tt.wantSampled, sampled)
+			assert.Equal(t, tt.wantRate, rate)
+		})
+	}
+
+	assert.Nil(t, NewRuleSampler(config.New()))
+}
+
+func TestSamplingRulesUserKeep(t *testing.T) {
+	rate := func(r float64) *float64 { return &r }
+	cfg := config.New()
+	cfg.SamplingRules = []*config.SamplingRule{
+		{ResourceRe: regexp.MustCompile("^GET /health"), Rate: rate(0)},
+	}
+	priorityEngine := testutil.NewMockEngine(true, 1)
+	a := &Agent{
+		ScoreSampler:       newMockSampler(false, 0.5),
+		ErrorsScoreSampler: newMockSampler(false, 0.5),
+		PrioritySampler:    &Sampler{engine: priorityEngine},
+		RuleSampler:        NewRuleSampler(cfg),
+	}
+
+	root := &pb.Span{Service: "web", Resource: "GET /health", Metrics: map[string]float64{}}
+	pt := ProcessedTrace{Trace: pb.Trace{root}, Root: root}
+
+	// the trace kept by the user is not dropped by the rule
+	sampler.SetSamplingPriority(root, sampler.PriorityUserKeep)
+	sampled, rate := a.runSamplers(pt)
+	assert.True(t, sampled)
+	assert.Equal(t, sampler.CombineRates(1, 0.5), rate)
+
+	// the rule applies to the other traces, which are still counted by the priority sampler
+	sampler.SetSamplingPriority(root, sampler.PriorityAutoKeep)
+	sampled, rate = a.runSamplers(pt)
+	assert.False(t, sampled)
+	assert.Equal(t, 0.0, rate)
+	assert.Equal(t, 2, priorityEngine.Calls)
+}
+
 func TestEventProcessorFromConf(t *testing.T) {
 	if _, ok := os.LookupEnv("INTEGRATION"); !ok {
 		t.Skip("set INTEGRATION environment variable to run")
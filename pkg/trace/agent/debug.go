@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// debugTraces prints to w the last traces received by the running trace-agent.
+func debugTraces(w io.Writer, cfg *config.AgentConfig) error {
+	if err := util.SetAuthToken(); err != nil {
+		return fmt.Errorf("could not get the auth token: %v", err)
+	}
+	url := fmt.Sprintf("http://%s:%d/debug/traces", cfg.ReceiverHost, cfg.ReceiverPort)
+	body, err := util.DoGet(&http.Client{Timeout: 3 * time.Second}, url)
+	if err != nil {
+		return fmt.Errorf("could not get the traces from %s, make sure the trace-agent is running with apm_config.debug_traces enabled: %v", url, err)
+	}
+	var records []*api.DebugRecord
+	if err := json.Unmarshal(body, &records); err != nil {
+		return fmt.Errorf("could not decode the traces: %v", err)
+	}
+	if len(records) == 0 {
+		fmt.Fprintln(w, "No trace received yet.")
+	}
+	for _, rec := range records {
+		printDebugRecord(w, rec)
+	}
+	return nil
+}
+
+// printDebugRecord prints a human-readable description of rec to w.
+func printDebugRecord(w io.Writer, rec *api.DebugRecord) {
+	var source []string
+	for _, tag := range [][2]string{
+		{"lang", rec.Source.Lang},
+		{"lang_version", rec.Source.LangVersion},
+		{"interpreter", rec.Source.Interpreter},
+		{"tracer_version", rec.Source.TracerVersion},
+		{"endpoint_version", rec.Source.EndpointVersion},
+	} {
+		if tag[1] != "" {
+			source = append(source, tag[0]+":"+tag[1])
+		}
+	}
+	fmt.Fprintf(w, "%s  %d span(s)  %s\n", rec.Received.Format(time.RFC3339), len(rec.Trace), strings.Join(source, " "))
+
+	decision := rec.Decision
+	switch rec.Decision {
+	case api.DecisionDropped, api.DecisionFiltered:
+		decision += " (" + rec.Reason + ")"
+	case api.DecisionSampled, api.DecisionNotSampled:
+		decision += fmt.Sprintf(" (rate: %g, events: %d sampled out of %d extracted)", rec.SampleRate, rec.EventsSampled, rec.EventsExtracted)
+	}
+	fmt.Fprintf(w, "  decision:   %s\n", decision)
+	if rec.Normalizer != "" {
+		fmt.Fprintf(w, "  normalizer: %s\n", rec.Normalizer)
+	}
+	fmt.Fprintln(w, "  received:")
+	printDebugSpans(w, rec.Trace)
+	if len(rec.Processed) > 0 {
+		fmt.Fprintln(w, "  processed:")
+		printDebugSpans(w, rec.Processed)
+	}
+	fmt.Fprintln(w)
+}
+
+// printDebugSpans prints a line per span of t to w.
+func printDebugSpans(w io.Writer, t pb.Trace) {
+	for _, s := range t {
+		fmt.Fprintf(w, "  - trace_id:%d span_id:%d parent_id:%d service:%q name:%q resource:%q duration:%s error:%d\n",
+			s.TraceID, s.SpanID, s.ParentID, s.Service, s.Name, s.Resource, time.Duration(s.Duration), s.Error)
+	}
+}
@@ -6,6 +6,7 @@
 package agent
 
 import (
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/stats"
@@ -18,6 +19,10 @@ type ProcessedTrace struct {
 	Root          *pb.Span
 	Env           string
 	Sublayers     stats.SublayerMap
+
+	// Debug holds the record of the trace when the traces are recorded for
+	// debugging, it is nil otherwise.
+	Debug *api.DebugRecord
 }
 
 // Weight returns the weight at the root span.
@@ -7,6 +7,7 @@ package agent
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"math/rand"
 	"os"
@@ -53,6 +54,13 @@ func Run(ctx context.Context) {
 		return
 	}
 
+	if flag.Arg(0) == "debug" && flag.Arg(1) == "traces" {
+		if err := debugTraces(os.Stdout, cfg); err != nil {
+			osutil.Exitf("failed to print the debug traces: %s", err)
+		}
+		return
+	}
+
 	if err := setupLogger(cfg); err != nil {
 		osutil.Exitf("cannot create logger: %v", err)
 	}
@@ -138,3 +138,70 @@ func (s *Sampler) logStats() {
 		}
 	}
 }
+
+// RuleSampler applies the user-defined sampling rules before the samplers.
+type RuleSampler struct {
+	engine *sampler.RuleEngine
+
+	exit chan struct{}
+}
+
+// NewRuleSampler creates a new sampler applying the sampling rules of the
+// configuration, it returns nil if there are none.
+func NewRuleSampler(conf *config.AgentConfig) *RuleSampler {
+	if len(conf.SamplingRules) == 0 {
+		return nil
+	}
+	rules := make([]*sampler.Rule, 0, len(conf.SamplingRules))
+	for _, r := range conf.SamplingRules {
+		rules = append(rules, &sampler.Rule{
+			Service:  r.Service,
+			Name:     r.Name,
+			Resource: r.ResourceRe,
+			Tags:     r.TagsRe,
+			Rate:     *r.Rate,
+			MaxTPS:   r.MaxTPS,
+		})
+	}
+	return &RuleSampler{
+		engine: sampler.NewRuleEngine(rules),
+		exit:   make(chan struct{}),
+	}
+}
+
+// Start starts reporting the statistics of the rules.
+func (s *RuleSampler) Start() {
+	go func() {
+		defer watchdog.LogOnPanic()
+		s.logStats()
+	}()
+}
+
+// Add applies the first rule matching the trace. It returns false if no rule
+// matches, otherwise the sampling decision and rate of the rule.
+func (s *RuleSampler) Add(t ProcessedTrace) (matched bool, sampled bool, rate float64) {
+	return s.engine.Sample(t.Root)
+}
+
+// Stop stops the sampler
+func (s *RuleSampler) Stop() {
+	s.exit <- struct{}{}
+	<-s.exit
+}
+
+// logStats updates the info exposed.
+func (s *RuleSampler) logStats() {
+	defer close(s.exit)
+
+	t := time.NewTicker(10 * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.exit:
+			return
+		case <-t.C:
+			info.UpdateSamplingRulesInfo(s.engine.GetState())
+		}
+	}
+}
@@ -0,0 +1,394 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package agent
+
+import (
+	"container/list"
+	"math"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/stats"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// tailBufferTickInterval is the delay between two checks of the expired traces.
+const tailBufferTickInterval = time.Second
+
+// tailTrace holds the spans of a trace received so far.
+type tailTrace struct {
+	ts       *info.TagStats
+	pt       *ProcessedTrace
+	size     int
+	deadline time.Time
+	elem     *list.Element
+}
+
+// TailBuffer holds the spans of the traces until their root span is received or
+// their window elapses, so that the sampling decision is made on complete traces.
+// It is safe for concurrent use.
+type TailBuffer struct {
+	window    time.Duration
+	maxTraces int
+	maxBytes  int
+	rules     []*tailRule
+
+	// maxMemory is the memory threshold of the agent above which
+	// the buffer is shrunk, checked every watchdogInterval.
+	maxMemory        float64
+	watchdogInterval time.Duration
+
+	mu     sync.Mutex
+	traces map[uint64]*tailTrace
+	order  *list.List // oldest traces first
+	bytes  int
+	stats  info.TailBufferInfo
+
+	// release is called with the traces leaving the buffer.
+	release func(*info.TagStats, ProcessedTrace)
+	// mem returns the number of bytes allocated by the agent, replaced in tests.
+	mem func() uint64
+
+	exit chan struct{}
+}
+
+// NewTailBuffer returns a new TailBuffer calling release with the traces once
+// they are complete, or nil if tail sampling is disabled.
+func NewTailBuffer(conf *config.AgentConfig, release func(*info.TagStats, ProcessedTrace)) *TailBuffer {
+	ts := conf.TailSampling
+	if ts == nil || !ts.Enabled {
+		return nil
+	}
+	// a zero limit disables it
+	maxTraces, maxBytes := ts.MaxTraces, int(ts.MaxMemory)
+	if maxTraces == 0 {
+		maxTraces = math.MaxInt32
+	}
+	if maxBytes == 0 {
+		maxBytes = math.MaxInt32
+	}
+	rules := make([]*tailRule, 0, len(ts.Rules))
+	for _, r := range ts.Rules {
+		rules = append(rules, newTailRule(r))
+	}
+	return &TailBuffer{
+		window:           time.Duration(ts.WindowSeconds * float64(time.Second)),
+		maxTraces:        maxTraces,
+		maxBytes:         maxBytes,
+		rules:            rules,
+		maxMemory:        conf.MaxMemory,
+		watchdogInterval: conf.WatchdogInterval,
+		traces:           make(map[uint64]*tailTrace),
+		order:            list.New(),
+		release:          release,
+		mem:              func() uint64 { return watchdog.Mem().Alloc },
+		exit:             make(chan struct{}),
+	}
+}
+
+// Start starts releasing the expired traces.
+func (b *TailBuffer) Start() {
+	go func() {
+		defer watchdog.LogOnPanic()
+		b.run()
+	}()
+}
+
+// Stop releases all the buffered traces and stops the buffer.
+func (b *TailBuffer) Stop() {
+	b.exit <- struct{}{}
+	<-b.exit
+	b.flush()
+}
+
+func (b *TailBuffer) run() {
+	defer close(b.exit)
+
+	tick := time.NewTicker(tailBufferTickInterval)
+	defer tick.Stop()
+	wd := time.NewTicker(b.watchdogInterval)
+	defer wd.Stop()
+	report := time.NewTicker(10 * time.Second)
+	defer report.Stop()
+
+	for {
+		select {
+		case <-b.exit:
+			return
+		case now := <-tick.C:
+			b.expire(now)
+		case <-wd.C:
+			b.watchdog()
+		case <-report.C:
+			b.report()
+		}
+	}
+}
+
+// Add adds the spans of pt to the buffer. The trace is released as soon as
+// its root span is received.
+func (b *TailBuffer) Add(ts *info.TagStats, pt ProcessedTrace) {
+	if len(pt.Trace) == 0 {
+		return
+	}
+	id := pt.Trace[0].TraceID
+	size := pt.Trace.Msgsize()
+
+	b.mu.Lock()
+	t, ok := b.traces[id]
+	if ok {
+		t.add(pt)
+		t.size += size
+	} else {
+		// the spans are copied so that the next parts of the trace can be appended
+		pt.Trace = append(pb.Trace(nil), pt.Trace...)
+		t = &tailTrace{
+			ts:       ts,
+			pt:       &pt,
+			size:     size,
+			deadline: time.Now().Add(b.window),
+		}
+		t.elem = b.order.PushBack(id)
+		b.traces[id] = t
+		b.stats.Traces++
+	}
+	b.bytes += size
+	b.stats.Spans += int64(len(pt.Trace))
+
+	var released []*tailTrace
+	if hasRootSpan(pt.Trace) {
+		b.remove(id, t)
+		b.stats.Completed++
+		released = append(released, t)
+	}
+	evicted := b.evict(b.maxTraces, b.maxBytes)
+	b.mu.Unlock()
+
+	b.releaseAll(released)
+	b.releaseAll(evicted)
+}
+
+// add adds the spans of another part of the trace.
+func (t *tailTrace) add(pt ProcessedTrace) {
+	t.pt.Trace = append(t.pt.Trace, pt.Trace...)
+	if env := traceutil.GetEnv(pt.Trace); env != "" {
+		// this part of the trace has a user defined env.
+		t.pt.Env = env
+	}
+	if len(pt.Sublayers) > 0 {
+		// the map of the first part may still be read by the concentrator
+		sublayers := make(stats.SublayerMap, len(t.pt.Sublayers)+len(pt.Sublayers))
+		for span, values := range t.pt.Sublayers {
+			sublayers[span] = values
+		}
+		for span, values := range pt.Sublayers {
+			sublayers[span] = values
+		}
+		t.pt.Sublayers = sublayers
+	}
+	// the trace is recorded once, with the spans of all its parts
+	if t.pt.Debug == nil {
+		t.pt.Debug = pt.Debug
+	} else {
+		t.pt.Debug.Append(pt.Debug)
+	}
+}
+
+// expire releases the traces whose window elapsed.
+func (b *TailBuffer) expire(now time.Time) {
+	var expired []*tailTrace
+	b.mu.Lock()
+	for e := b.order.Front(); e != nil; e = b.order.Front() {
+		id := e.Value.(uint64)
+		t := b.traces[id]
+		if t.deadline.After(now) {
+			break
+		}
+		b.remove(id, t)
+		b.stats.Expired++
+		expired = append(expired, t)
+	}
+	b.mu.Unlock()
+
+	b.releaseAll(expired)
+}
+
+// watchdog halves the buffer when the agent uses more memory than allowed,
+// before the receiver starts to rate limit the incoming traces.
+func (b *TailBuffer) watchdog() {
+	if b.maxMemory <= 0 || float64(b.mem()) <= b.maxMemory {
+		return
+	}
+	b.mu.Lock()
+	evicted := b.evict(len(b.traces)/2, b.bytes/2)
+	b.mu.Unlock()
+
+	if len(evicted) > 0 {
+		log.Warnf("Memory threshold exceeded (apm_config.max_memory: %.0f bytes), released %d traces from the tail sampling buffer", b.maxMemory, len(evicted))
+	}
+	b.releaseAll(evicted)
+}
+
+// flush releases all the buffered traces.
+func (b *TailBuffer) flush() {
+	var flushed []*tailTrace
+	b.mu.Lock()
+	for id, t := range b.traces {
+		b.remove(id, t)
+		flushed = append(flushed, t)
+	}
+	b.mu.Unlock()
+
+	b.releaseAll(flushed)
+}
+
+// evict removes the oldest traces until the buffer holds at most maxTraces
+// traces and maxBytes bytes, it returns the removed traces. It must be called
+// with the lock held.
+func (b *TailBuffer) evict(maxTraces, maxBytes int) []*tailTrace {
+	var evicted []*tailTrace
+	for e := b.order.Front(); e != nil && (len(b.traces) > maxTraces || b.bytes > maxBytes); e = b.order.Front() {
+		id := e.Value.(uint64)
+		t := b.traces[id]
+		b.remove(id, t)
+		b.stats.Evicted++
+		evicted = append(evicted, t)
+	}
+	if len(evicted) > 0 {
+		metrics.Count("datadog.trace_agent.tail_buffer.evicted", int64(len(evicted)), nil, 1)
+	}
+	return evicted
+}
+
+// remove removes a trace from the buffer. It must be called with the lock held.
+func (b *TailBuffer) remove(id uint64, t *tailTrace) {
+	delete(b.traces, id)
+	b.order.Remove(t.elem)
+	b.bytes -= t.size
+	b.stats.Traces--
+	b.stats.Spans -= int64(len(t.pt.Trace))
+}
+
+// releaseAll passes the traces to the release function, outside of the lock.
+func (b *TailBuffer) releaseAll(traces []*tailTrace) {
+	for _, t := range traces {
+		t.pt.Root = traceutil.GetRoot(t.pt.Trace)
+		// the weights of the spans depend on the root of the whole trace
+		t.pt.WeightedTrace = stats.NewWeightedTrace(t.pt.Trace, t.pt.Root)
+		b.release(t.ts, *t.pt)
+	}
+}
+
+// ApplyRules returns the decision of the first rule matching pt, along with
+// its keep rate. It returns false if no rule matches.
+func (b *TailBuffer) ApplyRules(pt ProcessedTrace) (matched bool, sampled bool, rate float64) {
+	for _, r := range b.rules {
+		if !matchTailRule(r, pt.Trace) {
+			continue
+		}
+		rate = 1.0
+		if r.KeepRate != nil {
+			rate = *r.KeepRate
+		}
+		sampled = sampler.SampleByRate(pt.Trace[0].TraceID, rate)
+		log.Tracef("Trace %d matched tail sampling rule %q, kept: %t", pt.Trace[0].TraceID, r.Name, sampled)
+
+		b.mu.Lock()
+		if sampled {
+			b.stats.RuleKept++
+		} else {
+			b.stats.RuleDropped++
+		}
+		b.mu.Unlock()
+		return true, sampled, rate
+	}
+	return false, false, 0
+}
+
+// report publishes the statistics of the buffer.
+func (b *TailBuffer) report() {
+	b.mu.Lock()
+	stats := b.stats
+	stats.Bytes = int64(b.bytes)
+	b.mu.Unlock()
+
+	info.UpdateTailBufferInfo(stats)
+	metrics.Gauge("datadog.trace_agent.tail_buffer.traces", float64(stats.Traces), nil, 1)
+	metrics.Gauge("datadog.trace_agent.tail_buffer.bytes", float64(stats.Bytes), nil, 1)
+}
+
+// hasRootSpan returns true if the trace contains the span starting it.
+func hasRootSpan(trace pb.Trace) bool {
+	for _, span := range trace {
+		if span.ParentID == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// tailRule is a tail sampling rule along with the sampling rule matching its
+// service, resource and tags conditions against the spans.
+type tailRule struct {
+	*config.TailSamplingRule
+	span *sampler.Rule // nil when the rule has no span condition
+}
+
+func newTailRule(r *config.TailSamplingRule) *tailRule {
+	tr := &tailRule{TailSamplingRule: r}
+	if r.Service == "" && r.ResourceRe == nil && len(r.Tags) == 0 {
+		return tr
+	}
+	tags := make(map[string]*regexp.Regexp, len(r.Tags))
+	for k, v := range r.Tags {
+		// the tags of the tail sampling rules match exact values
+		tags[k] = regexp.MustCompile("^" + regexp.QuoteMeta(v) + "$")
+	}
+	tr.span = &sampler.Rule{Service: r.Service, Resource: r.ResourceRe, Tags: tags}
+	return tr
+}
+
+// matchTailRule returns true if the trace matches all the conditions of the rule.
+func matchTailRule(r *tailRule, trace pb.Trace) bool {
+	if r.MinDurationMs > 0 && float64(traceDuration(trace)) < r.MinDurationMs*float64(time.Millisecond) {
+		return false
+	}
+	if r.Error && !traceContainsError(trace) {
+		return false
+	}
+	if r.span == nil {
+		return true
+	}
+	for _, span := range trace {
+		if r.span.Match(span) {
+			return true
+		}
+	}
+	return false
+}
+
+// traceDuration returns the time elapsed between the start of the first span
+// and the end of the last span of the trace, in nanoseconds.
+func traceDuration(trace pb.Trace) int64 {
+	var start, end int64
+	for i, span := range trace {
+		if i == 0 || span.Start < start {
+			start = span.Start
+		}
+		if i == 0 || span.Start+span.Duration > end {
+			end = span.Start + span.Duration
+		}
+	}
+	return end - start
+}
@@ -0,0 +1,313 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package agent
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/stats"
+	"github.com/DataDog/datadog-agent/pkg/trace/test/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestTailBuffer returns a TailBuffer recording the traces it releases.
+func newTestTailBuffer(ts *config.TailSamplingConfig) (*TailBuffer, *[]ProcessedTrace) {
+	conf := config.New()
+	conf.TailSampling = ts
+	var released []ProcessedTrace
+	b := NewTailBuffer(conf, func(_ *info.TagStats, pt ProcessedTrace) {
+		released = append(released, pt)
+	})
+	return b, &released
+}
+
+func testChunk(traceID uint64, spans ...*pb.Span) ProcessedTrace {
+	for _, s := range spans {
+		s.TraceID = traceID
+	}
+	return ProcessedTrace{Trace: pb.Trace(spans), Env: "none"}
+}
+
+func TestNewTailBuffer(t *testing.T) {
+	assert := assert.New(t)
+	b, _ := newTestTailBuffer(nil)
+	assert.Nil(b)
+	b, _ = newTestTailBuffer(&config.TailSamplingConfig{})
+	assert.Nil(b)
+	b, _ = newTestTailBuffer(&config.TailSamplingConfig{Enabled: true, WindowSeconds: 2.5})
+	assert.NotNil(b)
+	assert.Equal(2500*time.Millisecond, b.window)
+}
+
+func TestTailBufferRootSpan(t *testing.T) {
+	assert := assert.New(t)
+	b, released := newTestTailBuffer(&config.TailSamplingConfig{Enabled: true, WindowSeconds: 10})
+
+	first := testChunk(1, &pb.Span{SpanID: 3, ParentID: 2})
+	first.Debug = &api.DebugRecord{Trace: first.Trace}
+	b.Add(nil, first)
+	b.Add(nil, testChunk(2, &pb.Span{SpanID: 5, ParentID: 4}))
+	b.Add(nil, testChunk(1, &pb.Span{SpanID: 2, ParentID: 1, Meta: map[string]string{"env": "prod"}}))
+	assert.Empty(*released)
+	assert.EqualValues(2, b.stats.Traces)
+	assert.EqualValues(3, b.stats.Spans)
+
+	root := &pb.Span{SpanID: 1, ParentID: 0}
+	chunk := testChunk(1, root)
+	chunk.Sublayers = stats.SublayerMap{root: []stats.SublayerValue{{Metric: "_sublayers.span_count", Value: 3}}}
+	chunk.Debug = &api.DebugRecord{Trace: chunk.Trace}
+	b.Add(nil, chunk)
+	assert.Len(*released, 1)
+	pt := (*released)[0]
+	assert.Len(pt.Trace, 3)
+	assert.EqualValues(1, pt.Root.SpanID)
+	assert.Equal("prod", pt.Env)
+	// the whole trace is weighted from its root
+	assert.Len(pt.WeightedTrace, 3)
+	assert.Contains(pt.Sublayers, root)
+	// the trace is recorded once with the spans of the recorded parts
+	if assert.NotNil(pt.Debug) {
+		assert.Len(pt.Debug.Trace, 2)
+	}
+
+	assert.EqualValues(1, b.stats.Traces)
+	assert.EqualValues(1, b.stats.Spans)
+	assert.EqualValues(1, b.stats.Completed)
+	assert.Len(b.traces, 1)
+	assert.Equal(b.order.Len(), 1)
+}
+
+func TestTailBufferExpire(t *testing.T) {
+	assert := assert.New(t)
+	b, released := newTestTailBuffer(&config.TailSamplingConfig{Enabled: true, WindowSeconds: 10})
+
+	b.Add(nil, testChunk(1, &pb.Span{SpanID: 2, ParentID: 1}))
+	b.expire(time.Now())
+	assert.Empty(*released)
+
+	b.expire(time.Now().Add(11 * time.Second))
+	assert.Len(*released, 1)
+	assert.EqualValues(2, (*released)[0].Root.SpanID)
+	assert.EqualValues(1, b.stats.Expired)
+	assert.EqualValues(0, b.stats.Traces)
+	assert.Equal(0, b.bytes)
+}
+
+func TestTailBufferLimits(t *testing.T) {
+	t.Run("traces", func(t *testing.T) {
+		assert := assert.New(t)
+		b, released := newTestTailBuffer(&config.TailSamplingConfig{Enabled: true, WindowSeconds: 10, MaxTraces: 2})
+		for i := uint64(1); i <= 3; i++ {
+			b.Add(nil, testChunk(i, &pb.Span{SpanID: 2, ParentID: 1}))
+		}
+		assert.Len(*released, 1)
+		assert.EqualValues(1, (*released)[0].Trace[0].TraceID)
+		assert.EqualValues(1, b.stats.Evicted)
+		assert.Len(b.traces, 2)
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		assert := assert.New(t)
+		chunk := testChunk(1, &pb.Span{SpanID: 2, ParentID: 1})
+		b, released := newTestTailBuffer(&config.TailSamplingConfig{Enabled: true, WindowSeconds: 10, MaxMemory: float64(2 * chunk.Trace.Msgsize())})
+		b.Add(nil, chunk)
+		b.Add(nil, testChunk(1, &pb.Span{SpanID: 3, ParentID: 1}))
+		assert.Empty(*released)
+		b.Add(nil, testChunk(2, &pb.Span{SpanID: 5, ParentID: 4}))
+		assert.Len(*released, 1)
+		assert.Len((*released)[0].Trace, 2)
+		assert.EqualValues(1, b.stats.Evicted)
+	})
+
+	t.Run("watchdog", func(t *testing.T) {
+		assert := assert.New(t)
+		b, released := newTestTailBuffer(&config.TailSamplingConfig{Enabled: true, WindowSeconds: 10})
+		b.maxMemory = 1000
+		for i := uint64(1); i <= 4; i++ {
+			b.Add(nil, testChunk(i, &pb.Span{SpanID: 2, ParentID: 1}))
+		}
+
+		b.mem = func() uint64 { return 500 }
+		b.watchdog()
+		assert.Empty(*released)
+
+		b.mem = func() uint64 { return 2000 }
+		b.watchdog()
+		assert.Len(*released, 2)
+		assert.EqualValues(1, (*released)[0].Trace[0].TraceID)
+		assert.EqualValues(2, (*released)[1].Trace[0].TraceID)
+		assert.EqualValues(2, b.stats.Evicted)
+		assert.Len(b.traces, 2)
+	})
+}
+
+func TestTailBufferFlush(t *testing.T) {
+	assert := assert.New(t)
+	b, released := newTestTailBuffer(&config.TailSamplingConfig{Enabled: true, WindowSeconds: 10})
+	b.Start()
+	b.Add(nil, testChunk(1, &pb.Span{SpanID: 2, ParentID: 1}))
+	b.Add(nil, testChunk(2, &pb.Span{SpanID: 3, ParentID: 1}))
+	b.Stop()
+	assert.Len(*released, 2)
+	assert.EqualValues(0, b.stats.Evicted)
+	assert.Empty(b.traces)
+}
+
+func TestTailBufferApplyRules(t *testing.T) {
+	rate := func(r float64) *float64 { return &r }
+	trace := pb.Trace{
+		{TraceID: 1, SpanID: 1, Service: "web", Resource: "POST /checkout", Start: 0, Duration: int64(200 * time.Millisecond)},
+		{TraceID: 1, SpanID: 2, ParentID: 1, Service: "db", Resource: "SELECT", Start: int64(100 * time.Millisecond), Duration: int64(300 * time.Millisecond), Meta: map[string]string{"db.type": "postgres"}},
+	}
+	errTrace := pb.Trace{{TraceID: 1, SpanID: 1, Service: "web", Error: 1}}
+
+	for name, tt := range map[string]struct {
+		rule        *config.TailSamplingRule
+		trace       pb.Trace
+		wantMatched bool
+		wantSampled bool
+		wantRate    float64
+	}{
+		"empty": {
+			rule:        &config.TailSamplingRule{},
+			trace:       trace,
+			wantMatched: true,
+			wantSampled: true,
+			wantRate:    1,
+		},
+		"min duration": {
+			rule:        &config.TailSamplingRule{MinDurationMs: 400},
+			trace:       trace,
+			wantMatched: true,
+			wantSampled: true,
+			wantRate:    1,
+		},
+		"min duration too long": {
+			rule:  &config.TailSamplingRule{MinDurationMs: 401},
+			trace: trace,
+		},
+		"error": {
+			rule:        &config.TailSamplingRule{Error: true},
+			trace:       errTrace,
+			wantMatched: true,
+			wantSampled: true,
+			wantRate:    1,
+		},
+		"no error": {
+			rule:  &config.TailSamplingRule{Error: true},
+			trace: trace,
+		},
+		"service and tags": {
+			rule:        &config.TailSamplingRule{Service: "db", Tags: map[string]string{"db.type": "postgres"}},
+			trace:       trace,
+			wantMatched: true,
+			wantSampled: true,
+			wantRate:    1,
+		},
+		"service and tags on different spans": {
+			rule:  &config.TailSamplingRule{Service: "web", Tags: map[string]string{"db.type": "postgres"}},
+			trace: trace,
+		},
+		"tags exact value": {
+			rule:  &config.TailSamplingRule{Tags: map[string]string{"db.type": "postgre"}},
+			trace: trace,
+		},
+		"resource": {
+			rule:        &config.TailSamplingRule{ResourceRe: regexp.MustCompile("^POST /check")},
+			trace:       trace,
+			wantMatched: true,
+			wantSampled: true,
+			wantRate:    1,
+		},
+		"resource mismatch": {
+			rule:  &config.TailSamplingRule{Service: "db", ResourceRe: regexp.MustCompile("^POST /check")},
+			trace: trace,
+		},
+		"keep rate zero": {
+			rule:        &config.TailSamplingRule{Service: "web", KeepRate: rate(0)},
+			trace:       trace,
+			wantMatched: true,
+			wantSampled: false,
+			wantRate:    0,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			b, _ := newTestTailBuffer(&config.TailSamplingConfig{
+				Enabled: true,
+				Rules:   []*config.TailSamplingRule{tt.rule},
+			})
+			matched, sampled, rate := b.ApplyRules(ProcessedTrace{Trace: tt.trace, Root: tt.trace[0]})
+			assert.Equal(t, tt.wantMatched, matched)
+			assert.Equal(t, tt.wantSampled, sampled)
+			assert.Equal(t, tt.wantRate, rate)
+		})
+	}
+}
+
+func TestRunSamplersTailRules(t *testing.T) {
+	assert := assert.New(t)
+	b, _ := newTestTailBuffer(&config.TailSamplingConfig{
+		Enabled: true,
+		Rules:   []*config.TailSamplingRule{{Service: "web"}},
+	})
+	a := &Agent{
+		ScoreSampler:       newMockSampler(false, 0.1),
+		ErrorsScoreSampler: newMockSampler(false, 0.1),
+		PrioritySampler:    newMockSampler(false, 0.1),
+		TailBuffer:         b,
+	}
+
+	root := &pb.Span{TraceID: 1, SpanID: 1, Service: "web", Metrics: map[string]float64{}}
+	sampled, rate := a.runSamplers(ProcessedTrace{Trace: pb.Trace{root}, Root: root})
+	assert.True(sampled)
+	assert.Equal(1.0, rate)
+	assert.EqualValues(1, b.stats.RuleKept)
+
+	root = &pb.Span{TraceID: 2, SpanID: 1, Service: "db", Metrics: map[string]float64{}}
+	sampled, rate = a.runSamplers(ProcessedTrace{Trace: pb.Trace{root}, Root: root})
+	assert.False(sampled)
+	assert.Equal(0.1, rate)
+}
+
+func TestRunSamplersTailRulesUserKeep(t *testing.T) {
+	assert := assert.New(t)
+	keepRate := 0.0
+	b, _ := newTestTailBuffer(&config.TailSamplingConfig{
+		Enabled: true,
+		Rules:   []*config.TailSamplingRule{{Service: "web", KeepRate: &keepRate}},
+	})
+	priorityEngine := testutil.NewMockEngine(true, 1)
+	a := &Agent{
+		ScoreSampler:       newMockSampler(false, 0.1),
+		ErrorsScoreSampler: newMockSampler(false, 0.1),
+		PrioritySampler:    &Sampler{engine: priorityEngine},
+		TailBuffer:         b,
+	}
+
+	// the trace kept by the user is not dropped by the rule
+	root := &pb.Span{TraceID: 1, SpanID: 1, Service: "web", Metrics: map[string]float64{}}
+	sampler.SetSamplingPriority(root, sampler.PriorityUserKeep)
+	sampled, rate := a.runSamplers(ProcessedTrace{Trace: pb.Trace{root}, Root: root})
+	assert.True(sampled)
+	assert.Equal(1.0, rate)
+	assert.EqualValues(0, b.stats.RuleDropped)
+
+	// the rule applies to the other traces, which are still counted by the priority sampler
+	root = &pb.Span{TraceID: 2, SpanID: 1, Service: "web", Metrics: map[string]float64{}}
+	sampler.SetSamplingPriority(root, sampler.PriorityAutoKeep)
+	sampled, rate = a.runSamplers(ProcessedTrace{Trace: pb.Trace{root}, Root: root})
+	assert.False(sampled)
+	assert.Equal(0.0, rate)
+	assert.EqualValues(1, b.stats.RuleDropped)
+	assert.Equal(2, priorityEngine.Calls)
+}
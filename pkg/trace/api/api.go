@@ -29,6 +29,7 @@ import (
 
 	"github.com/tinylib/msgp/msgp"
 
+	"github.com/DataDog/datadog-agent/pkg/api/util"
 	"github.com/DataDog/datadog-agent/pkg/tagger"
 	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
@@ -68,6 +69,11 @@ const (
 	// Traces: msgpack/JSON (Content-Type) slice of traces + returns service sampling ratios
 	// Services: deprecated
 	v04 Version = "v0.4"
+	// v05
+	// Traces: msgpack slice of traces in the dictionary encoding, where spans reference
+	// their strings by index in a shared dictionary + returns service sampling ratios
+	// Services: not supported
+	v05 Version = "v0.5"
 )
 
 // HTTPReceiver is a collector that uses HTTP protocol and just holds
@@ -76,6 +82,9 @@ type HTTPReceiver struct {
 	Stats       *info.ReceiverStats
 	RateLimiter *rateLimiter
 
+	// Debug records the last traces received, it is nil unless enabled.
+	Debug *DebugRecorder
+
 	out     chan *Trace
 	conf    *config.AgentConfig
 	dynConf *sampler.DynamicConfig
@@ -95,9 +104,14 @@ func NewHTTPReceiver(conf *config.AgentConfig, dynConf *sampler.DynamicConfig, o
 	if config.HasFeature("429") {
 		rateLimiterResponse = http.StatusTooManyRequests
 	}
+	var debug *DebugRecorder
+	if conf.DebugTraces != nil {
+		debug = NewDebugRecorder(conf.DebugTraces.MaxTraces, conf.DebugTraces.MaxBytes)
+	}
 	return &HTTPReceiver{
 		Stats:       info.NewReceiverStats(),
 		RateLimiter: newRateLimiter(),
+		Debug:       debug,
 		out:         out,
 
 		conf:    conf,
@@ -127,6 +141,7 @@ func (r *HTTPReceiver) Start() {
 	mux.HandleFunc("/v0.3/services", r.handleWithVersion(v03, r.handleServices))
 	mux.HandleFunc("/v0.4/traces", r.handleWithVersion(v04, r.handleTraces))
 	mux.HandleFunc("/v0.4/services", r.handleWithVersion(v04, r.handleServices))
+	mux.HandleFunc("/v0.5/traces", r.handleWithVersion(v05, r.handleTraces))
 
 	timeout := 5 * time.Second
 	if r.conf.ReceiverTimeout > 0 {
@@ -201,6 +216,15 @@ func (r *HTTPReceiver) attachDebugHandlers(mux *http.ServeMux) {
 	})
 
 	mux.Handle("/debug/vars", expvar.Handler())
+
+	if r.Debug != nil {
+		if err := util.SetAuthToken(); err != nil {
+			log.Errorf("Not recording the traces for debugging, could not get the auth token: %v", err)
+			r.Debug = nil
+			return
+		}
+		mux.HandleFunc("/debug/traces", r.handleDebugTraces)
+	}
 }
 
 // listenUnix returns a net.Listener listening on the given "unix" socket path.
@@ -259,11 +283,17 @@ func (r *HTTPReceiver) Stop() error {
 
 func (r *HTTPReceiver) handleWithVersion(v Version, f func(Version, http.ResponseWriter, *http.Request)) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		if mediaType := getMediaType(req); mediaType == "application/msgpack" && (v == v01 || v == v02) {
+		mediaType := getMediaType(req)
+		if mediaType == "application/msgpack" && (v == v01 || v == v02) {
 			// msgpack is only supported for versions >= v0.3
 			httpFormatError(w, v, fmt.Errorf("unsupported media type: %q", mediaType))
 			return
 		}
+		if mediaType != "application/msgpack" && v == v05 {
+			// the dictionary encoding only exists in msgpack
+			httpFormatError(w, v, fmt.Errorf("unsupported media type: %q", mediaType))
+			return
+		}
 
 		req.Body = NewLimitedReader(req.Body, r.maxRequestBodyLength)
 
@@ -316,14 +346,19 @@ const (
 	headerTracerVersion = "Datadog-Meta-Tracer-Version"
 )
 
-func (r *HTTPReceiver) tagStats(req *http.Request) *info.TagStats {
-	return r.Stats.GetTagStats(info.Tags{
+func (r *HTTPReceiver) tagStats(v Version, req *http.Request) *info.TagStats {
+	tags := info.Tags{
 		Lang:          req.Header.Get(headerLang),
 		LangVersion:   req.Header.Get(headerLangVersion),
 		Interpreter:   req.Header.Get(headerLangInterpreter),
 		LangVendor:    req.Header.Get(headerLangInterpreterVendor),
 		TracerVersion: req.Header.Get(headerTracerVersion),
-	})
+	}
+	if v == v05 {
+		// the stats of the older endpoints keep their tags
+		tags.EndpointVersion = string(v)
+	}
+	return r.Stats.GetTagStats(tags)
 }
 
 func (r *HTTPReceiver) decodeTraces(v Version, req *http.Request) (pb.Traces, error) {
@@ -334,6 +369,17 @@ func (r *HTTPReceiver) decodeTraces(v Version, req *http.Request) (pb.Traces, er
 		}
 		return tracesFromSpans(spans), nil
 	}
+	if v == v05 {
+		buf, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		var traces pb.Traces
+		if err := traces.UnmarshalMsgDictionary(buf); err != nil {
+			return nil, err
+		}
+		return traces, nil
+	}
 	var traces pb.Traces
 	if err := decodeRequest(req, &traces); err != nil {
 		return nil, err
@@ -345,14 +391,14 @@ func (r *HTTPReceiver) replyOK(v Version, w http.ResponseWriter) {
 	switch v {
 	case v01, v02, v03:
 		httpOK(w)
-	case v04:
+	case v04, v05:
 		httpRateByService(w, r.dynConf)
 	}
 }
 
 // handleTraces knows how to handle a bunch of traces
 func (r *HTTPReceiver) handleTraces(v Version, w http.ResponseWriter, req *http.Request) {
-	ts := r.tagStats(req)
+	ts := r.tagStats(v, req)
 	traceCount, err := traceCount(req)
 	if err != nil {
 		log.Warnf("Error getting trace count: %q. Functionality may be limited.", err)
@@ -407,6 +453,10 @@ type Trace struct {
 
 	// Spans holds the spans of this trace.
 	Spans pb.Trace
+
+	// Debug holds the record of this trace when the receiver records the
+	// traces for debugging, it is nil otherwise.
+	Debug *DebugRecord
 }
 
 func (r *HTTPReceiver) processTraces(ts *info.TagStats, containerID string, traces pb.Traces) {
@@ -418,10 +468,24 @@ func (r *HTTPReceiver) processTraces(ts *info.TagStats, containerID string, trac
 
 		atomic.AddInt64(&ts.SpansReceived, int64(spans))
 
-		err := normalizeTrace(ts, trace)
+		var (
+			rec *DebugRecord
+			err error
+		)
+		if r.Debug != nil {
+			rec = newDebugRecord(ts.Tags, trace)
+			err = r.normalizeTraceDebug(ts, trace, rec)
+		} else {
+			err = normalizeTrace(ts, trace)
+		}
 		if err != nil {
 			log.Debug("Dropping invalid trace: %s", err)
 			atomic.AddInt64(&ts.SpansDropped, int64(spans))
+			if rec != nil {
+				rec.Decision = DecisionDropped
+				rec.Reason = err.Error()
+				r.Debug.Add(rec)
+			}
 			continue
 		}
 
@@ -429,6 +493,7 @@ func (r *HTTPReceiver) processTraces(ts *info.TagStats, containerID string, trac
 			Source:        &ts.Tags,
 			ContainerTags: containerTags,
 			Spans:         trace,
+			Debug:         rec,
 		}
 	}
 }
@@ -329,6 +329,73 @@ func TestReceiverMsgpackDecoder(t *testing.T) {
 	}
 }
 
+func TestReceiverDictionaryDecoder(t *testing.T) {
+	assert := assert.New(t)
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	server := httptest.NewServer(http.HandlerFunc(r.handleWithVersion(v05, r.handleTraces)))
+	defer server.Close()
+	client := &http.Client{}
+
+	t.Run("msgpack", func(t *testing.T) {
+		payload := testutil.GetTestTraces(1, 1, false).AppendMsgDictionary(nil)
+		req, err := http.NewRequest("POST", server.URL, bytes.NewReader(payload))
+		assert.NoError(err)
+		req.Header.Set("Content-Type", "application/msgpack")
+		req.Header.Set("Datadog-Meta-Lang", "go")
+
+		resp, err := client.Do(req)
+		assert.NoError(err)
+		assert.Equal(200, resp.StatusCode)
+		var tr traceResponse
+		assert.NoError(json.NewDecoder(resp.Body).Decode(&tr), "the answer should be a valid JSON")
+		resp.Body.Close()
+
+		select {
+		case rt := <-r.out:
+			assert.Len(rt.Spans, 1)
+			span := rt.Spans[0]
+			assert.Equal(uint64(42), span.TraceID)
+			assert.Equal(uint64(52), span.SpanID)
+			assert.Equal("fennel_is_amazing", span.Service)
+			assert.Equal("something_that_should_be_a_metric", span.Name)
+			assert.Equal("NOT touched because it is going to be hashed", span.Resource)
+			assert.Equal("192.168.0.1", span.Meta["http.host"])
+			assert.Equal(41.99, span.Metrics["http.monitor"])
+		case <-time.After(time.Second):
+			t.Fatalf("no data received")
+		}
+		ts := r.Stats.GetTagStats(info.Tags{Lang: "go", EndpointVersion: "v0.5"})
+		assert.EqualValues(1, ts.TracesReceived)
+		assert.EqualValues(len(payload), ts.TracesBytes)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		req, err := http.NewRequest("POST", server.URL, strings.NewReader("[]"))
+		assert.NoError(err)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		assert.NoError(err)
+		assert.Equal(415, resp.StatusCode)
+		resp.Body.Close()
+	})
+
+	t.Run("decoding-error", func(t *testing.T) {
+		payload := testutil.GetTestTraces(1, 1, false).AppendMsgDictionary(nil)
+		payload[1] = 0x90 // empty dictionary, followed by its strings instead of the traces
+		req, err := http.NewRequest("POST", server.URL, bytes.NewReader(payload))
+		assert.NoError(err)
+		req.Header.Set("Content-Type", "application/msgpack")
+		req.Header.Set(headerTraceCount, "1")
+
+		resp, err := client.Do(req)
+		assert.NoError(err)
+		assert.Equal(400, resp.StatusCode)
+		resp.Body.Close()
+		assert.EqualValues(1, r.Stats.GetTagStats(info.Tags{EndpointVersion: "v0.5"}).TracesDropped.DecodingError)
+	})
+}
+
 func TestReceiverDecodingError(t *testing.T) {
 	assert := assert.New(t)
 	conf := newTestReceiverConfig()
@@ -638,6 +705,19 @@ func BenchmarkDecoderMsgpack(b *testing.B) {
 	}
 }
 
+func BenchmarkDecoderMsgpackDictionary(b *testing.B) {
+	payload := testutil.GetTestTraces(150, 66, true).AppendMsgDictionary(nil)
+
+	// benchmark
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		var traces pb.Traces
+		_ = traces.UnmarshalMsgDictionary(payload)
+	}
+}
+
 func BenchmarkWatchdog(b *testing.B) {
 	now := time.Now()
 	conf := config.New()
@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// Decisions taken on a trace, as found in DebugRecord.Decision.
+const (
+	// DecisionDropped is when the trace was dropped by the normalizer.
+	DecisionDropped = "dropped"
+	// DecisionFiltered is when the trace was filtered by the blacklister or the tag filter.
+	DecisionFiltered = "filtered"
+	// DecisionRejected is when the trace was rejected by the client, through its sampling priority.
+	DecisionRejected = "rejected"
+	// DecisionSampled is when the trace was kept by the samplers.
+	DecisionSampled = "sampled"
+	// DecisionNotSampled is when the trace was dropped by the samplers.
+	DecisionNotSampled = "not_sampled"
+)
+
+// DebugRecord holds a trace as it was received by the agent, along with the
+// decisions taken on it. It must not be modified once added to a DebugRecorder.
+type DebugRecord struct {
+	Received time.Time `json:"received"`
+	Source   info.Tags `json:"source"`
+	Trace    pb.Trace  `json:"trace"` // the spans before normalization
+
+	// Processed holds the spans once normalized, obfuscated and truncated, it is
+	// empty when the trace was dropped or filtered before that.
+	Processed pb.Trace `json:"processed,omitempty"`
+
+	// Normalizer holds the fixes applied to the spans by the normalizer, in the
+	// "reason:count" format of the spans_malformed stats.
+	Normalizer string `json:"normalizer,omitempty"`
+
+	// Decision holds the final decision taken on the trace, one of the Decision* constants.
+	Decision string `json:"decision"`
+	// Reason explains why the trace was dropped or filtered.
+	Reason string `json:"reason,omitempty"`
+	// SampleRate holds the rate returned by the samplers.
+	SampleRate float64 `json:"sample_rate,omitempty"`
+	// EventsExtracted and EventsSampled count the APM events extracted from the trace
+	// and the ones which were kept.
+	EventsExtracted int `json:"events_extracted"`
+	EventsSampled   int `json:"events_sampled"`
+
+	size int
+}
+
+// newDebugRecord returns a new record of the trace t, which is copied so that
+// the record is not altered by the processing of the trace.
+func newDebugRecord(tags info.Tags, t pb.Trace) *DebugRecord {
+	rec := &DebugRecord{
+		Received: time.Now(),
+		Source:   tags,
+		Trace:    copyTrace(t),
+	}
+	rec.size = rec.Trace.Msgsize()
+	return rec
+}
+
+// SetProcessed records a copy of t as the processed trace of rec. It is a no-op
+// when rec is nil.
+func (rec *DebugRecord) SetProcessed(t pb.Trace) {
+	if rec == nil {
+		return
+	}
+	rec.Processed = copyTrace(t)
+	rec.size += rec.Processed.Msgsize()
+}
+
+// Append adds the spans of o, the record of another part of the same trace, to
+// rec. It is a no-op when either rec or o is nil.
+func (rec *DebugRecord) Append(o *DebugRecord) {
+	if rec == nil || o == nil {
+		return
+	}
+	rec.Trace = append(rec.Trace, o.Trace...)
+	rec.Processed = append(rec.Processed, o.Processed...)
+	rec.size += o.size
+}
+
+// copyTrace returns a deep copy of t.
+func copyTrace(t pb.Trace) pb.Trace {
+	cp := make(pb.Trace, len(t))
+	for i, s := range t {
+		span := *s
+		if s.Meta != nil {
+			span.Meta = make(map[string]string, len(s.Meta))
+			for k, v := range s.Meta {
+				span.Meta[k] = v
+			}
+		}
+		if s.Metrics != nil {
+			span.Metrics = make(map[string]float64, len(s.Metrics))
+			for k, v := range s.Metrics {
+				span.Metrics[k] = v
+			}
+		}
+		cp[i] = &span
+	}
+	return cp
+}
+
+// DebugRecorder keeps the last traces received by the agent in a ring buffer
+// bounded both in number of traces and in bytes.
+type DebugRecorder struct {
+	maxTraces int
+	maxBytes  int
+
+	mu      sync.RWMutex
+	records []*DebugRecord // oldest first
+	bytes   int
+}
+
+// NewDebugRecorder returns a new DebugRecorder keeping up to maxTraces traces
+// and maxBytes bytes.
+func NewDebugRecorder(maxTraces, maxBytes int) *DebugRecorder {
+	return &DebugRecorder{maxTraces: maxTraces, maxBytes: maxBytes}
+}
+
+// Add adds rec to the recorder, evicting the oldest records as needed. It is a
+// no-op when either d or rec is nil.
+func (d *DebugRecorder) Add(rec *DebugRecord) {
+	if d == nil || rec == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.records = append(d.records, rec)
+	d.bytes += rec.size
+	for len(d.records) > 0 && (len(d.records) > d.maxTraces || d.bytes > d.maxBytes) {
+		d.bytes -= d.records[0].size
+		d.records[0] = nil
+		d.records = d.records[1:]
+	}
+}
+
+// Records returns the records held by d, oldest first.
+func (d *DebugRecorder) Records() []*DebugRecord {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return append(make([]*DebugRecord, 0, len(d.records)), d.records...)
+}
+
+// normalizeTraceDebug normalizes the trace t like normalizeTrace and writes the
+// fixes applied by the normalizer into rec. The stats of the trace are collected
+// apart, to tell its fixes from the ones of the traces normalized concurrently,
+// and then added to ts.
+func (r *HTTPReceiver) normalizeTraceDebug(ts *info.TagStats, t pb.Trace, rec *DebugRecord) error {
+	local := info.NewReceiverStats()
+	lts := local.GetTagStats(ts.Tags)
+	err := normalizeTrace(lts, t)
+	rec.Normalizer = lts.SpansMalformed.String()
+	r.Stats.Acc(local)
+	return err
+}
+
+// handleDebugTraces serves the records of the recorder as JSON to the clients
+// authenticated with the auth token of the agent.
+func (r *HTTPReceiver) handleDebugTraces(w http.ResponseWriter, req *http.Request) {
+	if err := util.Validate(w, req); err != nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(r.Debug.Records()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
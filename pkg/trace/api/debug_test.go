@@ -0,0 +1,161 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/test/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugRecorder(t *testing.T) {
+	newRecord := func(traceID uint64) *DebugRecord {
+		return newDebugRecord(info.Tags{Lang: "go"}, pb.Trace{{TraceID: traceID, SpanID: 1}})
+	}
+	traceIDs := func(records []*DebugRecord) []uint64 {
+		var ids []uint64
+		for _, rec := range records {
+			ids = append(ids, rec.Trace[0].TraceID)
+		}
+		return ids
+	}
+
+	t.Run("max-traces", func(t *testing.T) {
+		d := NewDebugRecorder(2, 1<<20)
+		assert.NotNil(t, d.Records())
+		assert.Len(t, d.Records(), 0)
+		for i := uint64(1); i <= 3; i++ {
+			d.Add(newRecord(i))
+		}
+		assert.Equal(t, []uint64{2, 3}, traceIDs(d.Records()))
+	})
+
+	t.Run("max-bytes", func(t *testing.T) {
+		size := newRecord(1).size
+		d := NewDebugRecorder(100, 2*size)
+		for i := uint64(1); i <= 3; i++ {
+			d.Add(newRecord(i))
+		}
+		assert.Equal(t, []uint64{2, 3}, traceIDs(d.Records()))
+		assert.Equal(t, 2*size, d.bytes)
+
+		// a record larger than the recorder is not kept
+		big := newRecord(4)
+		big.size = 3 * size
+		d.Add(big)
+		assert.Len(t, d.Records(), 0)
+		assert.Equal(t, 0, d.bytes)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var d *DebugRecorder
+		assert.NotPanics(t, func() { d.Add(newRecord(1)) })
+		d = NewDebugRecorder(2, 1<<20)
+		d.Add(nil)
+		assert.Len(t, d.Records(), 0)
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		trace := pb.Trace{testutil.GetTestSpan()}
+		rec := newDebugRecord(info.Tags{}, trace)
+		trace[0].Service = "changed"
+		trace[0].Meta["changed"] = "yes"
+		assert.NotEqual(t, "changed", rec.Trace[0].Service)
+		assert.NotContains(t, rec.Trace[0].Meta, "changed")
+	})
+
+	t.Run("append", func(t *testing.T) {
+		rec := newRecord(1)
+		rec.SetProcessed(rec.Trace)
+		size := rec.size
+		other := newRecord(1)
+		other.SetProcessed(other.Trace)
+		rec.Append(other)
+		assert.Len(t, rec.Trace, 2)
+		assert.Len(t, rec.Processed, 2)
+		assert.Equal(t, 2*size, rec.size)
+
+		rec.Append(nil)
+		assert.Len(t, rec.Trace, 2)
+		var empty *DebugRecord
+		assert.NotPanics(t, func() { empty.Append(other) })
+	})
+}
+
+func TestDebugTracesRecording(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.DebugTraces = &config.DebugTracesConfig{Enabled: true, MaxTraces: 10, MaxBytes: 1 << 20}
+	r := newTestReceiverFromConfig(conf)
+	assert.NotNil(t, r.Debug)
+
+	fixed := testutil.GetTestSpan()
+	fixed.Service = ""
+	dropped := testutil.GetTestSpan()
+	dropped.TraceID = 0
+
+	ts := r.Stats.GetTagStats(info.Tags{Lang: "go"})
+	r.processTraces(ts, "", pb.Traces{{fixed}, {dropped}})
+
+	records := r.Debug.Records()
+	assert.Len(t, records, 1)
+	assert.Equal(t, DecisionDropped, records[0].Decision)
+	assert.Contains(t, records[0].Reason, "trace_id_zero")
+	assert.Equal(t, "go", records[0].Source.Lang)
+
+	out := <-r.out
+	if assert.NotNil(t, out.Debug) {
+		assert.Contains(t, out.Debug.Normalizer, "service_empty:1")
+		assert.Equal(t, "", out.Debug.Trace[0].Service)
+		assert.NotEqual(t, "", out.Spans[0].Service)
+	}
+	assert.EqualValues(t, 1, ts.SpansMalformed.ServiceEmpty)
+	assert.EqualValues(t, 1, ts.TracesDropped.TraceIDZero)
+}
+
+func TestHandleDebugTraces(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.DebugTraces = &config.DebugTracesConfig{Enabled: true, MaxTraces: 10, MaxBytes: 1 << 20}
+	r := newTestReceiverFromConfig(conf)
+	r.Debug.Add(newDebugRecord(info.Tags{Lang: "go"}, pb.Trace{testutil.GetTestSpan()}))
+
+	for name, tt := range map[string]struct {
+		auth string
+		code int
+	}{
+		"no-token":      {"", http.StatusUnauthorized},
+		"wrong-scheme":  {"Basic " + util.GetAuthToken(), http.StatusUnauthorized},
+		"invalid-token": {"Bearer " + util.GetAuthToken() + "invalid", http.StatusForbidden},
+		"valid-token":   {"Bearer " + util.GetAuthToken(), http.StatusOK},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/debug/traces", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rr := httptest.NewRecorder()
+			r.handleDebugTraces(rr, req)
+			assert.Equal(t, tt.code, rr.Code)
+			if tt.code != http.StatusOK {
+				return
+			}
+			var records []*DebugRecord
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&records))
+			if assert.Len(t, records, 1) {
+				assert.Equal(t, "go", records[0].Source.Lang)
+				assert.Equal(t, testutil.GetTestSpan().Resource, records[0].Trace[0].Resource)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -45,6 +46,22 @@ type ObfuscationConfig struct {
 	// Memcached holds the configuration for obfuscating the "memcached.command" tag
 	// for spans of type "memcached".
 	Memcached Enablable `mapstructure:"memcached"`
+
+	// GraphQL holds the configuration for obfuscating the queries and variables
+	// of spans of type "graphql".
+	GraphQL Enablable `mapstructure:"graphql"`
+
+	// MessageQueue holds the configuration for obfuscating the message payload tags
+	// of spans of type "queue", "kafka" and "amqp".
+	MessageQueue Enablable `mapstructure:"message_queue"`
+
+	// SQLDialects holds the configuration for tokenizing dialect-specific SQL syntax:
+	// dollar-quoted strings, backtick quoted identifiers and $1 placeholders.
+	SQLDialects Enablable `mapstructure:"sql_dialects"`
+
+	// CreditCards holds the configuration for masking the credit card numbers
+	// found in the tags of all spans.
+	CreditCards Enablable `mapstructure:"credit_cards"`
 }
 
 // HTTPObfuscationConfig holds the configuration settings for HTTP obfuscation.
@@ -90,6 +107,121 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// TagRule matches a span tag by key, and by value when one is given.
+type TagRule struct {
+	// Key is the key of the tag.
+	Key string
+	// Value matches the value of the tag exactly when not empty, unless Re is set.
+	Value string
+	// Re matches the value of the tag instead of Value when not nil.
+	Re *regexp.Regexp
+}
+
+// String returns the rule in its "key[:value]" configuration format.
+func (r *TagRule) String() string {
+	switch {
+	case r.Value != "":
+		return r.Key + ":" + r.Value
+	case r.Re != nil:
+		return r.Key + ":" + r.Re.String()
+	}
+	return r.Key
+}
+
+// SamplingRule specifies a user-defined sampling rule. A trace matches when its
+// root span matches all the conditions set on the rule.
+type SamplingRule struct {
+	// Service matches the root spans of this service.
+	Service string `mapstructure:"service"`
+
+	// Name matches the root spans of this operation name.
+	Name string `mapstructure:"name"`
+
+	// Resource specifies a regexp pattern matching the resource of the root span. It must compile.
+	Resource string `mapstructure:"resource"`
+
+	// ResourceRe holds the compiled Resource and is only used internally.
+	ResourceRe *regexp.Regexp `mapstructure:"-"`
+
+	// Tags maps tag keys to regexp patterns matching the tag values of the
+	// root span. They must compile.
+	Tags map[string]string `mapstructure:"tags"`
+
+	// TagsRe holds the compiled Tags and is only used internally.
+	TagsRe map[string]*regexp.Regexp `mapstructure:"-"`
+
+	// Rate specifies the rate at which the matching traces are kept. It is required.
+	Rate *float64 `mapstructure:"rate"`
+
+	// MaxTPS specifies the maximum number of matching traces kept per second,
+	// zero disables the limit.
+	MaxTPS float64 `mapstructure:"max_tps"`
+}
+
+// TailSamplingConfig holds the configuration of the buffer delaying the sampling
+// decision of a trace until all its spans were received.
+type TailSamplingConfig struct {
+	// Enabled specifies whether traces are buffered before being sampled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// WindowSeconds specifies how long the spans of a trace are held waiting
+	// for its root span, in seconds. Fractions are permitted.
+	WindowSeconds float64 `mapstructure:"window_seconds"`
+
+	// MaxTraces specifies the maximum number of traces held in the buffer.
+	MaxTraces int `mapstructure:"max_traces"`
+
+	// MaxMemory specifies the maximum size of the spans held in the buffer, in bytes.
+	MaxMemory float64 `mapstructure:"max_memory"`
+
+	// Rules specifies the rules deciding whether a complete trace is kept, they
+	// are evaluated in order before the samplers. The first matching rule wins.
+	Rules []*TailSamplingRule `mapstructure:"rules"`
+}
+
+// TailSamplingRule specifies a rule matching complete traces. A trace matches
+// when all the conditions set on the rule are true.
+type TailSamplingRule struct {
+	// Name specifies the name of the rule, it is used in logs only.
+	Name string `mapstructure:"name"`
+
+	// MinDurationMs matches the traces lasting at least this many milliseconds.
+	MinDurationMs float64 `mapstructure:"min_duration_ms"`
+
+	// Error matches the traces containing at least one error.
+	Error bool `mapstructure:"error"`
+
+	// Service matches the traces containing a span of this service.
+	Service string `mapstructure:"service"`
+
+	// Resource specifies a regexp pattern matching the traces containing a span
+	// with a matching resource. It must compile.
+	Resource string `mapstructure:"resource"`
+
+	// ResourceRe holds the compiled Resource and is only used internally.
+	ResourceRe *regexp.Regexp `mapstructure:"-"`
+
+	// Tags matches the traces containing a span having all these tags.
+	Tags map[string]string `mapstructure:"tags"`
+
+	// KeepRate specifies the probability of keeping a matching trace, matching
+	// traces which are not kept are dropped. It defaults to 1.
+	KeepRate *float64 `mapstructure:"keep_rate"`
+}
+
+// DebugTracesConfig holds the configuration of the buffer keeping the last traces
+// received by the agent, along with the decisions taken on them, for debugging.
+type DebugTracesConfig struct {
+	// Enabled specifies whether the traces are recorded.
+	Enabled bool `mapstructure:"enabled"`
+
+	// MaxTraces specifies the maximum number of traces kept.
+	MaxTraces int `mapstructure:"max_traces"`
+
+	// MaxBytes specifies the maximum size of the traces kept, in bytes.
+	MaxBytes int `mapstructure:"max_bytes"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -105,6 +237,55 @@ type WriterConfig struct {
 	FlushPeriodSeconds float64 `mapstructure:"flush_period_seconds"`
 }
 
+// DiskSpoolConfig holds the configuration of the spools keeping on disk the payloads
+// which could not be sent, until the endpoints recover.
+type DiskSpoolConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// Path specifies the directory holding the spools.
+	Path string `mapstructure:"path"`
+
+	// MaxSize specifies the maximum size of each spool, in bytes. There is a spool
+	// per writer and endpoint.
+	MaxSize int64 `mapstructure:"max_size"`
+
+	// MaxAgeSeconds specifies how long a payload is kept on disk before being dropped.
+	MaxAgeSeconds int `mapstructure:"max_age_seconds"`
+}
+
+// PeerTagsConfig holds the configuration of the inference of the "peer.service" and
+// "peer.hostname" tags on the client spans.
+type PeerTagsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// ExtraServiceKeys specifies additional meta keys from which "peer.service" is
+	// inferred, after the default ones.
+	ExtraServiceKeys []string `mapstructure:"extra_service_keys"`
+
+	// ExtraHostnameKeys specifies additional meta keys from which "peer.hostname" is
+	// inferred, after the default ones.
+	ExtraHostnameKeys []string `mapstructure:"extra_hostname_keys"`
+}
+
+// TraceMetricsConfig holds the configuration of the exporter sending the stats of the
+// traces as DogStatsD metrics.
+type TraceMetricsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// Tags specifies the tags of the stats kept on the metrics, among "env", "service",
+	// "resource" and the extra aggregators.
+	Tags []string `mapstructure:"tags"`
+
+	// MaxResourcesPerService specifies the maximum number of resources tagged for each
+	// service, the metrics of the other resources are tagged "resource:_other". There is
+	// no limit when it is 0.
+	MaxResourcesPerService int `mapstructure:"max_resources_per_service"`
+
+	// DogStatsDSocket specifies the path of the unix socket of DogStatsD. The metrics
+	// are sent to the DogStatsD UDP port when empty.
+	DogStatsDSocket string `mapstructure:"dogstatsd_socket"`
+}
+
 func (c *AgentConfig) applyDatadogConfig() error {
 	if len(c.Endpoints) == 0 {
 		c.Endpoints = []*Endpoint{{}}
@@ -194,6 +375,9 @@ func (c *AgentConfig) applyDatadogConfig() error {
 	if config.Datadog.IsSet("apm_config.max_traces_per_second") {
 		c.MaxTPS = config.Datadog.GetFloat64("apm_config.max_traces_per_second")
 	}
+	if config.Datadog.IsSet("apm_config.stats_sketches") {
+		c.StatsSketches = config.Datadog.GetBool("apm_config.stats_sketches")
+	}
 	if config.Datadog.IsSet("apm_config.ignore_resources") {
 		c.Ignore["resource"] = config.Datadog.GetStringSlice("apm_config.ignore_resources")
 	}
@@ -232,6 +416,91 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		}
 	}
 
+	for _, f := range []struct {
+		key   string
+		regex bool
+		rules *[]*TagRule
+	}{
+		{"apm_config.filter_tags.require", false, &c.RequireTags},
+		{"apm_config.filter_tags_regex.require", true, &c.RequireTags},
+		{"apm_config.filter_tags.reject", false, &c.RejectTags},
+		{"apm_config.filter_tags_regex.reject", true, &c.RejectTags},
+	} {
+		if !config.Datadog.IsSet(f.key) {
+			continue
+		}
+		rules, err := parseTagRules(config.Datadog.GetStringSlice(f.key), f.regex)
+		if err != nil {
+			osutil.Exitf("%s: %s", strings.TrimPrefix(f.key, "apm_config."), err)
+		}
+		*f.rules = append(*f.rules, rules...)
+	}
+
+	if config.Datadog.IsSet("apm_config.sampling_rules") {
+		var rules []*SamplingRule
+		err := config.Datadog.UnmarshalKey("apm_config.sampling_rules", &rules)
+		if err == nil {
+			if err := compileSamplingRules(rules); err != nil {
+				osutil.Exitf("sampling_rules: %s", err)
+			}
+			c.SamplingRules = rules
+		}
+	}
+
+	if config.Datadog.IsSet("apm_config.tail_sampling") {
+		ts := TailSamplingConfig{
+			WindowSeconds: 10,
+			MaxTraces:     10000,
+			MaxMemory:     1e8, // 100 Mb
+		}
+		err := config.Datadog.UnmarshalKey("apm_config.tail_sampling", &ts)
+		if err == nil {
+			if err := compileTailSamplingConfig(&ts); err != nil {
+				osutil.Exitf("tail_sampling: %s", err)
+			}
+			c.TailSampling = &ts
+		}
+	}
+
+	if config.Datadog.IsSet("apm_config.debug_traces") {
+		dt := DebugTracesConfig{
+			MaxTraces: 100,
+			MaxBytes:  10 * 1024 * 1024,
+		}
+		if err := config.Datadog.UnmarshalKey("apm_config.debug_traces", &dt); err == nil && dt.Enabled {
+			c.DebugTraces = &dt
+		}
+	}
+
+	if config.Datadog.IsSet("apm_config.disk_spool") {
+		ds := DiskSpoolConfig{
+			Path:          filepath.Join(config.Datadog.GetString("run_path"), "apm-spool"),
+			MaxSize:       500 * 1024 * 1024,
+			MaxAgeSeconds: 3600,
+		}
+		if err := config.Datadog.UnmarshalKey("apm_config.disk_spool", &ds); err == nil && ds.Enabled {
+			c.DiskSpool = &ds
+		}
+	}
+
+	if config.Datadog.IsSet("apm_config.peer_tags") {
+		var pt PeerTagsConfig
+		if err := config.Datadog.UnmarshalKey("apm_config.peer_tags", &pt); err == nil && pt.Enabled {
+			c.PeerTags = &pt
+		}
+	}
+
+	if config.Datadog.IsSet("apm_config.trace_metrics") {
+		tm := TraceMetricsConfig{
+			Tags:                   []string{"env", "service", "resource"},
+			MaxResourcesPerService: 100,
+			DogStatsDSocket:        config.Datadog.GetString("dogstatsd_socket"),
+		}
+		if err := config.Datadog.UnmarshalKey("apm_config.trace_metrics", &tm); err == nil && tm.Enabled {
+			c.TraceMetrics = &tm
+		}
+	}
+
 	// undocumented
 	if config.Datadog.IsSet("apm_config.max_cpu_percent") {
 		c.MaxCPU = config.Datadog.GetFloat64("apm_config.max_cpu_percent") / 100
@@ -290,6 +559,15 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		return err
 	}
 
+	if c.PeerTags != nil {
+		// the inferred tags are aggregated on, along with any extra aggregators
+		for _, agg := range []string{"peer.service", "peer.hostname"} {
+			if !containsString(c.ExtraAggregators, agg) {
+				c.ExtraAggregators = append(c.ExtraAggregators, agg)
+			}
+		}
+	}
+
 	if strings.ToLower(c.LogLevel) == "debug" && !config.Datadog.IsSet("apm_config.log_throttling") {
 		// if we are in "debug mode" and log throttling behavior was not
 		// set by the user, disable it
@@ -368,6 +646,107 @@ func compileReplaceRules(rules []*ReplaceRule) error {
 	return nil
 }
 
+// parseTagRules parses a list of "key" or "key:value" tag rules. When regex is
+// true, the values are compiled as regular expressions, otherwise the "*" in the
+// values match any sequence of characters. If it fails it returns the first error.
+func parseTagRules(tags []string, regex bool) ([]*TagRule, error) {
+	rules := make([]*TagRule, 0, len(tags))
+	for _, tag := range tags {
+		parts := strings.SplitN(tag, ":", 2)
+		r := &TagRule{Key: strings.TrimSpace(parts[0])}
+		if r.Key == "" {
+			return nil, fmt.Errorf("tag %q has no key", tag)
+		}
+		if len(parts) == 2 {
+			r.Value = strings.TrimSpace(parts[1])
+		}
+		if regex && r.Value != "" {
+			re, err := regexp.Compile(r.Value)
+			if err != nil {
+				return nil, fmt.Errorf("tag %q: %s", tag, err)
+			}
+			r.Value, r.Re = "", re
+		} else if strings.Contains(r.Value, "*") {
+			r.Re = globToRegexp(r.Value)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// globToRegexp returns the regular expression matching the whole strings matched
+// by the given pattern, in which "*" matches any sequence of characters.
+func globToRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// compileSamplingRules validates the sampling rules and compiles the regular
+// expressions found in them. If it fails it returns the first error.
+func compileSamplingRules(rules []*SamplingRule) error {
+	for i, r := range rules {
+		if r == nil {
+			return fmt.Errorf("rule #%d is empty", i)
+		}
+		if r.Rate == nil || *r.Rate < 0 || *r.Rate > 1 {
+			return fmt.Errorf(`rule #%d: "rate" must be set between 0 and 1`, i)
+		}
+		if r.MaxTPS < 0 {
+			return fmt.Errorf(`rule #%d: "max_tps" must be positive`, i)
+		}
+		if r.Resource != "" {
+			re, err := regexp.Compile(r.Resource)
+			if err != nil {
+				return fmt.Errorf("rule #%d: %s", i, err)
+			}
+			r.ResourceRe = re
+		}
+		if len(r.Tags) == 0 {
+			continue
+		}
+		r.TagsRe = make(map[string]*regexp.Regexp, len(r.Tags))
+		for k, v := range r.Tags {
+			re, err := regexp.Compile(v)
+			if err != nil {
+				return fmt.Errorf("rule #%d: tag %q: %s", i, k, err)
+			}
+			r.TagsRe[k] = re
+		}
+	}
+	return nil
+}
+
+// compileTailSamplingConfig validates the tail sampling configuration and compiles
+// the regular expressions found in its rules. If it fails it returns the first error.
+func compileTailSamplingConfig(ts *TailSamplingConfig) error {
+	if ts.WindowSeconds < 0 {
+		return errors.New(`"window_seconds" must be positive`)
+	}
+	if ts.MaxTraces < 0 || ts.MaxMemory < 0 {
+		return errors.New(`"max_traces" and "max_memory" must be positive`)
+	}
+	for i, r := range ts.Rules {
+		if r == nil {
+			return fmt.Errorf("rule #%d is empty", i)
+		}
+		if r.KeepRate != nil && (*r.KeepRate < 0 || *r.KeepRate > 1) {
+			return fmt.Errorf("rule %q: \"keep_rate\" must be between 0 and 1", r.Name)
+		}
+		if r.Resource == "" {
+			continue
+		}
+		re, err := regexp.Compile(r.Resource)
+		if err != nil {
+			return fmt.Errorf("rule %q: %s", r.Name, err)
+		}
+		r.ResourceRe = re
+	}
+	return nil
+}
+
 // getDuration returns the duration of the provided value in seconds
 func getDuration(seconds int) time.Duration {
 	return time.Duration(seconds) * time.Second
@@ -389,3 +768,13 @@ func splitString(s string, sep rune) ([]string, error) {
 
 	return r.Read()
 }
+
+// containsString returns true if ss contains s.
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
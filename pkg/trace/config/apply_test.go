@@ -27,3 +27,89 @@ func TestParseRepaceRules(t *testing.T) {
 		assert.Equal(r.Pattern, r.Re.String())
 	}
 }
+
+// TestParseTagRules tests the parseTagRules helper function.
+func TestParseTagRules(t *testing.T) {
+	assert := assert.New(t)
+
+	rules, err := parseTagRules([]string{"env:prod", " db.type ", "http.url:http://host:8080/"}, false)
+	assert.NoError(err)
+	assert.Equal([]*TagRule{
+		{Key: "env", Value: "prod"},
+		{Key: "db.type"},
+		{Key: "http.url", Value: "http://host:8080/"},
+	}, rules)
+	assert.Equal("http.url:http://host:8080/", rules[2].String())
+
+	rules, err = parseTagRules([]string{"http.url:.*healthz.*", "env"}, true)
+	assert.NoError(err)
+	assert.Equal(".*healthz.*", rules[0].Re.String())
+	assert.Empty(rules[0].Value)
+	assert.Nil(rules[1].Re)
+	assert.Equal("http.url:.*healthz.*", rules[0].String())
+
+	// the "*" match any sequence of characters
+	rules, err = parseTagRules([]string{"http.url:*healthz*", "env:synth.*"}, false)
+	assert.NoError(err)
+	assert.True(rules[0].Re.MatchString("http://host/healthz?full=1"))
+	assert.False(rules[0].Re.MatchString("http://host/users"))
+	assert.True(rules[1].Re.MatchString("synth.us"))
+	assert.False(rules[1].Re.MatchString("synthetics"))
+	assert.Equal("http.url:*healthz*", rules[0].String())
+
+	_, err = parseTagRules([]string{":prod"}, false)
+	assert.Error(err)
+	_, err = parseTagRules([]string{"http.url:("}, true)
+	assert.Error(err)
+}
+
+// TestCompileTailSamplingConfig tests the compileTailSamplingConfig helper function.
+func TestCompileTailSamplingConfig(t *testing.T) {
+	assert := assert.New(t)
+	rate := func(r float64) *float64 { return &r }
+
+	ts := &TailSamplingConfig{Rules: []*TailSamplingRule{
+		{Name: "slow", MinDurationMs: 100},
+		{Name: "checkout", Resource: "POST /checkout.*", KeepRate: rate(0.5)},
+	}}
+	assert.NoError(compileTailSamplingConfig(ts))
+	assert.Nil(ts.Rules[0].ResourceRe)
+	assert.Equal("POST /checkout.*", ts.Rules[1].ResourceRe.String())
+
+	for name, ts := range map[string]*TailSamplingConfig{
+		"window":    {WindowSeconds: -1},
+		"traces":    {MaxTraces: -1},
+		"empty":     {Rules: []*TailSamplingRule{nil}},
+		"keep_rate": {Rules: []*TailSamplingRule{{KeepRate: rate(1.5)}}},
+		"resource":  {Rules: []*TailSamplingRule{{Resource: "("}}},
+	} {
+		assert.Error(compileTailSamplingConfig(ts), name)
+	}
+}
+
+// TestCompileSamplingRules tests the compileSamplingRules helper function.
+func TestCompileSamplingRules(t *testing.T) {
+	assert := assert.New(t)
+	rate := func(r float64) *float64 { return &r }
+
+	rules := []*SamplingRule{
+		{Service: "checkout", Tags: map[string]string{"http.status_code": "^5"}, Rate: rate(1)},
+		{Resource: "GET /health", Rate: rate(0.01), MaxTPS: 10},
+	}
+	assert.NoError(compileSamplingRules(rules))
+	assert.Nil(rules[0].ResourceRe)
+	assert.True(rules[0].TagsRe["http.status_code"].MatchString("500"))
+	assert.False(rules[0].TagsRe["http.status_code"].MatchString("200"))
+	assert.Equal("GET /health", rules[1].ResourceRe.String())
+
+	for name, r := range map[string]*SamplingRule{
+		"empty":    nil,
+		"no rate":  {Service: "checkout"},
+		"rate":     {Rate: rate(2)},
+		"max_tps":  {Rate: rate(1), MaxTPS: -1},
+		"resource": {Rate: rate(1), Resource: "("},
+		"tags":     {Rate: rate(1), Tags: map[string]string{"env": "("}},
+	} {
+		assert.Error(compileSamplingRules([]*SamplingRule{r}), name)
+	}
+}
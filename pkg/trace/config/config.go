@@ -64,12 +64,25 @@ type AgentConfig struct {
 	// Concentrator
 	BucketInterval   time.Duration // the size of our pre-aggregation per bucket
 	ExtraAggregators []string
+	StatsSketches    bool // record the durations in DDSketches rather than in GK summaries
 
 	// Sampler configuration
 	ExtraSampleRate float64
 	MaxTPS          float64
 	MaxEPS          float64
 
+	// SamplingRules holds the user-defined sampling rules, evaluated in
+	// order before the samplers.
+	SamplingRules []*SamplingRule
+
+	// TailSampling holds the configuration of the buffer holding the spans
+	// of incomplete traces, it is disabled when nil.
+	TailSampling *TailSamplingConfig
+
+	// DebugTraces holds the configuration of the buffer recording the last
+	// traces received for debugging, it is disabled when nil.
+	DebugTraces *DebugTracesConfig
+
 	// Receiver
 	ReceiverHost    string
 	ReceiverPort    int
@@ -81,6 +94,18 @@ type AgentConfig struct {
 	StatsWriter *WriterConfig
 	TraceWriter *WriterConfig
 
+	// DiskSpool holds the configuration of the spools keeping on disk the payloads
+	// which could not be sent. It is nil unless enabled.
+	DiskSpool *DiskSpoolConfig
+
+	// PeerTags holds the configuration of the inference of the peer tags on the
+	// client spans. It is nil unless enabled.
+	PeerTags *PeerTagsConfig
+
+	// TraceMetrics holds the configuration of the exporter sending the stats as
+	// DogStatsD metrics. It is nil unless enabled.
+	TraceMetrics *TraceMetricsConfig
+
 	// internal telemetry
 	StatsdHost string
 	StatsdPort int
@@ -106,6 +131,12 @@ type AgentConfig struct {
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
 
+	// RequireTags and RejectTags filter traces by the tags of their root span:
+	// a trace is dropped unless its root span matches all the required tags,
+	// or when it matches any of the rejected tags.
+	RequireTags []*TagRule
+	RejectTags  []*TagRule
+
 	// transaction analytics
 	AnalyzedRateByServiceLegacy map[string]float64
 	AnalyzedSpansByService      map[string]map[string]float64
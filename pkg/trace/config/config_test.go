@@ -162,6 +162,7 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.EqualValues(123.4, c.MaxMemory)
 	assert.Equal("0.0.0.0", c.ReceiverHost)
 	assert.True(c.LogThrottling)
+	assert.True(c.StatsSketches)
 
 	noProxy := true
 	if _, ok := os.LookupEnv("NO_PROXY"); ok {
@@ -200,6 +201,11 @@ func TestFullYamlConfig(t *testing.T) {
 	}, c.ReplaceTags)
 
 	assert.EqualValues([]string{"/health", "/500"}, c.Ignore["resource"])
+	assert.Equal([]*TagRule{{Key: "env", Value: "prod"}, {Key: "db.type"}}, c.RequireTags)
+	assert.Equal([]*TagRule{
+		{Key: "outcome", Value: "success"},
+		{Key: "http.url", Re: regexp.MustCompile(".*healthz.*")},
+	}, c.RejectTags)
 
 	o := c.Obfuscation
 	assert.NotNil(o)
@@ -212,6 +218,42 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.True(o.RemoveStackTraces)
 	assert.True(c.Obfuscation.Redis.Enabled)
 	assert.True(c.Obfuscation.Memcached.Enabled)
+	assert.True(c.Obfuscation.GraphQL.Enabled)
+	assert.True(c.Obfuscation.MessageQueue.Enabled)
+	assert.True(c.Obfuscation.SQLDialects.Enabled)
+	assert.True(c.Obfuscation.CreditCards.Enabled)
+
+	rules := c.SamplingRules
+	assert.Len(rules, 2)
+	assert.Equal("checkout", rules[0].Service)
+	assert.Equal(map[string]string{"http.status_code": "^5"}, rules[0].Tags)
+	assert.Equal(regexp.MustCompile("^5"), rules[0].TagsRe["http.status_code"])
+	assert.Equal(1.0, *rules[0].Rate)
+	assert.Equal(regexp.MustCompile("GET /health.*"), rules[1].ResourceRe)
+	assert.Equal(0.01, *rules[1].Rate)
+	assert.Equal(5.0, rules[1].MaxTPS)
+
+	assert.Equal(&DebugTracesConfig{Enabled: true, MaxTraces: 20, MaxBytes: 10 * 1024 * 1024}, c.DebugTraces)
+	assert.Equal(&DiskSpoolConfig{Enabled: true, Path: "/var/spool/datadog-apm", MaxSize: 500 * 1024 * 1024, MaxAgeSeconds: 600}, c.DiskSpool)
+	assert.Equal(&PeerTagsConfig{Enabled: true, ExtraServiceKeys: []string{"rpc.service"}}, c.PeerTags)
+	assert.Equal([]string{"http.status_code", "peer.service", "peer.hostname"}, c.ExtraAggregators)
+	assert.Equal(&TraceMetricsConfig{Enabled: true, Tags: []string{"env", "service"}, MaxResourcesPerService: 100}, c.TraceMetrics)
+
+	ts := c.TailSampling
+	assert.NotNil(ts)
+	assert.True(ts.Enabled)
+	assert.Equal(5.0, ts.WindowSeconds)
+	assert.Equal(500, ts.MaxTraces)
+	assert.Equal(1e8, ts.MaxMemory)
+	assert.Len(ts.Rules, 2)
+	assert.Equal("slow-checkouts", ts.Rules[0].Name)
+	assert.Equal("web", ts.Rules[0].Service)
+	assert.Equal(regexp.MustCompile("POST /checkout.*"), ts.Rules[0].ResourceRe)
+	assert.Equal(500.0, ts.Rules[0].MinDurationMs)
+	assert.Equal(map[string]string{"http.status_code": "200"}, ts.Rules[0].Tags)
+	assert.Nil(ts.Rules[0].KeepRate)
+	assert.True(ts.Rules[1].Error)
+	assert.Equal(0.5, *ts.Rules[1].KeepRate)
 }
 
 func TestUndocumentedYamlConfig(t *testing.T) {
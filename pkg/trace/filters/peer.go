@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package filters
+
+import (
+	"net/url"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+const (
+	// tagPeerService is the tag holding the service a client span talks to.
+	tagPeerService = "peer.service"
+	// tagPeerHostname is the tag holding the host a client span talks to.
+	tagPeerHostname = "peer.hostname"
+)
+
+// clientSpanTypes holds the span types which are considered as client spans when
+// the span kind is not set.
+var clientSpanTypes = map[string]bool{
+	"db":            true,
+	"sql":           true,
+	"cache":         true,
+	"redis":         true,
+	"memcached":     true,
+	"mongodb":       true,
+	"cassandra":     true,
+	"elasticsearch": true,
+	"http":          true,
+	"rpc":           true,
+	"grpc":          true,
+}
+
+// PeerTagger infers the "peer.service" and "peer.hostname" tags of the client spans
+// from the meta set by the integrations, such as "db.instance" or "out.host", so that
+// the downstream dependencies of a service show up in its stats. It never overwrites
+// existing tags and keeps all spans.
+type PeerTagger struct {
+	serviceKeys  []string
+	hostnameKeys []string
+}
+
+// NewPeerTagger returns a new PeerTagger looking up the given extra keys after the
+// default ones.
+func NewPeerTagger(extraServiceKeys, extraHostnameKeys []string) *PeerTagger {
+	return &PeerTagger{
+		serviceKeys:  append([]string{tagPeerService, "db.instance"}, extraServiceKeys...),
+		hostnameKeys: append([]string{tagPeerHostname, "out.host", "http.url"}, extraHostnameKeys...),
+	}
+}
+
+// Tag sets the peer tags on the client spans of the trace.
+func (f *PeerTagger) Tag(trace pb.Trace) {
+	for _, s := range trace {
+		if s.Meta == nil || !isClientSpan(s) {
+			continue
+		}
+		if v := lookupPeerTag(s, f.serviceKeys); v != "" {
+			s.Meta[tagPeerService] = v
+		}
+		if v := lookupPeerTag(s, f.hostnameKeys); v != "" {
+			s.Meta[tagPeerHostname] = v
+		}
+	}
+}
+
+// lookupPeerTag returns the value of the first of keys found in the meta of s,
+// keeping only the host of URLs.
+func lookupPeerTag(s *pb.Span, keys []string) string {
+	for _, k := range keys {
+		v := s.Meta[k]
+		if v == "" {
+			continue
+		}
+		if k == "http.url" {
+			u, err := url.Parse(v)
+			if err != nil || u.Hostname() == "" {
+				continue
+			}
+			v = u.Hostname()
+		}
+		return v
+	}
+	return ""
+}
+
+// isClientSpan returns true if s describes a call made to another service, based on
+// its kind or, when unset, on its type.
+func isClientSpan(s *pb.Span) bool {
+	switch s.Meta["span.kind"] {
+	case "client", "producer":
+		return true
+	case "":
+		return clientSpanTypes[s.Type]
+	default:
+		return false
+	}
+}
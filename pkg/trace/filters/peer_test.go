@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerTagger(t *testing.T) {
+	for name, tt := range map[string]struct {
+		typ      string
+		meta     map[string]string
+		expected map[string]string
+	}{
+		"db": {
+			typ:      "sql",
+			meta:     map[string]string{"db.instance": "users", "out.host": "db.example.com"},
+			expected: map[string]string{"peer.service": "users", "peer.hostname": "db.example.com"},
+		},
+		"http-url": {
+			typ:      "http",
+			meta:     map[string]string{"http.url": "https://api.example.com:8443/users?id=1"},
+			expected: map[string]string{"peer.hostname": "api.example.com"},
+		},
+		"bad-url": {
+			typ:  "http",
+			meta: map[string]string{"http.url": "/users"},
+		},
+		"existing": {
+			typ:      "redis",
+			meta:     map[string]string{"peer.service": "sessions", "peer.hostname": "cache", "out.host": "10.0.0.1"},
+			expected: map[string]string{"peer.service": "sessions", "peer.hostname": "cache"},
+		},
+		"extra-keys": {
+			typ:      "rpc",
+			meta:     map[string]string{"rpc.service": "billing", "network.destination.name": "billing.internal"},
+			expected: map[string]string{"peer.service": "billing", "peer.hostname": "billing.internal"},
+		},
+		"client-kind": {
+			typ:      "custom",
+			meta:     map[string]string{"span.kind": "client", "out.host": "queue.internal"},
+			expected: map[string]string{"peer.hostname": "queue.internal"},
+		},
+		"server-kind": {
+			typ:  "http",
+			meta: map[string]string{"span.kind": "server", "http.url": "http://example.com/"},
+		},
+		"not-client": {
+			typ:  "web",
+			meta: map[string]string{"out.host": "example.com"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			span := &pb.Span{Type: tt.typ, Meta: map[string]string{}}
+			for k, v := range tt.meta {
+				span.Meta[k] = v
+			}
+			NewPeerTagger([]string{"rpc.service"}, []string{"network.destination.name"}).Tag(pb.Trace{span})
+			assert.Equal(t, tt.expected["peer.service"], span.Meta["peer.service"])
+			assert.Equal(t, tt.expected["peer.hostname"], span.Meta["peer.hostname"])
+		})
+	}
+
+	t.Run("nil-meta", func(t *testing.T) {
+		span := &pb.Span{Type: "sql"}
+		NewPeerTagger(nil, nil).Tag(pb.Trace{span})
+		assert.Nil(t, span.Meta)
+	})
+}
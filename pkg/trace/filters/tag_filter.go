@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package filters
+
+import (
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// TagFilter drops traces based on the tags of their root span.
+type TagFilter struct {
+	require []*config.TagRule
+	reject  []*config.TagRule
+}
+
+// NewTagFilter returns a new TagFilter keeping the traces whose root span matches
+// all the required rules and none of the rejected ones.
+func NewTagFilter(require, reject []*config.TagRule) *TagFilter {
+	return &TagFilter{require: require, reject: reject}
+}
+
+// Required returns true if the span matches all the required tags.
+func (f *TagFilter) Required(span *pb.Span) bool {
+	for _, r := range f.require {
+		if !matchTag(r, span) {
+			return false
+		}
+	}
+	return true
+}
+
+// Rejected returns true if the span matches any of the rejected tags.
+func (f *TagFilter) Rejected(span *pb.Span) bool {
+	for _, r := range f.reject {
+		if matchTag(r, span) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTag returns true if the span has the tag of the rule, looking up
+// the metrics when it is not found in the meta.
+func matchTag(r *config.TagRule, span *pb.Span) bool {
+	v, ok := span.Meta[r.Key]
+	if !ok {
+		m, ok := span.Metrics[r.Key]
+		if !ok {
+			return false
+		}
+		v = strconv.FormatFloat(m, 'f', -1, 64)
+	}
+	switch {
+	case r.Re != nil:
+		return r.Re.MatchString(v)
+	case r.Value != "":
+		return v == r.Value
+	}
+	return true
+}
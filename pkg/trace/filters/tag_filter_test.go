@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package filters
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagFilter(t *testing.T) {
+	span := &pb.Span{
+		Meta:    map[string]string{"env": "prod", "http.url": "http://host/healthz?full=1"},
+		Metrics: map[string]float64{"http.status_code": 200},
+	}
+	tests := []struct {
+		rule     *config.TagRule
+		expected bool
+	}{
+		{&config.TagRule{Key: "env"}, true},
+		{&config.TagRule{Key: "env", Value: "prod"}, true},
+		{&config.TagRule{Key: "env", Value: "staging"}, false},
+		{&config.TagRule{Key: "version"}, false},
+		{&config.TagRule{Key: "http.status_code", Value: "200"}, true},
+		{&config.TagRule{Key: "http.url", Re: regexp.MustCompile(".*healthz.*")}, true},
+		{&config.TagRule{Key: "http.url", Re: regexp.MustCompile("^/healthz")}, false},
+		{&config.TagRule{Key: "http.status_code", Re: regexp.MustCompile("^5")}, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, NewTagFilter([]*config.TagRule{test.rule}, nil).Required(span), test.rule.String())
+		assert.Equal(t, test.expected, NewTagFilter(nil, []*config.TagRule{test.rule}).Rejected(span), test.rule.String())
+	}
+}
+
+func TestTagFilterRules(t *testing.T) {
+	assert := assert.New(t)
+	span := &pb.Span{Meta: map[string]string{"env": "prod"}}
+	prod := &config.TagRule{Key: "env", Value: "prod"}
+	version := &config.TagRule{Key: "version"}
+
+	f := NewTagFilter(nil, nil)
+	assert.True(f.Required(span))
+	assert.False(f.Rejected(span))
+
+	f = NewTagFilter([]*config.TagRule{prod, version}, nil)
+	assert.False(f.Required(span))
+
+	f = NewTagFilter(nil, []*config.TagRule{version, prod})
+	assert.True(f.Rejected(span))
+}
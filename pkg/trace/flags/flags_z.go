@@ -5,7 +5,11 @@
 
 package flags
 
-import "flag"
+import (
+	"flag"
+	"fmt"
+	"os"
+)
 
 var (
 	// ConfigPath specifies the path to the configuration file.
@@ -51,4 +55,11 @@ func init() {
 	flag.StringVar(&MemProfile, "memprofile", "", "Write memory profile to `file`")
 
 	registerOSSpecificFlags()
+
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage: %s [flags]\n", os.Args[0])
+		fmt.Fprintf(out, "       %s debug traces\tShow the last traces received by the running trace agent and exit\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 }
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
 )
 
@@ -38,6 +39,8 @@ var (
 	samplerInfo         SamplerInfo
 	prioritySamplerInfo SamplerInfo
 	errorsSamplerInfo   SamplerInfo
+	samplingRulesInfo   []sampler.RuleState
+	tailBufferInfo      TailBufferInfo
 	rateByService       map[string]float64
 	rateLimiterStats    RateLimiterStats
 	start               = time.Now()
@@ -69,6 +72,9 @@ const (
   From {{if $ts.Tags.Lang}}{{ $ts.Tags.Lang }} {{ $ts.Tags.LangVersion }} ({{ $ts.Tags.Interpreter }}), client {{ $ts.Tags.TracerVersion }}{{else}}unknown clients{{end}}
     Traces received: {{ $ts.Stats.TracesReceived }} ({{ $ts.Stats.TracesBytes }} bytes)
     Spans received: {{ $ts.Stats.SpansReceived }}
+    {{ if or $ts.Stats.TracesFilteredRequireTags $ts.Stats.TracesFilteredRejectTags }}
+    Traces filtered by tags: {{ $ts.Stats.TracesFilteredRequireTags }} missing a required tag, {{ $ts.Stats.TracesFilteredRejectTags }} with a rejected tag
+    {{end}}
     {{ with $ts.WarnString }}
     WARNING: {{ . }}
     {{end}}
@@ -80,13 +86,35 @@ const (
   {{if lt .Status.RateLimiter.TargetRate 1.0}}
   WARNING: Rate-limiter keep percentage: {{percent .Status.RateLimiter.TargetRate}} %
   {{end}}
+  {{if .Status.SamplingRules}}
+
+  --- Sampling rules ---
+
+  {{ range $i, $r := .Status.SamplingRules }}
+  Rule {{ $r.Rule }}: {{percent $r.Rate}} %{{if gt $r.MaxTPS 0.0}}, max {{ $r.MaxTPS }} traces/s{{end}}
+    Traces matched: {{ $r.Matched }}, kept: {{ $r.Kept }}{{if gt $r.Limited 0}}, limited: {{ $r.Limited }}{{end}}
+  {{end}}
+  {{end}}
 
   --- Writer stats (1 min) ---
 
   Traces: {{.Status.TraceWriter.Payloads}} payloads, {{.Status.TraceWriter.Traces}} traces, {{if gt .Status.TraceWriter.Events 0}}{{.Status.TraceWriter.Events}} events, {{end}}{{.Status.TraceWriter.Bytes}} bytes
   {{if gt .Status.TraceWriter.Errors 0}}WARNING: Traces API errors (1 min): {{.Status.TraceWriter.Errors}}{{end}}
+  {{with .Status.TraceWriter.Spool}}Traces spool: {{.Payloads}} payloads, {{.Bytes}} bytes, oldest {{printf "%.0f" .OldestSeconds}} seconds ago{{end}}
+  {{with .Status.TraceWriter.Spool}}{{if gt .Dropped 0}}WARNING: Traces payloads dropped from the spool: {{.Dropped}}{{end}}{{end}}
   Stats: {{.Status.StatsWriter.Payloads}} payloads, {{.Status.StatsWriter.StatsBuckets}} stats buckets, {{.Status.StatsWriter.Bytes}} bytes
   {{if gt .Status.StatsWriter.Errors 0}}WARNING: Stats API errors (1 min): {{.Status.StatsWriter.Errors}}{{end}}
+  {{with .Status.StatsWriter.Spool}}Stats spool: {{.Payloads}} payloads, {{.Bytes}} bytes, oldest {{printf "%.0f" .OldestSeconds}} seconds ago{{end}}
+  {{with .Status.StatsWriter.Spool}}{{if gt .Dropped 0}}WARNING: Stats payloads dropped from the spool: {{.Dropped}}{{end}}{{end}}
+  {{with .Status.Config.TailSampling}}{{if .Enabled}}
+
+  --- Tail sampling buffer ---
+
+  Buffered: {{$.Status.TailBuffer.Traces}} traces, {{$.Status.TailBuffer.Spans}} spans, {{$.Status.TailBuffer.Bytes}} bytes
+  Released: {{$.Status.TailBuffer.Completed}} completed, {{$.Status.TailBuffer.Expired}} expired traces
+  Rules: {{$.Status.TailBuffer.RuleKept}} traces kept, {{$.Status.TailBuffer.RuleDropped}} traces dropped
+  {{if gt $.Status.TailBuffer.Evicted 0}}WARNING: Traces evicted to enforce the memory limits: {{$.Status.TailBuffer.Evicted}}{{end}}
+  {{end}}{{end}}
 `
 
 	notRunningTmplSrc = `{{.Banner}}
@@ -267,6 +295,8 @@ func InitInfo(conf *config.AgentConfig) error {
 		expvar.Publish("ratebyservice", expvar.Func(publishRateByService))
 		expvar.Publish("watchdog", expvar.Func(publishWatchdogInfo))
 		expvar.Publish("ratelimiter", expvar.Func(publishRateLimiterStats))
+		expvar.Publish("sampling_rules", expvar.Func(publishSamplingRulesInfo))
+		expvar.Publish("tail_buffer", expvar.Func(publishTailBufferInfo))
 
 		// copy the config to ensure we don't expose sensitive data such as API keys
 		c := *conf
@@ -317,14 +347,16 @@ type StatusInfo struct {
 	MemStats struct {
 		Alloc uint64
 	} `json:"memstats"`
-	Version       infoVersion        `json:"version"`
-	Receiver      []TagStats         `json:"receiver"`
-	RateByService map[string]float64 `json:"ratebyservice"`
-	TraceWriter   TraceWriterInfo    `json:"trace_writer"`
-	StatsWriter   StatsWriterInfo    `json:"stats_writer"`
-	Watchdog      watchdog.Info      `json:"watchdog"`
-	RateLimiter   RateLimiterStats   `json:"ratelimiter"`
-	Config        config.AgentConfig `json:"config"`
+	Version       infoVersion         `json:"version"`
+	Receiver      []TagStats          `json:"receiver"`
+	RateByService map[string]float64  `json:"ratebyservice"`
+	TraceWriter   TraceWriterInfo     `json:"trace_writer"`
+	StatsWriter   StatsWriterInfo     `json:"stats_writer"`
+	Watchdog      watchdog.Info       `json:"watchdog"`
+	RateLimiter   RateLimiterStats    `json:"ratelimiter"`
+	SamplingRules []sampler.RuleState `json:"sampling_rules"`
+	TailBuffer    TailBufferInfo      `json:"tail_buffer"`
+	Config        config.AgentConfig  `json:"config"`
 }
 
 func getProgramBanner(version string) (string, string) {
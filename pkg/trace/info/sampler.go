@@ -22,3 +22,50 @@ type SamplerStats struct {
 	// TotalTPS is the total number of traces (average per second for last flush)
 	TotalTPS float64
 }
+
+// UpdateSamplingRulesInfo updates internal stats about the sampling rules.
+func UpdateSamplingRulesInfo(rs []sampler.RuleState) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	samplingRulesInfo = rs
+}
+
+func publishSamplingRulesInfo() interface{} {
+	infoMu.RLock()
+	defer infoMu.RUnlock()
+	return samplingRulesInfo
+}
+
+// TailBufferInfo represents statistics from the buffer holding the spans
+// of incomplete traces until they are sampled.
+type TailBufferInfo struct {
+	// Traces is the number of traces currently held in the buffer.
+	Traces int64
+	// Spans is the number of spans currently held in the buffer.
+	Spans int64
+	// Bytes is the estimated size of the spans currently held in the buffer.
+	Bytes int64
+	// Completed is the number of traces released when their root span was received.
+	Completed int64
+	// Expired is the number of traces released when their window elapsed.
+	Expired int64
+	// Evicted is the number of traces released early to enforce the memory limits.
+	Evicted int64
+	// RuleKept is the number of traces kept by a tail sampling rule.
+	RuleKept int64
+	// RuleDropped is the number of traces dropped by a tail sampling rule.
+	RuleDropped int64
+}
+
+// UpdateTailBufferInfo updates internal stats about the tail sampling buffer.
+func UpdateTailBufferInfo(tbi TailBufferInfo) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	tailBufferInfo = tbi
+}
+
+func publishTailBufferInfo() interface{} {
+	infoMu.RLock()
+	defer infoMu.RUnlock()
+	return tailBufferInfo
+}
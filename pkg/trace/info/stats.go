@@ -128,6 +128,8 @@ func (ts *TagStats) publish() {
 	// Atomically load the stats from ts
 	tracesReceived := atomic.LoadInt64(&ts.TracesReceived)
 	tracesFiltered := atomic.LoadInt64(&ts.TracesFiltered)
+	tracesFilteredRequireTags := atomic.LoadInt64(&ts.TracesFilteredRequireTags)
+	tracesFilteredRejectTags := atomic.LoadInt64(&ts.TracesFilteredRejectTags)
 	tracesPriorityNone := atomic.LoadInt64(&ts.TracesPriorityNone)
 	tracesPriorityNeg := atomic.LoadInt64(&ts.TracesPriorityNeg)
 	tracesPriority0 := atomic.LoadInt64(&ts.TracesPriority0)
@@ -148,6 +150,8 @@ func (ts *TagStats) publish() {
 	metrics.Count("datadog.trace_agent.receiver.trace", tracesReceived, tags, 1)
 	metrics.Count("datadog.trace_agent.receiver.traces_received", tracesReceived, tags, 1)
 	metrics.Count("datadog.trace_agent.receiver.traces_filtered", tracesFiltered, tags, 1)
+	metrics.Count("datadog.trace_agent.receiver.traces_filtered_by_tags", tracesFilteredRequireTags, append(tags, "reason:require"), 1)
+	metrics.Count("datadog.trace_agent.receiver.traces_filtered_by_tags", tracesFilteredRejectTags, append(tags, "reason:reject"), 1)
 	metrics.Count("datadog.trace_agent.receiver.traces_priority", tracesPriorityNone, append(tags, "priority:none"), 1)
 	metrics.Count("datadog.trace_agent.receiver.traces_priority", tracesPriorityNeg, append(tags, "priority:neg"), 1)
 	metrics.Count("datadog.trace_agent.receiver.traces_priority", tracesPriority0, append(tags, "priority:0"), 1)
@@ -283,6 +287,10 @@ type Stats struct {
 	SpansMalformed *SpansMalformed
 	// TracesFiltered is the number of traces filtered.
 	TracesFiltered int64
+	// TracesFilteredRequireTags is the number of traces filtered because their root span lacks a required tag.
+	TracesFilteredRequireTags int64
+	// TracesFilteredRejectTags is the number of traces filtered because their root span has a rejected tag.
+	TracesFilteredRejectTags int64
 	// TracesPriorityNone is the number of traces with no sampling priority.
 	TracesPriorityNone int64
 	// TracesPriorityNeg is the number of traces with a negative sampling priority.
@@ -333,6 +341,8 @@ func (s *Stats) update(recent *Stats) {
 	atomic.AddInt64(&s.SpansMalformed.InvalidHTTPStatusCode, atomic.LoadInt64(&recent.SpansMalformed.InvalidHTTPStatusCode))
 
 	atomic.AddInt64(&s.TracesFiltered, atomic.LoadInt64(&recent.TracesFiltered))
+	atomic.AddInt64(&s.TracesFilteredRequireTags, atomic.LoadInt64(&recent.TracesFilteredRequireTags))
+	atomic.AddInt64(&s.TracesFilteredRejectTags, atomic.LoadInt64(&recent.TracesFilteredRejectTags))
 	atomic.AddInt64(&s.TracesPriorityNone, atomic.LoadInt64(&recent.TracesPriorityNone))
 	atomic.AddInt64(&s.TracesPriorityNeg, atomic.LoadInt64(&recent.TracesPriorityNeg))
 	atomic.AddInt64(&s.TracesPriority0, atomic.LoadInt64(&recent.TracesPriority0))
@@ -368,6 +378,8 @@ func (s *Stats) reset() {
 	atomic.StoreInt64(&s.SpansMalformed.InvalidDuration, 0)
 	atomic.StoreInt64(&s.SpansMalformed.InvalidHTTPStatusCode, 0)
 	atomic.StoreInt64(&s.TracesFiltered, 0)
+	atomic.StoreInt64(&s.TracesFilteredRequireTags, 0)
+	atomic.StoreInt64(&s.TracesFilteredRejectTags, 0)
 	atomic.StoreInt64(&s.TracesPriorityNone, 0)
 	atomic.StoreInt64(&s.TracesPriorityNeg, 0)
 	atomic.StoreInt64(&s.TracesPriority0, 0)
@@ -429,13 +441,13 @@ func (ts *TagStats) WarnString() string {
 
 // Tags holds the tags we parse when we handle the header of the payload.
 type Tags struct {
-	Lang, LangVersion, LangVendor, Interpreter, TracerVersion string
+	Lang, LangVersion, LangVendor, Interpreter, TracerVersion, EndpointVersion string
 }
 
 // toArray will transform the Tags struct into a slice of string.
 // We only publish the non-empty tags.
 func (t *Tags) toArray() []string {
-	tags := make([]string, 0, 5)
+	tags := make([]string, 0, 6)
 
 	if t.Lang != "" {
 		tags = append(tags, "lang:"+t.Lang)
@@ -452,6 +464,9 @@ func (t *Tags) toArray() []string {
 	if t.TracerVersion != "" {
 		tags = append(tags, "tracer_version:"+t.TracerVersion)
 	}
+	if t.EndpointVersion != "" {
+		tags = append(tags, "endpoint_version:"+t.EndpointVersion)
+	}
 
 	return tags
 }
@@ -17,6 +17,7 @@ type TraceWriterInfo struct {
 	BytesUncompressed int64
 	BytesEstimated    int64
 	SingleMaxSize     int64
+	Spool             *SpoolInfo `json:",omitempty"`
 }
 
 // StatsWriterInfo represents statistics from the stats writer.
@@ -27,6 +28,23 @@ type StatsWriterInfo struct {
 	Retries      int64
 	Splits       int64
 	Bytes        int64
+	Spool        *SpoolInfo `json:",omitempty"`
+}
+
+// SpoolInfo represents statistics from the disk spools of a writer, which keep
+// the payloads that could not be sent until the endpoints recover.
+type SpoolInfo struct {
+	// Payloads is the number of payloads currently held on disk.
+	Payloads int64
+	// Bytes is the size of the payloads currently held on disk.
+	Bytes int64
+	// OldestSeconds is the age of the oldest payload held on disk.
+	OldestSeconds float64
+	// Spooled is the number of payloads written to disk.
+	Spooled int64
+	// Dropped is the number of payloads dropped from disk to enforce the size
+	// and age limits.
+	Dropped int64
 }
 
 // UpdateTraceWriterInfo updates internal trace writer stats
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// Credit card numbers have between 13 and 19 digits.
+const (
+	minCardDigits = 13
+	maxCardDigits = 19
+)
+
+// obfuscateCreditCards replaces the credit card numbers found in the tag values of
+// the span with "?". Internal tags, starting with an underscore, are left untouched.
+func (*Obfuscator) obfuscateCreditCards(span *pb.Span) {
+	for k, v := range span.Meta {
+		if strings.HasPrefix(k, "_") {
+			continue
+		}
+		if out, ok := maskCreditCards(v); ok {
+			span.Meta[k] = out
+		}
+	}
+}
+
+// maskCreditCards replaces the credit card numbers found in s with "?". It reports
+// whether any was found. A credit card number is a standalone sequence of digits
+// starting with 2 to 6, optionally grouped by single spaces or dashes, which passes
+// the Luhn check.
+func maskCreditCards(s string) (string, bool) {
+	var (
+		out   strings.Builder
+		last  int // end of the last masked number
+		found bool
+	)
+	for i := 0; i < len(s); {
+		if !isASCIIDigit(s[i]) || i > 0 && isASCIIAlnum(s[i-1]) {
+			i++
+			continue
+		}
+		end, digits := scanCardNumber(s, i)
+		if s[i] >= '2' && s[i] <= '6' &&
+			digits >= minCardDigits && digits <= maxCardDigits &&
+			(end == len(s) || !isASCIIAlnum(s[end])) &&
+			luhnValid(s[i:end]) {
+			out.WriteString(s[last:i])
+			out.WriteByte('?')
+			last = end
+			found = true
+		}
+		i = end
+	}
+	if !found {
+		return s, false
+	}
+	out.WriteString(s[last:])
+	return out.String(), true
+}
+
+// scanCardNumber returns the position following the sequence of digits, grouped by
+// single spaces or dashes, starting at position i of s, and the number of its digits.
+func scanCardNumber(s string, i int) (end, digits int) {
+	for end = i; end < len(s); end++ {
+		switch {
+		case isASCIIDigit(s[end]):
+			digits++
+		case (s[end] == ' ' || s[end] == '-') && end+1 < len(s) && isASCIIDigit(s[end+1]):
+		default:
+			return end, digits
+		}
+	}
+	return end, digits
+}
+
+// luhnValid reports whether the digits of number pass the Luhn checksum.
+func luhnValid(number string) bool {
+	var sum int
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		if !isASCIIDigit(number[i]) {
+			continue
+		}
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func isASCIIAlnum(c byte) bool {
+	return isASCIIDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
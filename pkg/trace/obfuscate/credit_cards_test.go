@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskCreditCards(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{"4111111111111111", "?"},
+		{"card=4111 1111 1111 1111&cvc=123", "card=?&cvc=123"},
+		{"5500-0000-0000-0004 and 378282246310005", "? and ?"},
+		{"6011111111111117.", "?."},
+		// Luhn check fails
+		{"4111111111111112", "4111111111111112"},
+		// too short or too long
+		{"411111111111", "411111111111"},
+		{"41111111111111111111", "41111111111111111111"},
+		// not starting with 2 to 6
+		{"8111111111111119", "8111111111111119"},
+		// not standalone
+		{"id4111111111111111", "id4111111111111111"},
+		{"4111111111111111abc", "4111111111111111abc"},
+		// double separators
+		{"4111  1111 1111 1111", "4111  1111 1111 1111"},
+		{"no digits", "no digits"},
+	} {
+		out, ok := maskCreditCards(tt.in)
+		assert.Equal(t, tt.out, out, tt.in)
+		assert.Equal(t, tt.in != tt.out, ok, tt.in)
+	}
+}
+
+func TestObfuscateCreditCards(t *testing.T) {
+	assert := assert.New(t)
+	span := &pb.Span{
+		Type: "web",
+		Meta: map[string]string{
+			"payment.card": "4111-1111-1111-1111",
+			"_dd.hostname": "4111111111111111",
+		},
+	}
+	NewObfuscator(&Config{}).Obfuscate(span)
+	assert.Equal("4111-1111-1111-1111", span.Meta["payment.card"])
+
+	NewObfuscator(&Config{CreditCards: true}).Obfuscate(span)
+	assert.Equal("?", span.Meta["payment.card"])
+	assert.Equal("4111111111111111", span.Meta["_dd.hostname"])
+}
@@ -0,0 +1,175 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	graphQLQueryTag        = "graphql.query"
+	graphQLSourceTag       = "graphql.source"
+	graphQLVariablesPrefix = "graphql.variables."
+	nonParsableGraphQL     = "Non-parsable GraphQL query"
+)
+
+// obfuscateGraphQL obfuscates the queries of a GraphQL span, keeping their shape
+// but removing all literals, and the values of the variables of the query.
+func (o *Obfuscator) obfuscateGraphQL(span *pb.Span) {
+	for k, v := range span.Meta {
+		switch {
+		case k == graphQLQueryTag || k == graphQLSourceTag:
+			tags := []string{"type:graphql"}
+			out, err := obfuscateGraphQLString(v)
+			if err != nil {
+				// we have an error, discard the query to avoid leaking its literals.
+				log.Debugf("Error parsing GraphQL query: %v. Query: %q", err, v)
+				out = nonParsableGraphQL
+				tags = append(tags, "outcome:error")
+			} else {
+				tags = append(tags, "outcome:success")
+			}
+			span.Meta[k] = out
+			metrics.Count("datadog.trace_agent.obfuscations", 1, tags, 1)
+		case strings.HasPrefix(k, graphQLVariablesPrefix):
+			span.Meta[k] = "?"
+		}
+	}
+}
+
+// graphQLPunctuators holds the single-character punctuators of the GraphQL language.
+const graphQLPunctuators = "!$&():=@[]{|}"
+
+// obfuscateGraphQLString replaces the string, number, boolean and null literals of
+// a GraphQL document with "?", drops its comments and compacts its whitespaces.
+func obfuscateGraphQLString(in string) (string, error) {
+	var (
+		out  strings.Builder
+		last string // last token written
+	)
+	write := func(tok string) {
+		if out.Len() > 0 && graphQLNeedsSpace(last, tok) {
+			out.WriteByte(' ')
+		}
+		out.WriteString(tok)
+		last = tok
+	}
+	for i := 0; i < len(in); {
+		c := in[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#':
+			// comments run until the end of the line
+			for i < len(in) && in[i] != '\n' && in[i] != '\r' {
+				i++
+			}
+		case c == '"':
+			end, err := scanGraphQLString(in, i)
+			if err != nil {
+				return "", err
+			}
+			write("?")
+			i = end
+		case c == '-' || isASCIIDigit(c):
+			j := i + 1
+			for j < len(in) && (isASCIIDigit(in[j]) || strings.IndexByte(".eE+-", in[j]) >= 0) {
+				j++
+			}
+			if c == '-' && j == i+1 {
+				return "", fmt.Errorf("at position %d: expected digit after \"-\"", i)
+			}
+			write("?")
+			i = j
+		case isGraphQLNameStart(c):
+			j := i + 1
+			for j < len(in) && (isGraphQLNameStart(in[j]) || isASCIIDigit(in[j])) {
+				j++
+			}
+			name := in[i:j]
+			switch name {
+			case "true", "false", "null":
+				if last == ":" || last == "=" || last == "[" || last == "," || last == "?" {
+					// a value, not a field or argument name
+					name = "?"
+				}
+			}
+			write(name)
+			i = j
+		case c == '.':
+			if !strings.HasPrefix(in[i:], "...") {
+				return "", fmt.Errorf("at position %d: expected \"...\"", i)
+			}
+			write("...")
+			i += 3
+		case c == ',' || strings.IndexByte(graphQLPunctuators, c) >= 0:
+			write(in[i : i+1])
+			i++
+		default:
+			return "", fmt.Errorf("at position %d: unexpected character %q", i, c)
+		}
+	}
+	if out.Len() == 0 {
+		return "", errors.New("result is empty")
+	}
+	return out.String(), nil
+}
+
+// scanGraphQLString returns the position following the string or block string
+// starting at position i of in.
+func scanGraphQLString(in string, i int) (int, error) {
+	if strings.HasPrefix(in[i:], `"""`) {
+		for j := i + 3; j < len(in); j++ {
+			switch {
+			case strings.HasPrefix(in[j:], `\"""`):
+				j += 3
+			case strings.HasPrefix(in[j:], `"""`):
+				return j + 3, nil
+			}
+		}
+		return 0, fmt.Errorf("at position %d: unexpected EOF in block string", len(in))
+	}
+	for j := i + 1; j < len(in); j++ {
+		switch in[j] {
+		case '\\':
+			j++
+		case '\n', '\r':
+			return 0, fmt.Errorf("at position %d: unexpected line terminator in string", j)
+		case '"':
+			return j + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("at position %d: unexpected EOF in string", len(in))
+}
+
+// graphQLNeedsSpace reports whether a space should be written between the
+// tokens last and next.
+func graphQLNeedsSpace(last, next string) bool {
+	switch last {
+	case "(", "[", "$", "@":
+		return false
+	}
+	switch next {
+	case ")", "]", ":", "!", ",":
+		return false
+	case "(":
+		// arguments follow the name of a field or directive
+		return len(last) == 1 && strings.Contains(graphQLPunctuators, last)
+	}
+	return true
+}
+
+func isGraphQLNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isASCIIDigit(c byte) bool { return '0' <= c && c <= '9' }
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateGraphQLString(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			`{ user(id: 4) { name } }`,
+			`{ user(id: ?) { name } }`,
+		},
+		{
+			`query GetUser($id: ID!, $first: Int = 10) {
+				# the user and their friends
+				user(id: $id) {
+					name
+					friends(first: $first, after: "Y3Vyc29y") { name }
+				}
+			}`,
+			`query GetUser($id: ID!, $first: Int = ?) { user(id: $id) { name friends(first: $first, after: ?) { name } } }`,
+		},
+		{
+			`mutation { login(email: "jim@example.com", password: """se"cr\"""et""", remember: true, device: null) { token } }`,
+			`mutation { login(email: ?, password: ?, remember: ?, device: ?) { token } }`,
+		},
+		{
+			`{ search(ids: [1, -2.5e3, 3], filter: {active: false, role: ADMIN}) { ...Result @include(if: true) } }`,
+			`{ search(ids: [?, ?, ?], filter: { active: ?, role: ADMIN }) { ... Result @include(if: ?) } }`,
+		},
+		{
+			`{ true null }`,
+			`{ true null }`,
+		},
+	} {
+		out, err := obfuscateGraphQLString(tt.in)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.out, out)
+	}
+
+	for _, tt := range []struct {
+		in, err string
+	}{
+		{``, "result is empty"},
+		{`{ user(name: "jim) { id } }`, "at position 27: unexpected EOF in string"},
+		{"{ user(name: \"ji\nm\") }", "at position 16: unexpected line terminator in string"},
+		{`{ user(bio: """text) }`, "at position 22: unexpected EOF in block string"},
+		{`{ ..User }`, `at position 2: expected "..."`},
+		{`{ user(id: -) }`, `at position 11: expected digit after "-"`},
+		{`{ user % }`, `at position 7: unexpected character '%'`},
+	} {
+		_, err := obfuscateGraphQLString(tt.in)
+		assert.EqualError(t, err, tt.err)
+	}
+}
+
+func TestObfuscateGraphQL(t *testing.T) {
+	assert := assert.New(t)
+	span := &pb.Span{
+		Type:     "graphql",
+		Resource: "GetUser",
+		Meta: map[string]string{
+			"graphql.source":         `query GetUser { user(id: "42") { name } }`,
+			"graphql.variables.id":   "42",
+			"graphql.operation.name": "GetUser",
+		},
+	}
+	NewObfuscator(&Config{GraphQL: true}).Obfuscate(span)
+	assert.Equal("query GetUser { user(id: ?) { name } }", span.Meta["graphql.source"])
+	assert.Equal("?", span.Meta["graphql.variables.id"])
+	assert.Equal("GetUser", span.Meta["graphql.operation.name"])
+	assert.Equal("GetUser", span.Resource)
+
+	span = &pb.Span{Type: "graphql", Meta: map[string]string{"graphql.query": `{ user(id: "42 }`}}
+	NewObfuscator(&Config{GraphQL: true}).Obfuscate(span)
+	assert.Equal(nonParsableGraphQL, span.Meta["graphql.query"])
+}
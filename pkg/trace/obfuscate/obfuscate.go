@@ -46,6 +46,21 @@ type Config struct {
 	// Redis enables obfuscatiion of the "memcached.command" tag for spans of type "memcached".
 	Memcached bool
 
+	// GraphQL enables obfuscation of the "graphql.query" and "graphql.source" tags for spans
+	// of type "graphql", along with the "graphql.variables.*" tags.
+	GraphQL bool
+
+	// MessageQueue enables obfuscation of the message payload tags for spans of type "queue",
+	// "kafka" and "amqp".
+	MessageQueue bool
+
+	// SQLDialects enables the tokenizing of dialect-specific SQL: PostgreSQL dollar-quoted
+	// strings, MySQL backtick quoted identifiers and positional placeholders such as $1.
+	SQLDialects bool
+
+	// CreditCards enables masking of the credit card numbers found in the tags of all spans.
+	CreditCards bool
+
 	// sqlLiteralEscapes reports whether we should treat escape characters literally or as escape characters.
 	// A non-zero value means 'yes'. Different SQL engines behave in different ways and the tokenizer needs
 	// to be generic.
@@ -103,6 +118,17 @@ func (o *Obfuscator) Obfuscate(span *pb.Span) {
 		o.obfuscateJSON(span, "mongodb.query", o.mongo)
 	case "elasticsearch":
 		o.obfuscateJSON(span, "elasticsearch.body", o.es)
+	case "graphql":
+		if o.opts.GraphQL {
+			o.obfuscateGraphQL(span)
+		}
+	case "queue", "kafka", "amqp":
+		if o.opts.MessageQueue {
+			o.obfuscateMessageQueue(span)
+		}
+	}
+	if o.opts.CreditCards {
+		o.obfuscateCreditCards(span)
 	}
 }
 
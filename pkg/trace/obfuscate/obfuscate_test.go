@@ -220,6 +220,54 @@ func TestObfuscateConfig(t *testing.T) {
 		"set key 0 0 0 noreply\r\nvalue",
 		&Config{},
 	))
+
+	t.Run("graphql/enabled", testConfig(
+		"graphql",
+		"graphql.query",
+		`{ user(id: 42) { name } }`,
+		`{ user(id: ?) { name } }`,
+		&Config{GraphQL: true},
+	))
+
+	t.Run("graphql/disabled", testConfig(
+		"graphql",
+		"graphql.query",
+		`{ user(id: 42) { name } }`,
+		`{ user(id: 42) { name } }`,
+		&Config{},
+	))
+
+	t.Run("queue/enabled", testConfig(
+		"queue",
+		"kafka.message.payload",
+		`{"email": "jim@example.com"}`,
+		"?",
+		&Config{MessageQueue: true},
+	))
+
+	t.Run("queue/disabled", testConfig(
+		"queue",
+		"kafka.message.payload",
+		`{"email": "jim@example.com"}`,
+		`{"email": "jim@example.com"}`,
+		&Config{},
+	))
+
+	t.Run("amqp/enabled", testConfig(
+		"amqp",
+		"amqp.body",
+		"secret",
+		"?",
+		&Config{MessageQueue: true},
+	))
+
+	t.Run("amqp/other", testConfig(
+		"amqp",
+		"amqp.exchange",
+		"orders",
+		"orders",
+		&Config{MessageQueue: true},
+	))
 }
 
 func TestLiteralEscapes(t *testing.T) {
@@ -0,0 +1,28 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package obfuscate
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// obfuscateMessageQueue replaces the values of the tags holding the payload of the
+// messages sent or received by a message queue span (e.g. "kafka.message.payload"
+// or "amqp.body"), identified by their last "payload" or "body" segment.
+func (*Obfuscator) obfuscateMessageQueue(span *pb.Span) {
+	for k := range span.Meta {
+		name := k
+		if i := strings.LastIndexByte(k, '.'); i >= 0 {
+			name = k[i+1:]
+		}
+		switch name {
+		case "payload", "body":
+			span.Meta[k] = "?"
+		}
+	}
+}
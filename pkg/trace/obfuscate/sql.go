@@ -154,14 +154,14 @@ func (f *groupingFilter) Reset() {
 // The process calls all filters inside the []tokenFilter.
 func (o *Obfuscator) obfuscateSQLString(in string) (string, error) {
 	literalEscapes := o.SQLLiteralEscapes()
-	tokenizer := NewSQLTokenizer(in, literalEscapes)
+	tokenizer := o.newSQLTokenizer(in, literalEscapes)
 	filters := []tokenFilter{&discardFilter{}, &replaceFilter{}, &groupingFilter{}}
 
 	out, err := attemptObfuscation(tokenizer, filters)
 	if err != nil && tokenizer.SeenEscape() {
 		// If the tokenizer failed, but saw an escape character in the process,
 		// try again treating escapes differently
-		tokenizer = NewSQLTokenizer(in, !literalEscapes)
+		tokenizer = o.newSQLTokenizer(in, !literalEscapes)
 		if out, err2 := attemptObfuscation(tokenizer, filters); err2 == nil {
 			// If the second attempt succeeded, change the default behavior
 			o.SetSQLLiteralEscapes(!literalEscapes)
@@ -171,6 +171,13 @@ func (o *Obfuscator) obfuscateSQLString(in string) (string, error) {
 	return out, err
 }
 
+// newSQLTokenizer returns a SQLTokenizer for in, using the SQL dialects setting of the Obfuscator.
+func (o *Obfuscator) newSQLTokenizer(in string, literalEscapes bool) *SQLTokenizer {
+	tokenizer := NewSQLTokenizer(in, literalEscapes)
+	tokenizer.dialects = o.opts.SQLDialects
+	return tokenizer
+}
+
 func attemptObfuscation(tokenizer *SQLTokenizer, filters []tokenFilter) (string, error) {
 	var (
 		out       bytes.Buffer
@@ -843,6 +843,55 @@ func TestSQLErrors(t *testing.T) {
 	}
 }
 
+func TestSQLDialects(t *testing.T) {
+	o := NewObfuscator(&Config{SQLDialects: true})
+	for _, tc := range []sqlTestCase{
+		{
+			"SELECT * FROM users WHERE id = $1 AND name = $2",
+			"SELECT * FROM users WHERE id = $1 AND name = $2",
+		},
+		{
+			"SELECT $$it's a string$$, $tag$with $$ inside$tag$ FROM dual",
+			"SELECT ? FROM dual",
+		},
+		{
+			"INSERT INTO notes (body) VALUES ($body$ it's; $body$)",
+			"INSERT INTO notes ( body ) VALUES ( ? )",
+		},
+		{
+			"SELECT `1st column`, `order` FROM `my``table` WHERE `id` = 42",
+			"SELECT 1st column, order FROM my`table WHERE id = ?",
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			out, err := o.obfuscateSQLString(tc.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, out)
+		})
+	}
+
+	for _, tc := range []sqlTestCase{
+		{
+			"SELECT $tag$text$tag",
+			`at position 20: unexpected EOF in dollar-quoted string`,
+		},
+		{
+			"SELECT $tag text",
+			`at position 11: dollar-quoted string tags must end in "$", got " " (32)`,
+		},
+		{
+			"SELECT `name FROM profile",
+			"at position 25: literal identifiers must end in \"`\", got EOF",
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			_, err := o.obfuscateSQLString(tc.query)
+			assert.Error(t, err)
+			assert.Equal(t, tc.expected, err.Error())
+		})
+	}
+}
+
 func TestLiteralEscapesUpdates(t *testing.T) {
 	for _, c := range []struct {
 		initial bool
@@ -80,6 +80,7 @@ type SQLTokenizer struct {
 
 	literalEscapes bool // indicates we should not treat backslashes as escape characters
 	seenEscape     bool // indicates whether this tokenizer has seen an escape character within a string
+	dialects       bool // indicates we should recognize dialect-specific syntax such as dollar-quoted strings
 }
 
 // NewSQLTokenizer creates a new SQLTokenizer for the given SQL string.
@@ -203,6 +204,9 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 		case '"':
 			return tkn.scanString(ch, DoubleQuotedString)
 		case '`':
+			if tkn.dialects {
+				return tkn.scanQuotedIdentifier('`')
+			}
 			return tkn.scanLiteralIdentifier('`')
 		case '%':
 			if tkn.lastChar == '(' {
@@ -215,6 +219,9 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 			// modulo operator (e.g. 'id % 8')
 			return TokenKind(ch), runeBytes(ch)
 		case '$':
+			if tkn.dialects {
+				return tkn.scanDollar()
+			}
 			return tkn.scanPreparedStatement('$')
 		case '{':
 			return tkn.scanEscapeSequence('{')
@@ -266,6 +273,67 @@ func (tkn *SQLTokenizer) scanLiteralIdentifier(quote rune) (TokenKind, []byte) {
 	return ID, buffer.Bytes()
 }
 
+// scanQuotedIdentifier scans a MySQL identifier enclosed between quotes, which may
+// contain any character. A quote is embedded in the identifier by doubling it.
+func (tkn *SQLTokenizer) scanQuotedIdentifier(quote rune) (TokenKind, []byte) {
+	buffer := &bytes.Buffer{}
+	for {
+		ch := tkn.lastChar
+		if ch == EOFChar {
+			tkn.setErr(`literal identifiers must end in "%c", got EOF`, quote)
+			return LexError, buffer.Bytes()
+		}
+		tkn.next()
+		if ch == quote {
+			if tkn.lastChar != quote {
+				break
+			}
+			tkn.next()
+		}
+		buffer.WriteRune(ch)
+	}
+	if buffer.Len() == 0 {
+		tkn.setErr("empty literal identifier")
+		return LexError, nil
+	}
+	return ID, buffer.Bytes()
+}
+
+// scanDollar scans the tokens starting with a dollar sign in PostgreSQL: positional
+// placeholders (e.g. $1) and dollar-quoted strings (e.g. $$text$$ or $tag$text$tag$).
+func (tkn *SQLTokenizer) scanDollar() (TokenKind, []byte) {
+	if isDigit(tkn.lastChar) {
+		kind, buff := tkn.scanNumber(false)
+		if kind == LexError {
+			tkn.setErr("invalid number")
+			return LexError, buff
+		}
+		return ValueArg, append([]byte{'$'}, buff...)
+	}
+	delim := &bytes.Buffer{}
+	delim.WriteRune('$')
+	for isLeadingLetter(tkn.lastChar) || isDigit(tkn.lastChar) {
+		delim.WriteRune(tkn.lastChar)
+		tkn.next()
+	}
+	if tkn.lastChar != '$' {
+		tkn.setErr(`dollar-quoted string tags must end in "$", got "%c" (%d)`, tkn.lastChar, tkn.lastChar)
+		return LexError, delim.Bytes()
+	}
+	tkn.next()
+	delim.WriteRune('$')
+
+	buffer := &bytes.Buffer{}
+	for !bytes.HasSuffix(buffer.Bytes(), delim.Bytes()) {
+		if tkn.lastChar == EOFChar {
+			tkn.setErr("unexpected EOF in dollar-quoted string")
+			return LexError, buffer.Bytes()
+		}
+		tkn.consumeNext(buffer)
+	}
+	return String, buffer.Bytes()[:buffer.Len()-delim.Len()]
+}
+
 func (tkn *SQLTokenizer) scanVariableIdentifier(prefix rune) (TokenKind, []byte) {
 	buffer := &bytes.Buffer{}
 	buffer.WriteRune(prefix)
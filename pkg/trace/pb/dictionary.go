@@ -0,0 +1,275 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package pb
+
+import (
+	"fmt"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// The dictionary encoding of a list of traces is a msgpack array of 2 elements:
+// the dictionary, an array holding every string of the payload once, and an array
+// of traces, each of them an array of spans. Spans are arrays of 12 elements, in
+// this order:
+//
+//   service (uint32), name (uint32), resource (uint32), trace_id (uint64),
+//   span_id (uint64), parent_id (uint64), start (int64), duration (int64),
+//   error (int32), meta (map[uint32]uint32), metrics (map[uint32]float64),
+//   type (uint32)
+//
+// where every uint32 is the index of a string in the dictionary.
+const (
+	dictionaryPayloadLen = 2
+	dictionarySpanLen    = 12
+)
+
+// UnmarshalMsgDictionary decodes traces in the dictionary encoding from bts.
+func (t *Traces) UnmarshalMsgDictionary(bts []byte) error {
+	sz, bts, err := readArrayHeaderBytes(bts)
+	if err != nil {
+		return err
+	}
+	if sz != dictionaryPayloadLen {
+		return fmt.Errorf("expected payload of %d elements, got %d", dictionaryPayloadLen, sz)
+	}
+
+	sz, bts, err = readArrayHeaderBytes(bts)
+	if err != nil {
+		return err
+	}
+	dict := make([]string, sz)
+	for i := range dict {
+		if dict[i], bts, err = parseStringBytes(bts); err != nil {
+			return err
+		}
+	}
+
+	sz, bts, err = readArrayHeaderBytes(bts)
+	if err != nil {
+		return err
+	}
+	*t = make(Traces, sz)
+	for i := range *t {
+		if sz, bts, err = readArrayHeaderBytes(bts); err != nil {
+			return err
+		}
+		trace := make(Trace, sz)
+		for j := range trace {
+			trace[j] = &Span{}
+			if bts, err = trace[j].unmarshalMsgDictionary(bts, dict); err != nil {
+				return err
+			}
+		}
+		(*t)[i] = trace
+	}
+	return nil
+}
+
+// unmarshalMsgDictionary decodes a span from bts, looking up its strings in dict,
+// and returns the remaining bytes.
+func (z *Span) unmarshalMsgDictionary(bts []byte, dict []string) ([]byte, error) {
+	sz, bts, err := readArrayHeaderBytes(bts)
+	if err != nil {
+		return bts, err
+	}
+	if sz != dictionarySpanLen {
+		return bts, fmt.Errorf("expected span of %d elements, got %d", dictionarySpanLen, sz)
+	}
+	if z.Service, bts, err = parseDictionaryStringBytes(bts, dict); err != nil {
+		return bts, err
+	}
+	if z.Name, bts, err = parseDictionaryStringBytes(bts, dict); err != nil {
+		return bts, err
+	}
+	if z.Resource, bts, err = parseDictionaryStringBytes(bts, dict); err != nil {
+		return bts, err
+	}
+	if z.TraceID, bts, err = parseUint64Bytes(bts); err != nil {
+		return bts, err
+	}
+	if z.SpanID, bts, err = parseUint64Bytes(bts); err != nil {
+		return bts, err
+	}
+	if z.ParentID, bts, err = parseUint64Bytes(bts); err != nil {
+		return bts, err
+	}
+	if z.Start, bts, err = msgp.ReadInt64Bytes(bts); err != nil {
+		return bts, err
+	}
+	if z.Duration, bts, err = msgp.ReadInt64Bytes(bts); err != nil {
+		return bts, err
+	}
+	if z.Error, bts, err = msgp.ReadInt32Bytes(bts); err != nil {
+		return bts, err
+	}
+
+	if sz, bts, err = readMapHeaderBytes(bts); err != nil {
+		return bts, err
+	}
+	z.Meta = make(map[string]string, sz)
+	for i := uint32(0); i < sz; i++ {
+		var k, v string
+		if k, bts, err = parseDictionaryStringBytes(bts, dict); err != nil {
+			return bts, err
+		}
+		if v, bts, err = parseDictionaryStringBytes(bts, dict); err != nil {
+			return bts, err
+		}
+		z.Meta[k] = v
+	}
+
+	if sz, bts, err = readMapHeaderBytes(bts); err != nil {
+		return bts, err
+	}
+	z.Metrics = make(map[string]float64, sz)
+	for i := uint32(0); i < sz; i++ {
+		var (
+			k string
+			v float64
+		)
+		if k, bts, err = parseDictionaryStringBytes(bts, dict); err != nil {
+			return bts, err
+		}
+		if v, bts, err = parseFloat64Bytes(bts); err != nil {
+			return bts, err
+		}
+		z.Metrics[k] = v
+	}
+
+	z.Type, bts, err = parseDictionaryStringBytes(bts, dict)
+	return bts, err
+}
+
+// readArrayHeaderBytes reads an array header from bts, making sure that the
+// remaining bytes can hold that many elements before anything is allocated for them.
+func readArrayHeaderBytes(bts []byte) (uint32, []byte, error) {
+	sz, bts, err := msgp.ReadArrayHeaderBytes(bts)
+	if err == nil && uint64(sz) > uint64(len(bts)) {
+		err = msgp.ErrShortBytes
+	}
+	return sz, bts, err
+}
+
+// readMapHeaderBytes reads a map header from bts, making sure that the remaining
+// bytes can hold that many key-value pairs before anything is allocated for them.
+func readMapHeaderBytes(bts []byte) (uint32, []byte, error) {
+	sz, bts, err := msgp.ReadMapHeaderBytes(bts)
+	if err == nil && 2*uint64(sz) > uint64(len(bts)) {
+		err = msgp.ErrShortBytes
+	}
+	return sz, bts, err
+}
+
+// parseStringBytes reads the next BinType or StrType in bts and copies it in a string.
+func parseStringBytes(bts []byte) (string, []byte, error) {
+	var (
+		v   []byte
+		err error
+	)
+	switch t := msgp.NextType(bts); t {
+	case msgp.BinType:
+		v, bts, err = msgp.ReadBytesZC(bts)
+	case msgp.StrType:
+		v, bts, err = msgp.ReadStringZC(bts)
+	default:
+		return "", bts, msgp.TypeError{Encoded: t, Method: msgp.StrType}
+	}
+	return string(v), bts, err
+}
+
+// parseDictionaryStringBytes reads the next index in bts and returns the string
+// of dict it references.
+func parseDictionaryStringBytes(bts []byte, dict []string) (string, []byte, error) {
+	i, bts, err := parseUint64Bytes(bts)
+	if err != nil {
+		return "", bts, err
+	}
+	if i >= uint64(len(dict)) {
+		return "", bts, fmt.Errorf("string index %d out of the bounds of the dictionary (%d strings)", i, len(dict))
+	}
+	return dict[i], bts, nil
+}
+
+// parseUint64Bytes is the equivalent of parseUint64 for a byte slice.
+func parseUint64Bytes(bts []byte) (uint64, []byte, error) {
+	switch t := msgp.NextType(bts); t {
+	case msgp.UintType:
+		return msgp.ReadUint64Bytes(bts)
+	case msgp.IntType:
+		i, bts, err := msgp.ReadInt64Bytes(bts)
+		return uint64(i), bts, err
+	default:
+		return 0, bts, msgp.TypeError{Encoded: t, Method: msgp.IntType}
+	}
+}
+
+// parseFloat64Bytes is the equivalent of parseFloat64 for a byte slice.
+func parseFloat64Bytes(bts []byte) (float64, []byte, error) {
+	switch t := msgp.NextType(bts); t {
+	case msgp.IntType:
+		i, bts, err := msgp.ReadInt64Bytes(bts)
+		return float64(i), bts, err
+	case msgp.UintType:
+		u, bts, err := msgp.ReadUint64Bytes(bts)
+		return float64(u), bts, err
+	default:
+		return msgp.ReadFloat64Bytes(bts)
+	}
+}
+
+// AppendMsgDictionary appends the dictionary encoding of the traces to b.
+func (t Traces) AppendMsgDictionary(b []byte) []byte {
+	var (
+		dict  []string
+		index = make(map[string]uint32)
+	)
+	ref := func(s string) uint32 {
+		i, ok := index[s]
+		if !ok {
+			i = uint32(len(dict))
+			index[s] = i
+			dict = append(dict, s)
+		}
+		return i
+	}
+
+	var spans []byte
+	spans = msgp.AppendArrayHeader(spans, uint32(len(t)))
+	for _, trace := range t {
+		spans = msgp.AppendArrayHeader(spans, uint32(len(trace)))
+		for _, s := range trace {
+			spans = msgp.AppendArrayHeader(spans, dictionarySpanLen)
+			spans = msgp.AppendUint32(spans, ref(s.Service))
+			spans = msgp.AppendUint32(spans, ref(s.Name))
+			spans = msgp.AppendUint32(spans, ref(s.Resource))
+			spans = msgp.AppendUint64(spans, s.TraceID)
+			spans = msgp.AppendUint64(spans, s.SpanID)
+			spans = msgp.AppendUint64(spans, s.ParentID)
+			spans = msgp.AppendInt64(spans, s.Start)
+			spans = msgp.AppendInt64(spans, s.Duration)
+			spans = msgp.AppendInt32(spans, s.Error)
+			spans = msgp.AppendMapHeader(spans, uint32(len(s.Meta)))
+			for k, v := range s.Meta {
+				spans = msgp.AppendUint32(spans, ref(k))
+				spans = msgp.AppendUint32(spans, ref(v))
+			}
+			spans = msgp.AppendMapHeader(spans, uint32(len(s.Metrics)))
+			for k, v := range s.Metrics {
+				spans = msgp.AppendUint32(spans, ref(k))
+				spans = msgp.AppendFloat64(spans, v)
+			}
+			spans = msgp.AppendUint32(spans, ref(s.Type))
+		}
+	}
+
+	b = msgp.AppendArrayHeader(b, dictionaryPayloadLen)
+	b = msgp.AppendArrayHeader(b, uint32(len(dict)))
+	for _, s := range dict {
+		b = msgp.AppendString(b, s)
+	}
+	return append(b, spans...)
+}
@@ -0,0 +1,165 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package pb
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func dictionaryTestTraces() Traces {
+	return Traces{
+		{
+			{
+				Service:  "web",
+				Name:     "http.request",
+				Resource: "GET /users",
+				TraceID:  1,
+				SpanID:   2,
+				Start:    1548931840954169000,
+				Duration: 100000000,
+				Meta:     map[string]string{"http.method": "GET", "http.url": "/users"},
+				Metrics:  map[string]float64{"_sampling_priority_v1": 1},
+				Type:     "web",
+			},
+			{
+				Service:  "db",
+				Name:     "postgres.query",
+				Resource: "SELECT * FROM users",
+				TraceID:  1,
+				SpanID:   3,
+				ParentID: 2,
+				Start:    1548931840954169100,
+				Duration: 42,
+				Error:    1,
+				Meta:     map[string]string{"http.method": "web"},
+				Metrics:  map[string]float64{},
+				Type:     "sql",
+			},
+		},
+		{
+			{Service: "web", Name: "http.request", TraceID: 4, SpanID: 5, Meta: map[string]string{}, Metrics: map[string]float64{}},
+		},
+	}
+}
+
+func decodeDictionary(b []byte) (Traces, error) {
+	var traces Traces
+	err := traces.UnmarshalMsgDictionary(b)
+	return traces, err
+}
+
+func TestUnmarshalMsgDictionary(t *testing.T) {
+	want := dictionaryTestTraces()
+	got, err := decodeDictionary(want.AppendMsgDictionary(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	got, err = decodeDictionary(Traces{}.AppendMsgDictionary(nil))
+	assert.NoError(t, err)
+	assert.Len(t, got, 0)
+}
+
+func TestUnmarshalMsgDictionaryErrors(t *testing.T) {
+	dict := func(strs ...string) []byte {
+		b := msgp.AppendArrayHeader(nil, uint32(len(strs)))
+		for _, s := range strs {
+			b = msgp.AppendString(b, s)
+		}
+		return b
+	}
+	// span returns a span of n elements whose strings are all empty but its service
+	span := func(service uint32, n int) []byte {
+		b := msgp.AppendArrayHeader(nil, uint32(n))
+		b = msgp.AppendUint32(b, service)
+		for i := 1; i < n; i++ {
+			switch i {
+			case 9, 10:
+				b = msgp.AppendMapHeader(b, 0)
+			default:
+				b = msgp.AppendUint32(b, 0)
+			}
+		}
+		return b
+	}
+	payload := func(dict []byte, spans ...[]byte) []byte {
+		b := msgp.AppendArrayHeader(nil, 2)
+		b = append(b, dict...)
+		b = msgp.AppendArrayHeader(b, 1)
+		b = msgp.AppendArrayHeader(b, uint32(len(spans)))
+		for _, s := range spans {
+			b = append(b, s...)
+		}
+		return b
+	}
+
+	metaType := span(0, 12)
+	metaType[10] = 0 // meta is encoded as a positive fixint, not as a map
+
+	for name, b := range map[string][]byte{
+		"empty":            nil,
+		"not-an-array":     msgp.AppendString(nil, "traces"),
+		"payload-length":   msgp.AppendArrayHeader(nil, 3),
+		"dictionary-type":  payload(msgp.AppendArrayHeader(msgp.AppendArrayHeader(nil, 1), 0)),
+		"span-length":      payload(dict(""), span(0, 11)),
+		"index-bounds":     payload(dict(""), span(1, 12)),
+		"empty-dictionary": payload(dict(), span(0, 12)),
+		"meta-type":        payload(dict(""), metaType),
+		"truncated":        payload(dict(""), span(0, 12)[:6]),
+		"huge-headers":     payload(msgp.AppendArrayHeader(nil, 1<<31)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeDictionary(b)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := decodeDictionary(payload(dict(""), span(0, 12)))
+	assert.NoError(t, err)
+}
+
+// TestUnmarshalMsgDictionaryFuzz makes sure that corrupted payloads never make the
+// decoder panic.
+func TestUnmarshalMsgDictionaryFuzz(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	valid := dictionaryTestTraces().AppendMsgDictionary(nil)
+	for i := 0; i < 10000; i++ {
+		b := make([]byte, len(valid))
+		copy(b, valid)
+		switch r.Intn(3) {
+		case 0:
+			// truncate
+			b = b[:r.Intn(len(b))]
+		case 1:
+			// flip random bytes
+			for n := r.Intn(4) + 1; n > 0; n-- {
+				b[r.Intn(len(b))] = byte(r.Intn(256))
+			}
+		case 2:
+			// random bytes after a valid header
+			b = b[:1+r.Intn(len(b)-1)]
+			for n := r.Intn(64); n > 0; n-- {
+				b = append(b, byte(r.Intn(256)))
+			}
+		}
+		decodeDictionary(b)
+	}
+}
+
+func BenchmarkUnmarshalMsgDictionary(b *testing.B) {
+	payload := dictionaryTestTraces().AppendMsgDictionary(nil)
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := decodeDictionary(payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
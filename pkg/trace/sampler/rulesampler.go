@@ -0,0 +1,224 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sampler
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+const (
+	// KeySamplingRateRule is a metric key holding the rate of the sampling rule matching a trace.
+	KeySamplingRateRule = "_dd.rule_psr"
+
+	// KeySamplingRateRuleLimiter is a metric key holding the effective rate of the
+	// rate limiter of the sampling rule matching a trace.
+	KeySamplingRateRuleLimiter = "_dd.limit_psr"
+)
+
+// Rule is a user-defined sampling rule. A trace matches when its root span
+// matches all the conditions set on the rule.
+type Rule struct {
+	// Service matches the service of the root span when not empty.
+	Service string
+	// Name matches the operation name of the root span when not empty.
+	Name string
+	// Resource matches the resource of the root span when not nil.
+	Resource *regexp.Regexp
+	// Tags match the tag values of the root span.
+	Tags map[string]*regexp.Regexp
+	// Rate is the rate at which the matching traces are kept.
+	Rate float64
+	// MaxTPS is the maximum number of matching traces kept per second, zero disables the limit.
+	MaxTPS float64
+}
+
+// String returns a description of the conditions of the rule.
+func (r *Rule) String() string {
+	var conds []string
+	if r.Service != "" {
+		conds = append(conds, "service:"+r.Service)
+	}
+	if r.Name != "" {
+		conds = append(conds, "name:"+r.Name)
+	}
+	if r.Resource != nil {
+		conds = append(conds, "resource:"+r.Resource.String())
+	}
+	tags := make([]string, 0, len(r.Tags))
+	for k, re := range r.Tags {
+		tags = append(tags, k+":"+re.String())
+	}
+	sort.Strings(tags)
+	conds = append(conds, tags...)
+	if len(conds) == 0 {
+		return "*"
+	}
+	return strings.Join(conds, ",")
+}
+
+// Match returns true if the span matches all the conditions of the rule.
+func (r *Rule) Match(root *pb.Span) bool {
+	if r.Service != "" && root.Service != r.Service {
+		return false
+	}
+	if r.Name != "" && root.Name != r.Name {
+		return false
+	}
+	if r.Resource != nil && !r.Resource.MatchString(root.Resource) {
+		return false
+	}
+	for k, re := range r.Tags {
+		v, ok := root.Meta[k]
+		if !ok {
+			m, ok := root.Metrics[k]
+			if !ok {
+				return false
+			}
+			v = fmt.Sprint(m)
+		}
+		if !re.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// RuleState exposes the statistics of a sampling rule.
+type RuleState struct {
+	Rule    string
+	Rate    float64
+	MaxTPS  float64
+	Matched int64
+	Kept    int64
+	Limited int64
+}
+
+// ruleLimiter caps the number of traces kept per second by a rule, it
+// tracks the share of the traces it allows over the last two periods.
+type ruleLimiter struct {
+	maxTPS  float64
+	tokens  float64
+	last    time.Time
+	period  time.Time
+	seen    [2]float64
+	allowed [2]float64
+}
+
+func newRuleLimiter(maxTPS float64, now time.Time) *ruleLimiter {
+	return &ruleLimiter{
+		maxTPS: maxTPS,
+		tokens: maxTPS,
+		last:   now,
+		period: now,
+	}
+}
+
+// allow returns true if a trace can be kept, along with the effective rate of the limiter.
+func (l *ruleLimiter) allow(now time.Time) (bool, float64) {
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens += elapsed * l.maxTPS
+		if l.tokens > l.maxTPS {
+			l.tokens = l.maxTPS
+		}
+		l.last = now
+	}
+	if now.Sub(l.period) >= time.Second {
+		if now.Sub(l.period) >= 2*time.Second {
+			// the previous period is older than a second
+			l.seen[1], l.allowed[1] = 0, 0
+		} else {
+			l.seen[1], l.allowed[1] = l.seen[0], l.allowed[0]
+		}
+		l.seen[0], l.allowed[0] = 0, 0
+		l.period = now
+	}
+	allowed := l.tokens >= 1
+	if allowed {
+		l.tokens--
+		l.allowed[0]++
+	}
+	l.seen[0]++
+	return allowed, (l.allowed[0] + l.allowed[1]) / (l.seen[0] + l.seen[1])
+}
+
+// RuleEngine samples the traces matching the user-defined sampling rules,
+// the first rule matching the root span of a trace applies.
+type RuleEngine struct {
+	mu       sync.Mutex
+	rules    []*Rule
+	limiters []*ruleLimiter
+	states   []RuleState
+	now      func() time.Time
+}
+
+// NewRuleEngine returns a RuleEngine applying the given rules in order.
+func NewRuleEngine(rules []*Rule) *RuleEngine {
+	e := &RuleEngine{
+		rules:    rules,
+		limiters: make([]*ruleLimiter, len(rules)),
+		states:   make([]RuleState, len(rules)),
+		now:      time.Now,
+	}
+	now := e.now()
+	for i, r := range rules {
+		if r.MaxTPS > 0 {
+			e.limiters[i] = newRuleLimiter(r.MaxTPS, now)
+		}
+		e.states[i] = RuleState{Rule: r.String(), Rate: r.Rate, MaxTPS: r.MaxTPS}
+	}
+	return e
+}
+
+// Sample applies the first rule matching the root span of a trace. It returns
+// false if no rule matches, otherwise the sampling decision and rate of the rule,
+// which are also set on the root span.
+func (e *RuleEngine) Sample(root *pb.Span) (matched bool, sampled bool, rate float64) {
+	if root == nil {
+		return false, false, 0
+	}
+	for i, r := range e.rules {
+		if !r.Match(root) {
+			continue
+		}
+		setMetric(root, KeySamplingRateRule, r.Rate)
+		sampled, rate = SampleByRate(root.TraceID, r.Rate), r.Rate
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		state := &e.states[i]
+		state.Matched++
+		if sampled && e.limiters[i] != nil {
+			allowed, limiterRate := e.limiters[i].allow(e.now())
+			setMetric(root, KeySamplingRateRuleLimiter, limiterRate)
+			rate *= limiterRate
+			if !allowed {
+				sampled = false
+				state.Limited++
+			}
+		}
+		if sampled {
+			state.Kept++
+		}
+		return true, sampled, rate
+	}
+	return false, false, 0
+}
+
+// GetState returns the statistics of the rules.
+func (e *RuleEngine) GetState() []RuleState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	states := make([]RuleState, len(e.states))
+	copy(states, e.states)
+	return states
+}
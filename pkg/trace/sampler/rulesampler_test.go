@@ -0,0 +1,143 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package sampler
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleMatch(t *testing.T) {
+	root := &pb.Span{
+		Service:  "checkout",
+		Name:     "http.request",
+		Resource: "POST /cart",
+		Meta:     map[string]string{"http.status_code": "503"},
+		Metrics:  map[string]float64{"retries": 3},
+	}
+	for name, tt := range map[string]struct {
+		rule *Rule
+		want bool
+	}{
+		"empty":            {&Rule{}, true},
+		"service":          {&Rule{Service: "checkout"}, true},
+		"other service":    {&Rule{Service: "web"}, false},
+		"name":             {&Rule{Service: "checkout", Name: "http.request"}, true},
+		"other name":       {&Rule{Service: "checkout", Name: "sql.query"}, false},
+		"resource":         {&Rule{Resource: regexp.MustCompile("^POST ")}, true},
+		"other resource":   {&Rule{Resource: regexp.MustCompile("^GET ")}, false},
+		"tag":              {&Rule{Tags: map[string]*regexp.Regexp{"http.status_code": regexp.MustCompile("^5")}}, true},
+		"other tag value":  {&Rule{Tags: map[string]*regexp.Regexp{"http.status_code": regexp.MustCompile("^2")}}, false},
+		"missing tag":      {&Rule{Tags: map[string]*regexp.Regexp{"env": regexp.MustCompile(".*")}}, false},
+		"metric tag":       {&Rule{Tags: map[string]*regexp.Regexp{"retries": regexp.MustCompile("^3$")}}, true},
+		"other metric tag": {&Rule{Tags: map[string]*regexp.Regexp{"retries": regexp.MustCompile("^4$")}}, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rule.Match(root))
+		})
+	}
+}
+
+func TestRuleString(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("*", (&Rule{}).String())
+	assert.Equal("service:checkout,name:http.request,resource:^POST,env:prod,http.status_code:5..", (&Rule{
+		Service:  "checkout",
+		Name:     "http.request",
+		Resource: regexp.MustCompile("^POST"),
+		Tags: map[string]*regexp.Regexp{
+			"http.status_code": regexp.MustCompile("5.."),
+			"env":              regexp.MustCompile("prod"),
+		},
+	}).String())
+}
+
+func TestRuleEngineSample(t *testing.T) {
+	assert := assert.New(t)
+	e := NewRuleEngine([]*Rule{
+		{Service: "checkout", Rate: 1},
+		{Service: "health", Rate: 0},
+		{Rate: 0.5},
+	})
+
+	root := &pb.Span{TraceID: 1, Service: "checkout"}
+	matched, sampled, rate := e.Sample(root)
+	assert.True(matched)
+	assert.True(sampled)
+	assert.Equal(1.0, rate)
+	assert.Equal(1.0, root.Metrics[KeySamplingRateRule])
+	_, ok := root.Metrics[KeySamplingRateRuleLimiter]
+	assert.False(ok)
+
+	root = &pb.Span{TraceID: 1, Service: "health"}
+	matched, sampled, rate = e.Sample(root)
+	assert.True(matched)
+	assert.False(sampled)
+	assert.Equal(0.0, rate)
+
+	// the last rule matches all the traces
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		root = &pb.Span{TraceID: randomTraceID(), Service: "web"}
+		matched, sampled, rate = e.Sample(root)
+		assert.True(matched)
+		assert.Equal(0.5, rate)
+		if sampled {
+			kept++
+		}
+	}
+	assert.InDelta(500, kept, 100)
+
+	state := e.GetState()
+	assert.Len(state, 3)
+	assert.Equal(RuleState{Rule: "service:checkout", Rate: 1, Matched: 1, Kept: 1}, state[0])
+	assert.Equal(RuleState{Rule: "service:health", Rate: 0, Matched: 1}, state[1])
+	assert.EqualValues(1000, state[2].Matched)
+	assert.EqualValues(kept, state[2].Kept)
+
+	e = NewRuleEngine(nil)
+	matched, _, _ = e.Sample(&pb.Span{TraceID: 1})
+	assert.False(matched)
+	matched, _, _ = e.Sample(nil)
+	assert.False(matched)
+}
+
+func TestRuleEngineMaxTPS(t *testing.T) {
+	assert := assert.New(t)
+	e := NewRuleEngine([]*Rule{{Service: "checkout", Rate: 1, MaxTPS: 2}})
+	now := time.Now()
+	e.now = func() time.Time { return now }
+	e.limiters[0] = newRuleLimiter(2, now)
+
+	var decisions []bool
+	var root *pb.Span
+	for i := 0; i < 4; i++ {
+		root = &pb.Span{TraceID: uint64(i), Service: "checkout"}
+		_, sampled, _ := e.Sample(root)
+		decisions = append(decisions, sampled)
+	}
+	assert.Equal([]bool{true, true, false, false}, decisions)
+	assert.Equal(0.5, root.Metrics[KeySamplingRateRuleLimiter])
+
+	// the bucket refills with time and the effective rate
+	// accounts for the previous period
+	now = now.Add(time.Second)
+	root = &pb.Span{TraceID: 5, Service: "checkout"}
+	_, sampled, rate := e.Sample(root)
+	assert.True(sampled)
+	assert.Equal(0.6, rate)
+	assert.Equal(0.6, root.Metrics[KeySamplingRateRuleLimiter])
+
+	state := e.GetState()[0]
+	assert.EqualValues(5, state.Matched)
+	assert.EqualValues(3, state.Kept)
+	assert.EqualValues(2, state.Limited)
+}
@@ -37,6 +37,8 @@ type Concentrator struct {
 	// wait such time before flushing the stats.
 	// This only applies to past buckets. Stats buckets in the future are allowed with no restriction.
 	bufferLen int
+	// sketches specifies whether the durations are recorded in sketches instead of distributions.
+	sketches bool
 
 	In  chan *Input
 	Out chan []Bucket
@@ -48,11 +50,13 @@ type Concentrator struct {
 	mu      sync.Mutex
 }
 
-// NewConcentrator initializes a new concentrator ready to be started
-func NewConcentrator(aggregators []string, bsize int64, out chan []Bucket) *Concentrator {
+// NewConcentrator initializes a new concentrator ready to be started. When sketches
+// is true, the span durations are recorded in sketches instead of distributions.
+func NewConcentrator(aggregators []string, bsize int64, sketches bool, out chan []Bucket) *Concentrator {
 	c := Concentrator{
 		aggregators: aggregators,
 		bsize:       bsize,
+		sketches:    sketches,
 		buckets:     make(map[int64]*RawBucket),
 		// At start, only allow stats for the current time bucket. Ensure we don't
 		// override buckets which could have been sent before an Agent restart.
@@ -147,6 +151,7 @@ func (c *Concentrator) addNow(i *Input, now int64) {
 		b, ok := c.buckets[btime]
 		if !ok {
 			b = NewRawBucket(btime, c.bsize)
+			b.sketches = c.sketches
 			c.buckets[btime] = b
 		}
 
@@ -0,0 +1,148 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/quantile"
+)
+
+// sketchConfig is the configuration of the sketches, the same as the one used
+// by quantile.Agent to insert values.
+var sketchConfig = quantile.Default()
+
+// SketchDistribution represents the durations of the spans of a tag set in
+// relative-error sketches: unlike the Greenwald-Khanna summary of a Distribution,
+// the error of any quantile, including the highest ones, is bounded by a
+// fraction of its value, however few the spans are.
+//
+// The durations of the spans with and without errors are kept apart.
+type SketchDistribution struct {
+	Key     string
+	Name    string // the name of the trace/spans we count
+	Measure string // represents the entity we count, e.g. "duration"
+	TagSet  TagSet // set of tags for which we account this distribution
+
+	TopLevel float64 // number of top-level spans contributing to this distribution
+
+	OK    *quantile.Sketch // durations of the spans without errors, nil if none
+	Error *quantile.Sketch // durations of the spans with errors, nil if none
+}
+
+// sketchDistributionJSON is the representation of a SketchDistribution in the
+// stats payload, its sketches are encoded with quantile.Sketch.MarshalBinary,
+// whose first byte holds the version of the encoding.
+type sketchDistributionJSON struct {
+	Key      string  `json:"key"`
+	Name     string  `json:"name"`
+	Measure  string  `json:"measure"`
+	TagSet   TagSet  `json:"tagset"`
+	TopLevel float64 `json:"top_level"`
+	OK       []byte  `json:"ok_sketch,omitempty"`
+	Error    []byte  `json:"error_sketch,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d SketchDistribution) MarshalJSON() ([]byte, error) {
+	var err error
+	v := sketchDistributionJSON{
+		Key:      d.Key,
+		Name:     d.Name,
+		Measure:  d.Measure,
+		TagSet:   d.TagSet,
+		TopLevel: d.TopLevel,
+	}
+	if d.OK != nil {
+		if v.OK, err = d.OK.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	if d.Error != nil {
+		if v.Error, err = d.Error.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *SketchDistribution) UnmarshalJSON(data []byte) error {
+	var v sketchDistributionJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*d = SketchDistribution{
+		Key:      v.Key,
+		Name:     v.Name,
+		Measure:  v.Measure,
+		TagSet:   v.TagSet,
+		TopLevel: v.TopLevel,
+	}
+	var err error
+	if d.OK, err = unmarshalSketch(v.OK); err != nil {
+		return fmt.Errorf("ok sketch of %s: %v", v.Key, err)
+	}
+	if d.Error, err = unmarshalSketch(v.Error); err != nil {
+		return fmt.Errorf("error sketch of %s: %v", v.Key, err)
+	}
+	return nil
+}
+
+func unmarshalSketch(data []byte) (*quantile.Sketch, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var s quantile.Sketch
+	if err := s.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Merge merges the sketches of d2 into the ones of d and returns the result.
+// The sketches of d2 are left untouched.
+func (d SketchDistribution) Merge(d2 SketchDistribution) SketchDistribution {
+	d.OK = mergeSketches(d.OK, d2.OK)
+	d.Error = mergeSketches(d.Error, d2.Error)
+	return d
+}
+
+// mergeSketches merges s2 into s1, either of which may be nil, and returns the result.
+func mergeSketches(s1, s2 *quantile.Sketch) *quantile.Sketch {
+	switch {
+	case s2 == nil:
+		return s1
+	case s1 == nil:
+		return s2.Copy()
+	}
+	s1.Merge(sketchConfig, s2)
+	return s1
+}
+
+// Quantile returns the duration of the spans, with or without errors, at quantile q.
+func (d SketchDistribution) Quantile(q float64) float64 {
+	s := mergeSketches(nil, d.OK)
+	s = mergeSketches(s, d.Error)
+	if s == nil {
+		return 0
+	}
+	return s.Quantile(sketchConfig, q)
+}
+
+// Points returns up to max durations of the spans, with or without errors, at
+// quantiles evenly spread across them.
+func (d SketchDistribution) Points(max int) []float64 {
+	s := mergeSketches(nil, d.OK)
+	s = mergeSketches(s, d.Error)
+	if s == nil {
+		return nil
+	}
+	return quantilePoints(int(s.Basic.Cnt), max, func(q float64) float64 {
+		return s.Quantile(sketchConfig, q)
+	})
+}
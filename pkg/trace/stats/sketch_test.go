@@ -0,0 +1,220 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package stats
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/quantile"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	gk "github.com/DataDog/datadog-agent/pkg/trace/stats/quantile"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSketch(values ...float64) *quantile.Sketch {
+	var a quantile.Agent
+	for _, v := range values {
+		a.Insert(v)
+	}
+	return a.Finish()
+}
+
+func TestSketchDistributionJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	d := SketchDistribution{
+		Key:      "duration|web.request|env:default,resource:GET /,service:web",
+		Name:     "web.request",
+		Measure:  DURATION,
+		TagSet:   TagSet{Tag{"env", "default"}, Tag{"resource", "GET /"}, Tag{"service", "web"}},
+		TopLevel: 3,
+		OK:       testSketch(1e6, 2e6),
+	}
+	b, err := json.Marshal(d)
+	assert.NoError(err)
+
+	var d2 SketchDistribution
+	assert.NoError(json.Unmarshal(b, &d2))
+	assert.Equal(d.Key, d2.Key)
+	assert.Equal(d.TagSet, d2.TagSet)
+	assert.Equal(d.TopLevel, d2.TopLevel)
+	assert.Nil(d2.Error)
+	if assert.NotNil(d2.OK) {
+		assert.Equal(d.OK.String(), d2.OK.String())
+	}
+
+	// an unknown encoding version is rejected
+	b = []byte(`{"key":"k","ok_sketch":"/w=="}`)
+	assert.Error(json.Unmarshal(b, &d2))
+}
+
+func TestSketchDistributionMerge(t *testing.T) {
+	assert := assert.New(t)
+
+	d1 := SketchDistribution{OK: testSketch(1, 2, 3)}
+	d2 := SketchDistribution{OK: testSketch(4), Error: testSketch(5, 6)}
+
+	d := d1.Merge(d2)
+	assert.EqualValues(4, d.OK.Basic.Cnt)
+	assert.EqualValues(2, d.Error.Basic.Cnt)
+	// the merged sketches are left untouched
+	assert.EqualValues(1, d2.OK.Basic.Cnt)
+	assert.EqualValues(2, d2.Error.Basic.Cnt)
+
+	assert.InEpsilon(6, d.Quantile(1), 0.01)
+	assert.Equal(0.0, SketchDistribution{}.Quantile(0.5))
+}
+
+func TestDistributionPoints(t *testing.T) {
+	assert := assert.New(t)
+
+	d := SketchDistribution{OK: testSketch(1e6, 2e6, 3e6), Error: testSketch(4e6)}
+	points := d.Points(100)
+	if assert.Len(points, 4) {
+		for i, v := range []float64{1e6, 2e6, 3e6, 4e6} {
+			assert.InEpsilon(v, points[i], 0.02)
+		}
+	}
+	points = d.Points(2)
+	if assert.Len(points, 2) {
+		assert.True(points[0] < points[1])
+	}
+	assert.Nil(SketchDistribution{}.Points(100))
+
+	gd := NewDistribution(DURATION, "key", "web.request", nil)
+	for i := 1; i <= 1000; i++ {
+		gd.Add(float64(i), uint64(i))
+	}
+	points = gd.Points(10)
+	if assert.Len(points, 10) {
+		assert.InDelta(50, points[0], 10)
+		assert.InDelta(950, points[9], 10)
+	}
+	assert.Nil(NewDistribution(DURATION, "key", "web.request", nil).Points(10))
+}
+
+func TestRawBucketSketches(t *testing.T) {
+	assert := assert.New(t)
+
+	srb := NewRawBucket(0, 1e9)
+	srb.sketches = true
+	for i, d := range []int64{10, 20, 30, 40} {
+		s := &WeightedSpan{
+			Span:     &pb.Span{Service: "A", Name: "A.foo", Resource: "α", Duration: d},
+			Weight:   1,
+			TopLevel: true,
+		}
+		if i%2 == 1 {
+			s.Error = 1
+		}
+		srb.HandleSpan(s, "default", nil, nil)
+	}
+
+	b := srb.Export()
+	assert.Empty(b.Distributions)
+	assert.Empty(b.ErrDistributions)
+	assert.Len(b.Counts, 3) // hits, errors and duration
+	if assert.Len(b.Sketches, 1) {
+		for _, d := range b.Sketches {
+			assert.Equal("A.foo", d.Name)
+			assert.Equal(4.0, d.TopLevel)
+			assert.EqualValues(2, d.OK.Basic.Cnt)
+			assert.EqualValues(2, d.Error.Basic.Cnt)
+			assert.Equal(30.0, d.OK.Basic.Max)
+			assert.Equal(40.0, d.Error.Basic.Max)
+		}
+	}
+}
+
+// benchDurations returns n durations, in nanoseconds, following a log-normal
+// distribution around 10ms.
+func benchDurations(n int) []float64 {
+	r := rand.New(rand.NewSource(42))
+	vals := make([]float64, n)
+	for i := range vals {
+		vals[i] = 1e7 * r.ExpFloat64() * r.ExpFloat64()
+	}
+	return vals
+}
+
+func BenchmarkSummaryInsert(b *testing.B) {
+	vals := benchDurations(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := gk.NewSliceSummary()
+		for _, v := range vals {
+			s.Insert(v, 0)
+		}
+	}
+}
+
+func BenchmarkSketchInsert(b *testing.B) {
+	vals := benchDurations(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var a quantile.Agent
+		for _, v := range vals {
+			a.Insert(v)
+		}
+		a.Finish()
+	}
+}
+
+func BenchmarkSummaryMerge(b *testing.B) {
+	vals := benchDurations(1000)
+	s1, s2 := gk.NewSliceSummary(), gk.NewSliceSummary()
+	for i, v := range vals {
+		if i%2 == 0 {
+			s1.Insert(v, 0)
+		} else {
+			s2.Insert(v, 0)
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := s1.Copy()
+		s.Merge(s2)
+	}
+}
+
+func BenchmarkSketchMerge(b *testing.B) {
+	vals := benchDurations(1000)
+	s1, s2 := testSketch(vals[:500]...), testSketch(vals[500:]...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mergeSketches(s1.Copy(), s2)
+	}
+}
+
+func BenchmarkSummaryEncoding(b *testing.B) {
+	s := gk.NewSliceSummary()
+	for _, v := range benchDurations(1000) {
+		s.Insert(v, 0)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out, _ := json.Marshal(s)
+		b.SetBytes(int64(len(out)))
+	}
+}
+
+func BenchmarkSketchEncoding(b *testing.B) {
+	d := SketchDistribution{OK: testSketch(benchDurations(1000)...)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out, _ := json.Marshal(d)
+		b.SetBytes(int64(len(out)))
+	}
+}
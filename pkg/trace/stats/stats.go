@@ -133,6 +133,31 @@ func (d Distribution) Copy() Distribution {
 	return d2
 }
 
+// Points returns up to max values of the distribution, at quantiles evenly spread
+// across it.
+func (d Distribution) Points(max int) []float64 {
+	if d.Summary == nil {
+		return nil
+	}
+	return quantilePoints(d.Summary.N, max, d.Summary.Quantile)
+}
+
+// quantilePoints returns the values at min(n, max) quantiles evenly spread across a
+// distribution of n values, using quantile to query it.
+func quantilePoints(n, max int, quantile func(q float64) float64) []float64 {
+	if n > max {
+		n = max
+	}
+	if n <= 0 {
+		return nil
+	}
+	points := make([]float64, n)
+	for i := range points {
+		points[i] = quantile((float64(i) + 0.5) / float64(n))
+	}
+	return points
+}
+
 // Bucket is a time bucket to track statistic around multiple Counts
 type Bucket struct {
 	Start    int64 // Timestamp of start in our format
@@ -142,6 +167,9 @@ type Bucket struct {
 	Counts           map[string]Count        // All the counts
 	Distributions    map[string]Distribution // All the distributions (e.g.: for quantile queries)
 	ErrDistributions map[string]Distribution // All the error distributions (e.g.: for apdex, as they account for frustrated)
+
+	// Sketches replace Distributions and ErrDistributions when the durations are recorded in sketches.
+	Sketches map[string]SketchDistribution `json:",omitempty"`
 }
 
 // NewBucket opens a new bucket for time ts and initializes it properly
@@ -153,10 +181,11 @@ func NewBucket(ts, d int64) Bucket {
 		Counts:           make(map[string]Count),
 		Distributions:    make(map[string]Distribution),
 		ErrDistributions: make(map[string]Distribution),
+		Sketches:         make(map[string]SketchDistribution),
 	}
 }
 
 // IsEmpty just says if this stats bucket has no information (in which case it's useless)
 func (sb Bucket) IsEmpty() bool {
-	return len(sb.Counts) == 0 && len(sb.Distributions) == 0 && len(sb.ErrDistributions) == 0
+	return len(sb.Counts) == 0 && len(sb.Distributions) == 0 && len(sb.ErrDistributions) == 0 && len(sb.Sketches) == 0
 }
@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"sort"
 
+	ddsketch "github.com/DataDog/datadog-agent/pkg/quantile"
 	"github.com/DataDog/datadog-agent/pkg/trace/stats/quantile"
 )
 
@@ -25,6 +26,10 @@ type groupedStats struct {
 	duration                float64
 	durationDistribution    *quantile.SliceSummary
 	errDurationDistribution *quantile.SliceSummary
+
+	// set instead of the distributions when the durations are recorded in sketches
+	okDurationSketch  *ddsketch.Agent
+	errDurationSketch *ddsketch.Agent
 }
 
 type sublayerStats struct {
@@ -35,7 +40,14 @@ type sublayerStats struct {
 	value int64
 }
 
-func newGroupedStats(tags TagSet) groupedStats {
+func newGroupedStats(tags TagSet, sketches bool) groupedStats {
+	if sketches {
+		return groupedStats{
+			tags:              tags,
+			okDurationSketch:  &ddsketch.Agent{},
+			errDurationSketch: &ddsketch.Agent{},
+		}
+	}
 	return groupedStats{
 		tags:                    tags,
 		durationDistribution:    quantile.NewSliceSummary(),
@@ -73,6 +85,10 @@ type RawBucket struct {
 	data         map[statsKey]groupedStats
 	sublayerData map[statsSubKey]sublayerStats
 
+	// sketches specifies whether the durations are recorded in sketches
+	// instead of distributions.
+	sketches bool
+
 	// internal buffer for aggregate strings - not threadsafe
 	keyBuf bytes.Buffer
 }
@@ -121,6 +137,18 @@ func (sb *RawBucket) Export() Bucket {
 			TopLevel: v.topLevel,
 			Value:    float64(v.duration),
 		}
+		if sb.sketches {
+			ret.Sketches[durationKey] = SketchDistribution{
+				Key:      durationKey,
+				Name:     k.name,
+				Measure:  DURATION,
+				TagSet:   v.tags,
+				TopLevel: v.topLevel,
+				OK:       v.okDurationSketch.Finish(),
+				Error:    v.errDurationSketch.Finish(),
+			}
+			continue
+		}
 		ret.Distributions[durationKey] = Distribution{
 			Key:      durationKey,
 			Name:     k.name,
@@ -218,7 +246,7 @@ func (sb *RawBucket) add(s *WeightedSpan, aggr string, tags TagSet) {
 
 	key := statsKey{name: s.Name, aggr: aggr}
 	if gs, ok = sb.data[key]; !ok {
-		gs = newGroupedStats(tags)
+		gs = newGroupedStats(tags, sb.sketches)
 	}
 
 	if s.TopLevel {
@@ -231,6 +259,16 @@ func (sb *RawBucket) add(s *WeightedSpan, aggr string, tags TagSet) {
 	}
 	gs.duration += float64(s.Duration) * s.Weight
 
+	if sb.sketches {
+		if s.Error != 0 {
+			gs.errDurationSketch.Insert(float64(s.Duration))
+		} else {
+			gs.okDurationSketch.Insert(float64(s.Duration))
+		}
+		sb.data[key] = gs
+		return
+	}
+
 	// TODO add for s.Metrics ability to define arbitrary counts and distros, check some config?
 	// alter resolution of duration distro
 	trundur := nsTimestampToFloat(s.Duration)
@@ -14,6 +14,9 @@ import (
 type MockEngine struct {
 	wantSampled bool
 	wantRate    float64
+
+	// Calls is the number of traces passed to Sample
+	Calls int
 }
 
 // NewMockEngine returns a MockEngine for tests
@@ -23,6 +26,7 @@ func NewMockEngine(wantSampled bool, wantRate float64) *MockEngine {
 
 // Sample returns a constant rate
 func (e *MockEngine) Sample(_ pb.Trace, _ *pb.Span, _ string) (bool, float64) {
+	e.Calls++
 	return e.wantSampled, e.wantRate
 }
 
@@ -9,10 +9,13 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"math/rand"
 	"net/http"
 	"net/url"
+	"path"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -25,8 +28,8 @@ import (
 )
 
 // newSenders returns a list of senders based on the given agent configuration, using climit
-// as the maximum number of concurrent outgoing connections, writing to path.
-func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, qsize int) []*sender {
+// as the maximum number of concurrent outgoing connections, writing to urlPath.
+func newSenders(cfg *config.AgentConfig, r eventRecorder, urlPath string, climit, qsize int) []*sender {
 	if e := cfg.Endpoints; len(e) == 0 || e[0].Host == "" || e[0].APIKey == "" {
 		panic(errors.New("config was not properly validated"))
 	}
@@ -35,7 +38,7 @@ func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, q
 	maxConns := math.Max(1, float64(climit/len(cfg.Endpoints)))
 	senders := make([]*sender, len(cfg.Endpoints))
 	for i, endpoint := range cfg.Endpoints {
-		url, err := url.Parse(endpoint.Host + path)
+		url, err := url.Parse(endpoint.Host + urlPath)
 		if err != nil {
 			osutil.Exitf("Invalid host endpoint: %q", endpoint.Host)
 		}
@@ -46,11 +49,31 @@ func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, q
 			url:       url,
 			apiKey:    endpoint.APIKey,
 			recorder:  r,
+			spool:     newEndpointSpool(cfg.DiskSpool, endpoint, urlPath),
 		})
 	}
 	return senders
 }
 
+// newEndpointSpool returns the spool of the payloads sent to urlPath on the given
+// endpoint, or nil if spooling is disabled.
+func newEndpointSpool(cfg *config.DiskSpoolConfig, endpoint *config.Endpoint, urlPath string) *spool {
+	if cfg == nil {
+		return nil
+	}
+	// the directory of the spool must not change across restarts, nor be shared
+	// by endpoints, which may only differ by their API key.
+	h := fnv.New64a()
+	h.Write([]byte(endpoint.Host + "|" + endpoint.APIKey))
+	dir := filepath.Join(cfg.Path, path.Base(urlPath), fmt.Sprintf("%x", h.Sum64()))
+	s, err := newSpool(dir, cfg.MaxSize, time.Duration(cfg.MaxAgeSeconds)*time.Second)
+	if err != nil {
+		log.Errorf("Disk spool disabled for %s: %v", endpoint.Host, err)
+		return nil
+	}
+	return s
+}
+
 // eventRecorder implementations are able to take note of events happening in
 // the sender.
 type eventRecorder interface {
@@ -72,6 +95,9 @@ const (
 	// eventTypeDropped specifies that a payload had to be dropped to make room
 	// in the queue.
 	eventTypeDropped
+	// eventTypeSpooled specifies that a payload was written to the disk spool
+	// instead of being dropped.
+	eventTypeSpooled
 )
 
 var eventTypeStrings = map[eventType]string{
@@ -79,6 +105,7 @@ var eventTypeStrings = map[eventType]string{
 	eventTypeSent:     "eventTypeSent",
 	eventTypeRejected: "eventTypeRejected",
 	eventTypeDropped:  "eventTypeDropped",
+	eventTypeSpooled:  "eventTypeSpooled",
 }
 
 // String implements fmt.Stringer.
@@ -123,6 +150,9 @@ type senderConfig struct {
 	// recorder specifies the eventRecorder to use when reporting events occurring
 	// in the sender.
 	recorder eventRecorder
+	// spool specifies the disk spool receiving the payloads which would otherwise
+	// be dropped. It is nil when spooling is disabled.
+	spool *spool
 }
 
 // sender is responsible for sending payloads to a given URL. It uses a size-limited
@@ -132,6 +162,7 @@ type sender struct {
 
 	queue    chan *payload // payload queue
 	climit   chan struct{} // semaphore for limiting concurrent connections
+	drain    chan struct{} // signals that the spool may be drained
 	inflight int32         // inflight payloads
 	attempt  int32         // active retry attempt
 
@@ -145,8 +176,14 @@ func newSender(cfg *senderConfig) *sender {
 		cfg:    cfg,
 		queue:  make(chan *payload, cfg.maxQueued),
 		climit: make(chan struct{}, cfg.maxConns),
+		drain:  make(chan struct{}, 1),
 	}
 	go s.loop()
+	if cfg.spool != nil {
+		go s.drainLoop()
+		// send the payloads left by a previous run
+		s.drain <- struct{}{}
+	}
 	return &s
 }
 
@@ -191,7 +228,20 @@ outer:
 	s.mu.Lock()
 	s.closed = true
 	s.mu.Unlock()
+	if s.cfg.spool != nil {
+		// keep the payloads which are still queued for the next run
+	queued:
+		for {
+			select {
+			case p := <-s.queue:
+				s.spoolPayload(p, &eventData{bytes: p.body.Len(), count: 1})
+			default:
+				break queued
+			}
+		}
+	}
 	close(s.queue)
+	close(s.drain)
 }
 
 // Push pushes p onto the sender's queue, to be written to the destination.
@@ -206,7 +256,7 @@ func (s *sender) Push(p *payload) {
 			// drop the oldest item in the queue to make room
 			select {
 			case p := <-s.queue:
-				s.releasePayload(p, eventTypeDropped, &eventData{
+				s.dropPayload(p, &eventData{
 					bytes: p.body.Len(),
 					count: 1,
 				})
@@ -241,6 +291,9 @@ func (s *sender) sendPayload(p *payload) {
 		defer s.mu.RUnlock()
 		if s.closed {
 			// sender is stopped
+			if s.cfg.spool != nil {
+				s.spoolPayload(p, stats)
+			}
 			return
 		}
 		atomic.AddInt32(&s.attempt, 1)
@@ -250,7 +303,7 @@ func (s *sender) sendPayload(p *payload) {
 			return
 		default:
 			// queue is full; since this is the oldest payload, we drop it
-			s.releasePayload(p, eventTypeDropped, stats)
+			s.dropPayload(p, stats)
 		}
 	case nil:
 		// request was successful; the retry queue may have grown large - we should
@@ -263,12 +316,97 @@ func (s *sender) sendPayload(p *payload) {
 			}
 		}
 		s.releasePayload(p, eventTypeSent, stats)
+		if s.cfg.spool != nil {
+			// the endpoint is up, send the spooled payloads
+			s.signalDrain()
+		}
 	default:
 		// this is a fatal error, we have to drop this payload
 		s.releasePayload(p, eventTypeRejected, stats)
 	}
 }
 
+// dropPayload drops the payload p, which could not be sent, writing it to the
+// spool when it is enabled.
+func (s *sender) dropPayload(p *payload, data *eventData) {
+	if s.cfg.spool == nil {
+		s.releasePayload(p, eventTypeDropped, data)
+		return
+	}
+	s.spoolPayload(p, data)
+}
+
+// spoolPayload writes the payload p to the spool and releases it. The payload is
+// dropped if it can't be written.
+func (s *sender) spoolPayload(p *payload, data *eventData) {
+	if err := s.cfg.spool.add(p); err != nil {
+		log.Errorf("Error writing payload to the disk spool: %v", err)
+		s.releasePayload(p, eventTypeDropped, data)
+		return
+	}
+	s.releasePayload(p, eventTypeSpooled, data)
+}
+
+// signalDrain signals the drain loop that the spool may be drained.
+func (s *sender) signalDrain() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.drain <- struct{}{}:
+	default:
+		// already signaled
+	}
+}
+
+// drainLoop moves the spooled payloads back to the queue every time it is signaled
+// that the endpoint is up, until the sender is stopped.
+func (s *sender) drainLoop() {
+	for range s.drain {
+		s.drainSpool()
+	}
+}
+
+// drainSpool moves the spooled payloads back to the queue, oldest first, as long
+// as the queue is at most half full, leaving room for the new payloads.
+func (s *sender) drainSpool() {
+	for len(s.queue) <= cap(s.queue)/2 {
+		if !s.unspoolPayload() {
+			return
+		}
+	}
+}
+
+// unspoolPayload moves the oldest spooled payload to the queue. It returns false
+// if there is nothing to move or the queue can't take it.
+func (s *sender) unspoolPayload() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return false
+	}
+	p, name, err := s.cfg.spool.peek()
+	if err != nil {
+		log.Errorf("Error reading payload from the disk spool: %v", err)
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	select {
+	case s.queue <- p:
+		atomic.AddInt32(&s.inflight, 1)
+		s.cfg.spool.remove(name)
+		return true
+	default:
+		// the queue was filled in the meantime, the payload stays in the spool
+		ppool.Put(p)
+		return false
+	}
+}
+
 // releasePayload releases the payload p and records the specified event. The payload
 // should not be used again after a release.
 func (s *sender) releasePayload(p *payload, t eventType, data *eventData) {
@@ -231,6 +231,85 @@ func TestSender(t *testing.T) {
 			assert.True(time.Since(start)-failed[i].duration < time.Second)
 		}
 	})
+
+	t.Run("spool", func(t *testing.T) {
+		// waitAccepted waits for the server to accept n payloads.
+		waitAccepted := func(t *testing.T, server *testServer, n int) {
+			for timeout := time.After(5 * time.Second); server.Accepted() < n; {
+				select {
+				case <-timeout:
+					t.Fatalf("timed out waiting for %d payloads, got %d", n, server.Accepted())
+				case <-time.After(time.Millisecond):
+				}
+			}
+		}
+
+		t.Run("outage", func(t *testing.T) {
+			assert := assert.New(t)
+			server := newTestServer()
+			defer server.Close()
+			defer useBackoffDuration(0)()
+			dir, err := ioutil.TempDir("", "spool")
+			assert.NoError(err)
+			defer os.RemoveAll(dir)
+
+			var recorder mockRecorder
+			cfg := testSenderConfig(server.URL)
+			cfg.maxConns = 1
+			cfg.maxQueued = 2
+			cfg.recorder = &recorder
+			cfg.spool, err = newSpool(dir, 1<<20, time.Hour)
+			assert.NoError(err)
+			s := newSender(cfg)
+
+			// every payload is retried once, overflowing the queue
+			for i := 0; i < 20; i++ {
+				s.Push(expectResponses(503, 200))
+			}
+			waitAccepted(t, server, 20)
+			s.Stop()
+
+			assert.Equal(20, server.Accepted(), "accepted")
+			assert.Equal(20, server.Retried(), "retry")
+			assert.NotEmpty(recorder.data(eventTypeSpooled))
+			assert.Empty(recorder.data(eventTypeDropped))
+			assert.EqualValues(0, cfg.spool.stats().Payloads)
+		})
+
+		t.Run("restart", func(t *testing.T) {
+			assert := assert.New(t)
+			server := newTestServer()
+			defer server.Close()
+			dir, err := ioutil.TempDir("", "spool")
+			assert.NoError(err)
+			defer os.RemoveAll(dir)
+
+			// payloads spooled by a previous run
+			sp, err := newSpool(dir, 1<<20, time.Hour)
+			assert.NoError(err)
+			var bodies []string
+			for i := 0; i < 5; i++ {
+				p := expectResponses(200)
+				bodies = append(bodies, p.body.String())
+				assert.NoError(sp.add(p))
+			}
+
+			cfg := testSenderConfig(server.URL)
+			cfg.maxConns = 1
+			cfg.spool, err = newSpool(dir, 1<<20, time.Hour)
+			assert.NoError(err)
+			s := newSender(cfg)
+			waitAccepted(t, server, 5)
+			s.Stop()
+
+			var got []string
+			for _, p := range server.Payloads() {
+				got = append(got, p.body.String())
+			}
+			assert.Equal(bodies, got)
+			assert.EqualValues(0, cfg.spool.stats().Payloads)
+		})
+	})
 }
 
 func TestPayload(t *testing.T) {
@@ -285,8 +364,8 @@ func useBackoffDuration(d time.Duration) func() {
 
 // mockRecorder is a mock eventRecorder which records all calls to recordEvent.
 type mockRecorder struct {
-	mu                             sync.RWMutex
-	retry, sent, dropped, rejected []*eventData
+	mu                                      sync.RWMutex
+	retry, sent, dropped, rejected, spooled []*eventData
 }
 
 // data returns all call data for the given eventType.
@@ -302,6 +381,8 @@ func (r *mockRecorder) data(t eventType) []*eventData {
 		return r.dropped
 	case eventTypeRejected:
 		return r.rejected
+	case eventTypeSpooled:
+		return r.spooled
 	default:
 		panic("unknown event")
 	}
@@ -320,5 +401,7 @@ func (r *mockRecorder) recordEvent(t eventType, data *eventData) {
 		r.dropped = append(r.dropped, data)
 	case eventTypeRejected:
 		r.rejected = append(r.rejected, data)
+	case eventTypeSpooled:
+		r.spooled = append(r.spooled, data)
 	}
 }
@@ -0,0 +1,271 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// spoolFileExt is the extension of the files holding spooled payloads.
+	spoolFileExt = ".payload"
+	// spoolTempExt is the extension of the files being written.
+	spoolTempExt = ".tmp"
+)
+
+// spool keeps on disk the payloads which could not be sent, until the endpoint
+// recovers. Each payload is written to its own file, named after the time at which
+// it was spooled, so that payloads are sent back oldest first, including after a
+// restart of the agent.
+type spool struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+
+	mu    sync.Mutex  // guards below
+	files []spoolFile // spooled files, oldest first
+	size  int64       // total size of the spooled files
+	last  int64       // timestamp of the newest file, in nanoseconds
+
+	spooled int64 // atomic, payloads spooled since the last call to stats
+	dropped int64 // atomic, payloads dropped since the last call to stats
+}
+
+// spoolFile describes a file holding a spooled payload.
+type spoolFile struct {
+	name string
+	size int64
+	time time.Time
+}
+
+// newSpool returns a new spool keeping payloads in dir, up to maxSize bytes and for
+// at most maxAge. The payloads left in dir by a previous run are loaded back.
+func newSpool(dir string, maxSize int64, maxAge time.Duration) (*spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	fis, err := ioutil.ReadDir(dir) // sorted by name, hence by age
+	if err != nil {
+		return nil, err
+	}
+	s := &spool{
+		dir:     dir,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+	}
+	for _, fi := range fis {
+		name := fi.Name()
+		if fi.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(name, spoolTempExt) {
+			// an interrupted write
+			os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		if !strings.HasSuffix(name, spoolFileExt) {
+			continue
+		}
+		ns, err := strconv.ParseInt(strings.TrimSuffix(name, spoolFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		s.files = append(s.files, spoolFile{name: name, size: fi.Size(), time: time.Unix(0, ns)})
+		s.size += fi.Size()
+		s.last = ns
+	}
+	s.mu.Lock()
+	s.evict(0, time.Now())
+	s.mu.Unlock()
+	return s, nil
+}
+
+// add writes p to the spool, dropping the expired and then the oldest payloads to
+// make room for it.
+func (s *spool) add(p *payload) error {
+	headers, err := json.Marshal(p.headers)
+	if err != nil {
+		return err
+	}
+	size := int64(len(headers) + 1 + p.body.Len())
+	if size > s.maxSize {
+		return fmt.Errorf("payload of %d bytes exceeds the spool size", size)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.evict(size, now)
+	ns := now.UnixNano()
+	if ns <= s.last {
+		// keep the names unique and ordered
+		ns = s.last + 1
+	}
+	// pad the timestamp so that names sort in the same order as timestamps
+	name := fmt.Sprintf("%019d%s", ns, spoolFileExt)
+	if err := s.write(name, headers, p.body.Bytes()); err != nil {
+		return err
+	}
+	s.last = ns
+	s.files = append(s.files, spoolFile{name: name, size: size, time: time.Unix(0, ns)})
+	s.size += size
+	atomic.AddInt64(&s.spooled, 1)
+	return nil
+}
+
+// write atomically writes a file named name holding the given headers and body.
+func (s *spool) write(name string, headers, body []byte) error {
+	tmp := filepath.Join(s.dir, name+spoolTempExt)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(headers, '\n'))
+	if err == nil {
+		_, err = f.Write(body)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, filepath.Join(s.dir, name))
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// peek returns the oldest payload of the spool along with the name of its file,
+// or a nil payload if the spool is empty. The payload is kept in the spool until
+// it is removed using remove.
+func (s *spool) peek() (*payload, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evict(0, time.Now())
+	if len(s.files) == 0 {
+		return nil, "", nil
+	}
+	f := s.files[0]
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, f.name))
+	if err != nil {
+		s.drop()
+		return nil, "", err
+	}
+	var headers map[string]string
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 || json.Unmarshal(data[:i], &headers) != nil {
+		s.drop()
+		return nil, "", fmt.Errorf("malformed spool file %s", f.name)
+	}
+	p := newPayload(headers)
+	p.body.Write(data[i+1:])
+	return p, f.name, nil
+}
+
+// remove removes the file name returned by peek from the spool. It is a no-op if
+// the file was dropped in the meantime.
+func (s *spool) remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.files) > 0 && s.files[0].name == name {
+		s.removeOldest()
+	}
+}
+
+// evict drops the expired payloads, and then the oldest ones until there is room
+// for size more bytes. s.mu must be held.
+func (s *spool) evict(size int64, now time.Time) {
+	for len(s.files) > 0 {
+		if now.Sub(s.files[0].time) <= s.maxAge && s.size+size <= s.maxSize {
+			return
+		}
+		s.drop()
+	}
+}
+
+// drop drops the oldest payload. s.mu must be held.
+func (s *spool) drop() {
+	s.removeOldest()
+	atomic.AddInt64(&s.dropped, 1)
+}
+
+// removeOldest removes the oldest file of the spool. s.mu must be held.
+func (s *spool) removeOldest() {
+	f := s.files[0]
+	if err := os.Remove(filepath.Join(s.dir, f.name)); err != nil && !os.IsNotExist(err) {
+		log.Errorf("Error removing spooled payload: %v", err)
+	}
+	s.files[0] = spoolFile{}
+	s.files = s.files[1:]
+	s.size -= f.size
+}
+
+// stats returns statistics about the spool, resetting its counters.
+func (s *spool) stats() info.SpoolInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	si := info.SpoolInfo{
+		Payloads: int64(len(s.files)),
+		Bytes:    s.size,
+		Spooled:  atomic.SwapInt64(&s.spooled, 0),
+		Dropped:  atomic.SwapInt64(&s.dropped, 0),
+	}
+	if len(s.files) > 0 {
+		si.OldestSeconds = time.Since(s.files[0].time).Seconds()
+	}
+	return si
+}
+
+// spoolStats sums the statistics of the spools of the given senders. It returns
+// nil when spooling is disabled.
+func spoolStats(senders []*sender) *info.SpoolInfo {
+	var total *info.SpoolInfo
+	for _, s := range senders {
+		if s.cfg.spool == nil {
+			continue
+		}
+		if total == nil {
+			total = &info.SpoolInfo{}
+		}
+		si := s.cfg.spool.stats()
+		total.Payloads += si.Payloads
+		total.Bytes += si.Bytes
+		total.Spooled += si.Spooled
+		total.Dropped += si.Dropped
+		if si.OldestSeconds > total.OldestSeconds {
+			total.OldestSeconds = si.OldestSeconds
+		}
+	}
+	return total
+}
+
+// reportSpool reports the statistics of a writer's spools as metrics prefixed
+// with prefix. It is a no-op when si is nil.
+func reportSpool(prefix string, si *info.SpoolInfo) {
+	if si == nil {
+		return
+	}
+	metrics.Gauge(prefix+".payloads", float64(si.Payloads), nil, 1)
+	metrics.Gauge(prefix+".bytes", float64(si.Bytes), nil, 1)
+	metrics.Gauge(prefix+".oldest_seconds", si.OldestSeconds, nil, 1)
+	metrics.Count(prefix+".spooled", si.Spooled, nil, 1)
+	metrics.Count(prefix+".dropped", si.Dropped, nil, 1)
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package writer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpool(t *testing.T) {
+	newTestSpool := func(t *testing.T, maxSize int64, maxAge time.Duration) (*spool, func()) {
+		dir, err := ioutil.TempDir("", "spool")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := newSpool(dir, maxSize, maxAge)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s, func() { os.RemoveAll(dir) }
+	}
+	newTestPayload := func(body string) *payload {
+		p := newPayload(map[string]string{"Content-Type": "application/x-protobuf"})
+		p.body.WriteString(body)
+		return p
+	}
+	// next returns the body of the oldest payload in the spool and removes it.
+	next := func(t *testing.T, s *spool) string {
+		p, name, err := s.peek()
+		assert.NoError(t, err)
+		if p == nil {
+			return ""
+		}
+		s.remove(name)
+		return p.body.String()
+	}
+
+	t.Run("order", func(t *testing.T) {
+		assert := assert.New(t)
+		s, cleanup := newTestSpool(t, 1<<20, time.Hour)
+		defer cleanup()
+
+		for _, body := range []string{"1", "2", "3"} {
+			assert.NoError(s.add(newTestPayload(body)))
+		}
+		p, name, err := s.peek()
+		assert.NoError(err)
+		assert.Equal(map[string]string{"Content-Type": "application/x-protobuf"}, p.headers)
+		assert.Equal("1", p.body.String())
+		// peeking again returns the same payload until it is removed
+		_, name2, _ := s.peek()
+		assert.Equal(name, name2)
+		s.remove(name)
+
+		assert.Equal("2", next(t, s))
+		assert.Equal("3", next(t, s))
+		assert.Equal("", next(t, s))
+
+		si := s.stats()
+		assert.EqualValues(0, si.Payloads)
+		assert.EqualValues(0, si.Bytes)
+		assert.EqualValues(3, si.Spooled)
+		assert.EqualValues(0, si.Dropped)
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		assert := assert.New(t)
+		s, cleanup := newTestSpool(t, 1<<20, time.Hour)
+		defer cleanup()
+
+		for _, body := range []string{"1", "2"} {
+			assert.NoError(s.add(newTestPayload(body)))
+		}
+		// an interrupted write and an unrelated file
+		assert.NoError(ioutil.WriteFile(filepath.Join(s.dir, "0000000000000000001.payload.tmp"), []byte("partial"), 0600))
+		assert.NoError(ioutil.WriteFile(filepath.Join(s.dir, "README"), []byte("hello"), 0600))
+
+		s2, err := newSpool(s.dir, 1<<20, time.Hour)
+		assert.NoError(err)
+		si := s2.stats()
+		assert.EqualValues(2, si.Payloads)
+		assert.Equal(s.size, si.Bytes)
+		assert.True(si.OldestSeconds >= 0)
+		assert.NoError(s2.add(newTestPayload("3")))
+		assert.Equal("1", next(t, s2))
+		assert.Equal("2", next(t, s2))
+		assert.Equal("3", next(t, s2))
+
+		_, err = os.Stat(filepath.Join(s.dir, "0000000000000000001.payload.tmp"))
+		assert.True(os.IsNotExist(err))
+	})
+
+	t.Run("max-size", func(t *testing.T) {
+		assert := assert.New(t)
+		size := int64(len(`{"Content-Type":"application/x-protobuf"}`) + 1 + len("1"))
+		s, cleanup := newTestSpool(t, 2*size, time.Hour)
+		defer cleanup()
+
+		for _, body := range []string{"1", "2", "3"} {
+			assert.NoError(s.add(newTestPayload(body)))
+		}
+		si := s.stats()
+		assert.EqualValues(2, si.Payloads)
+		assert.EqualValues(2*size, si.Bytes)
+		assert.EqualValues(1, si.Dropped)
+		assert.Equal("2", next(t, s))
+
+		assert.Error(s.add(newTestPayload(strings.Repeat("x", 100))))
+	})
+
+	t.Run("max-age", func(t *testing.T) {
+		assert := assert.New(t)
+		s, cleanup := newTestSpool(t, 1<<20, time.Millisecond)
+		defer cleanup()
+
+		assert.NoError(s.add(newTestPayload("1")))
+		time.Sleep(5 * time.Millisecond)
+		assert.Equal("", next(t, s))
+		assert.EqualValues(1, s.stats().Dropped)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		assert := assert.New(t)
+		s, cleanup := newTestSpool(t, 1<<20, time.Hour)
+		defer cleanup()
+
+		assert.NoError(ioutil.WriteFile(filepath.Join(s.dir, "0000000000000000001.payload"), []byte("garbage"), 0600))
+		s, err := newSpool(s.dir, 1<<20, 100*365*24*time.Hour)
+		assert.NoError(err)
+		assert.NoError(s.add(newTestPayload("1")))
+
+		_, _, err = s.peek()
+		assert.Error(err)
+		assert.Equal("1", next(t, s))
+		assert.EqualValues(1, s.stats().Dropped)
+	})
+}
@@ -199,6 +199,13 @@ func (w *StatsWriter) buildPayloads(s []stats.Bucket, maxEntriesPerPayloads int)
 					newsb.ErrDistributions[ekey] = b.ErrDistributions[ekey]
 				}
 			}
+			if _, ok := b.Sketches[ekey]; ok {
+				if _, ok := newsb.Sketches[ekey]; ok {
+					newsb.Sketches[ekey] = newsb.Sketches[ekey].Merge(b.Sketches[ekey])
+				} else {
+					newsb.Sketches[ekey] = b.Sketches[ekey]
+				}
+			}
 			i++
 		}
 	}
@@ -226,12 +233,23 @@ func (w *StatsWriter) buildPayloads(s []stats.Bucket, maxEntriesPerPayloads int)
 var _ eventRecorder = (*StatsWriter)(nil)
 
 func (w *StatsWriter) report() {
-	metrics.Count("datadog.trace_agent.stats_writer.payloads", atomic.SwapInt64(&w.stats.Payloads, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.stats_writer.stats_buckets", atomic.SwapInt64(&w.stats.StatsBuckets, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.stats_writer.bytes", atomic.SwapInt64(&w.stats.Bytes, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.stats_writer.retries", atomic.SwapInt64(&w.stats.Retries, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.stats_writer.splits", atomic.SwapInt64(&w.stats.Splits, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.stats_writer.errors", atomic.SwapInt64(&w.stats.Errors, 0), nil, 1)
+	sws := info.StatsWriterInfo{
+		Payloads:     atomic.SwapInt64(&w.stats.Payloads, 0),
+		StatsBuckets: atomic.SwapInt64(&w.stats.StatsBuckets, 0),
+		Bytes:        atomic.SwapInt64(&w.stats.Bytes, 0),
+		Retries:      atomic.SwapInt64(&w.stats.Retries, 0),
+		Splits:       atomic.SwapInt64(&w.stats.Splits, 0),
+		Errors:       atomic.SwapInt64(&w.stats.Errors, 0),
+		Spool:        spoolStats(w.senders),
+	}
+	metrics.Count("datadog.trace_agent.stats_writer.payloads", sws.Payloads, nil, 1)
+	metrics.Count("datadog.trace_agent.stats_writer.stats_buckets", sws.StatsBuckets, nil, 1)
+	metrics.Count("datadog.trace_agent.stats_writer.bytes", sws.Bytes, nil, 1)
+	metrics.Count("datadog.trace_agent.stats_writer.retries", sws.Retries, nil, 1)
+	metrics.Count("datadog.trace_agent.stats_writer.splits", sws.Splits, nil, 1)
+	metrics.Count("datadog.trace_agent.stats_writer.errors", sws.Errors, nil, 1)
+	reportSpool("datadog.trace_agent.stats_writer.spool", sws.Spool)
+	info.UpdateStatsWriterInfo(sws)
 }
 
 // recordEvent implements eventRecorder.
@@ -259,5 +277,8 @@ func (w *StatsWriter) recordEvent(t eventType, data *eventData) {
 		log.Warnf("Stats writer queue full. Payload dropped (%.2fKB).", float64(data.bytes)/1024)
 		metrics.Count("datadog.trace_agent.stats_writer.dropped", 1, nil, 1)
 		metrics.Count("datadog.trace_agent.stats_writer.dropped_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeSpooled:
+		log.Debugf("Stats writer queue full. Payload written to the disk spool (%.2fKB).", float64(data.bytes)/1024)
 	}
 }
@@ -219,15 +219,29 @@ func (w *TraceWriter) flush() {
 }
 
 func (w *TraceWriter) report() {
-	metrics.Count("datadog.trace_agent.trace_writer.payloads", atomic.SwapInt64(&w.stats.Payloads, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.trace_writer.bytes_uncompressed", atomic.SwapInt64(&w.stats.BytesUncompressed, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.trace_writer.retries", atomic.SwapInt64(&w.stats.Retries, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.trace_writer.bytes_estimated", atomic.SwapInt64(&w.stats.BytesEstimated, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.trace_writer.bytes", atomic.SwapInt64(&w.stats.Bytes, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.trace_writer.errors", atomic.SwapInt64(&w.stats.Errors, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.trace_writer.traces", atomic.SwapInt64(&w.stats.Traces, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.trace_writer.events", atomic.SwapInt64(&w.stats.Events, 0), nil, 1)
-	metrics.Count("datadog.trace_agent.trace_writer.spans", atomic.SwapInt64(&w.stats.Spans, 0), nil, 1)
+	tws := info.TraceWriterInfo{
+		Payloads:          atomic.SwapInt64(&w.stats.Payloads, 0),
+		BytesUncompressed: atomic.SwapInt64(&w.stats.BytesUncompressed, 0),
+		Retries:           atomic.SwapInt64(&w.stats.Retries, 0),
+		BytesEstimated:    atomic.SwapInt64(&w.stats.BytesEstimated, 0),
+		Bytes:             atomic.SwapInt64(&w.stats.Bytes, 0),
+		Errors:            atomic.SwapInt64(&w.stats.Errors, 0),
+		Traces:            atomic.SwapInt64(&w.stats.Traces, 0),
+		Events:            atomic.SwapInt64(&w.stats.Events, 0),
+		Spans:             atomic.SwapInt64(&w.stats.Spans, 0),
+		Spool:             spoolStats(w.senders),
+	}
+	metrics.Count("datadog.trace_agent.trace_writer.payloads", tws.Payloads, nil, 1)
+	metrics.Count("datadog.trace_agent.trace_writer.bytes_uncompressed", tws.BytesUncompressed, nil, 1)
+	metrics.Count("datadog.trace_agent.trace_writer.retries", tws.Retries, nil, 1)
+	metrics.Count("datadog.trace_agent.trace_writer.bytes_estimated", tws.BytesEstimated, nil, 1)
+	metrics.Count("datadog.trace_agent.trace_writer.bytes", tws.Bytes, nil, 1)
+	metrics.Count("datadog.trace_agent.trace_writer.errors", tws.Errors, nil, 1)
+	metrics.Count("datadog.trace_agent.trace_writer.traces", tws.Traces, nil, 1)
+	metrics.Count("datadog.trace_agent.trace_writer.events", tws.Events, nil, 1)
+	metrics.Count("datadog.trace_agent.trace_writer.spans", tws.Spans, nil, 1)
+	reportSpool("datadog.trace_agent.trace_writer.spool", tws.Spool)
+	info.UpdateTraceWriterInfo(tws)
 }
 
 var _ eventRecorder = (*TraceWriter)(nil)
@@ -257,5 +271,8 @@ func (w *TraceWriter) recordEvent(t eventType, data *eventData) {
 		log.Warnf("Trace writer queue full. Payload dropped (%.2fKB).", float64(data.bytes)/1024)
 		metrics.Count("datadog.trace_agent.trace_writer.dropped", 1, nil, 1)
 		metrics.Count("datadog.trace_agent.trace_writer.dropped_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeSpooled:
+		log.Debugf("Trace writer queue full. Payload written to the disk spool (%.2fKB).", float64(data.bytes)/1024)
 	}
 }
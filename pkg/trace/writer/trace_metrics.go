@@ -0,0 +1,235 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package writer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
+	"github.com/DataDog/datadog-agent/pkg/trace/stats"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-go/statsd"
+)
+
+const (
+	// traceMetricsPrefix is the prefix of the names of the trace metrics.
+	traceMetricsPrefix = "trace."
+	// otherResource is the resource of the metrics of the resources exceeding the
+	// maximum number of resources of a service.
+	otherResource = "_other"
+	// maxDistributionPoints is the maximum number of points sent for the durations of
+	// each tag set of a bucket.
+	maxDistributionPoints = 100
+	// traceMetricsBufferLength is the number of metrics buffered by the DogStatsD client.
+	traceMetricsBufferLength = 256
+	// resourcesResetExports is the number of exports after which the resources tagged
+	// are forgotten, about an hour with the default flush interval of the concentrator.
+	resourcesResetExports = 360
+)
+
+// countMetrics holds the suffixes of the names of the counts sent for each measure.
+var countMetrics = map[string]string{
+	stats.HITS:   "hits",
+	stats.ERRORS: "errors",
+}
+
+// tagValueReplacer replaces the characters of tag values which have a meaning in the
+// DogStatsD protocol.
+var tagValueReplacer = strings.NewReplacer(",", "_", "|", "_", "\n", " ")
+
+// traceMetricsClient sends the trace metrics, it is implemented by *statsdTraceMetricsClient.
+// The rate of the distribution points is the rate at which they were sampled by the writer.
+type traceMetricsClient interface {
+	Count(name string, value int64, tags []string, rate float64) error
+	Distribution(name string, value float64, tags []string, rate float64) error
+	Flush() error
+	Close() error
+}
+
+// TraceMetricsWriter sends the stats buckets computed by the concentrator as
+// DogStatsD metrics, before passing them on to the stats writer. The hits and errors
+// of each span name are sent as the "trace.<name>.hits" and "trace.<name>.errors"
+// counts, and their durations, in seconds, as the "trace.<name>.duration"
+// distribution. The metrics reach the aggregator of the core agent through its
+// DogStatsD server.
+type TraceMetricsWriter struct {
+	in     <-chan []stats.Bucket
+	out    chan<- []stats.Bucket
+	client traceMetricsClient // nil if the client could not be created
+	stop   chan struct{}
+
+	tags         []string                       // tags of the stats kept on the metrics
+	maxResources int                            // maximum number of resources tagged per service, 0 for no limit
+	resources    map[string]map[string]struct{} // resources tagged, by service
+	exports      int                            // exports since the resources were reset
+}
+
+// NewTraceMetricsWriter returns a new TraceMetricsWriter forwarding the buckets from
+// in to out. It must be started using Run.
+func NewTraceMetricsWriter(cfg *config.AgentConfig, in <-chan []stats.Bucket, out chan<- []stats.Bucket) *TraceMetricsWriter {
+	w := &TraceMetricsWriter{
+		in:           in,
+		out:          out,
+		stop:         make(chan struct{}),
+		tags:         cfg.TraceMetrics.Tags,
+		maxResources: cfg.TraceMetrics.MaxResourcesPerService,
+		resources:    make(map[string]map[string]struct{}),
+	}
+	addr := fmt.Sprintf("%s:%d", cfg.StatsdHost, cfg.StatsdPort)
+	if cfg.TraceMetrics.DogStatsDSocket != "" {
+		addr = statsd.UnixAddressPrefix + cfg.TraceMetrics.DogStatsDSocket
+	}
+	client, err := newStatsdTraceMetricsClient(addr)
+	if err != nil {
+		log.Errorf("Error creating the DogStatsD client of the trace metrics, they won't be sent: %v", err)
+		return w
+	}
+	w.client = client
+	log.Debugf("Trace metrics writer initialized (addr=%s)", addr)
+	return w
+}
+
+// Run starts the TraceMetricsWriter.
+func (w *TraceMetricsWriter) Run() {
+	defer close(w.stop)
+	for {
+		select {
+		case buckets := <-w.in:
+			w.export(buckets)
+			w.out <- buckets
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Stop stops a running TraceMetricsWriter.
+func (w *TraceMetricsWriter) Stop() {
+	w.stop <- struct{}{}
+	<-w.stop
+	if w.client != nil {
+		w.client.Close()
+	}
+}
+
+// traceMetricKey identifies a count sent by the writer.
+type traceMetricKey struct {
+	name string
+	tags string
+}
+
+// export sends the metrics of the given buckets.
+func (w *TraceMetricsWriter) export(buckets []stats.Bucket) {
+	if w.client == nil {
+		return
+	}
+	defer timing.Since("datadog.trace_agent.trace_metrics.export_ms", time.Now())
+
+	// the resources seen are forgotten from time to time so that the services
+	// and resources which are gone don't accumulate
+	w.exports++
+	if w.exports >= resourcesResetExports {
+		w.resources = make(map[string]map[string]struct{})
+		w.exports = 0
+	}
+
+	// the tag sets of several counts may have the same tags once filtered,
+	// so the counts are summed before being sent
+	counts := make(map[traceMetricKey]float64)
+	tags := make(map[string][]string)
+	var points, overflow int64
+	for _, b := range buckets {
+		// the hits of each tag set, by which the durations are sampled
+		hits := make(map[string]float64)
+		for _, c := range b.Counts {
+			suffix, ok := countMetrics[c.Measure]
+			if !ok {
+				// durations and sublayers are not sent as counts
+				continue
+			}
+			t, over := w.metricTags(c.TagSet)
+			if c.Measure == stats.HITS {
+				hits[c.TagSet.TagKey(c.Name)] = c.Value
+				if over {
+					overflow++
+				}
+			}
+			key := traceMetricKey{name: traceMetricsPrefix + c.Name + "." + suffix, tags: strings.Join(t, ",")}
+			counts[key] += c.Value
+			tags[key.tags] = t
+		}
+		for _, d := range b.Distributions {
+			points += w.sendDistribution(d.Name, d.TagSet, d.Points(maxDistributionPoints), hits[d.TagSet.TagKey(d.Name)])
+		}
+		for _, d := range b.Sketches {
+			points += w.sendDistribution(d.Name, d.TagSet, d.Points(maxDistributionPoints), hits[d.TagSet.TagKey(d.Name)])
+		}
+	}
+	for key, v := range counts {
+		w.client.Count(key.name, int64(math.Round(v)), tags[key.tags], 1)
+	}
+	if err := w.client.Flush(); err != nil {
+		log.Debugf("Error flushing the trace metrics: %v", err)
+	}
+	metrics.Count("datadog.trace_agent.trace_metrics.counts", int64(len(counts)), nil, 1)
+	metrics.Count("datadog.trace_agent.trace_metrics.distribution_points", points, nil, 1)
+	metrics.Count("datadog.trace_agent.trace_metrics.other_resources", overflow, nil, 1)
+}
+
+// sendDistribution sends the given durations of the spans named name, returning the
+// number of points sent. They are only a sample of the durations when there are more
+// than maxDistributionPoints spans, or when the traces were sampled by the tracer, so
+// they are sent with the rate at which they represent the hits of the tag set.
+func (w *TraceMetricsWriter) sendDistribution(name string, ts stats.TagSet, durations []float64, hits float64) int64 {
+	t, _ := w.metricTags(ts)
+	rate := 1.0
+	if hits > float64(len(durations)) {
+		rate = float64(len(durations)) / hits
+	}
+	for _, v := range durations {
+		w.client.Distribution(traceMetricsPrefix+name+".duration", v/1e9, t, rate)
+	}
+	return int64(len(durations))
+}
+
+// metricTags returns the tags of the metrics of the stats of ts, and whether their
+// resource exceeded the maximum number of resources of their service.
+func (w *TraceMetricsWriter) metricTags(ts stats.TagSet) ([]string, bool) {
+	var over bool
+	match := ts.Match(w.tags)
+	tags := make([]string, 0, len(match))
+	for _, t := range match {
+		if t.Name == "resource" && !w.allowResource(ts.Get("service").Value, t.Value) {
+			t.Value = otherResource
+			over = true
+		}
+		tags = append(tags, t.Name+":"+tagValueReplacer.Replace(t.Value))
+	}
+	return tags, over
+}
+
+// allowResource returns true if resource can be tagged on the metrics of service,
+// which is the case of the first resources seen for each service.
+func (w *TraceMetricsWriter) allowResource(service, resource string) bool {
+	seen, ok := w.resources[service]
+	if !ok {
+		seen = make(map[string]struct{})
+		w.resources[service] = seen
+	}
+	if _, ok := seen[resource]; ok {
+		return true
+	}
+	if w.maxResources > 0 && len(seen) >= w.maxResources {
+		return false
+	}
+	seen[resource] = struct{}{}
+	return true
+}
@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package writer
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-go/statsd"
+)
+
+// maxDistributionPacketSize is the maximum size of the packets of distribution points.
+const maxDistributionPacketSize = 8192
+
+// statsdTraceMetricsClient sends the trace metrics to DogStatsD. The counts go through
+// a *statsd.Client, whereas the distribution points are written on their own connection:
+// they already are a sample of the durations, which *statsd.Client would sample again
+// on its side when their rate is below 1.
+type statsdTraceMetricsClient struct {
+	*statsd.Client
+	conn   net.Conn
+	packet bytes.Buffer
+}
+
+// newStatsdTraceMetricsClient returns a client sending the trace metrics to the DogStatsD
+// server listening on addr, either host:port or a unix socket prefixed by "unix://".
+func newStatsdTraceMetricsClient(addr string) (*statsdTraceMetricsClient, error) {
+	client, err := statsd.NewBuffered(addr, traceMetricsBufferLength)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	if strings.HasPrefix(addr, statsd.UnixAddressPrefix) {
+		conn, err = net.Dial("unixgram", addr[len(statsd.UnixAddressPrefix):])
+	} else {
+		conn, err = net.Dial("udp", addr)
+	}
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &statsdTraceMetricsClient{Client: client, conn: conn}, nil
+}
+
+// Distribution adds a point to the packet of distribution points, along with its rate.
+func (c *statsdTraceMetricsClient) Distribution(name string, value float64, tags []string, rate float64) error {
+	line := name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|d"
+	if rate < 1 {
+		line += "|@" + strconv.FormatFloat(rate, 'f', -1, 64)
+	}
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	if c.packet.Len() > 0 && c.packet.Len()+1+len(line) > maxDistributionPacketSize {
+		if err := c.flushPacket(); err != nil {
+			return err
+		}
+	}
+	if c.packet.Len() > 0 {
+		c.packet.WriteByte('\n')
+	}
+	c.packet.WriteString(line)
+	return nil
+}
+
+// flushPacket sends the pending distribution points.
+func (c *statsdTraceMetricsClient) flushPacket() error {
+	if c.packet.Len() == 0 {
+		return nil
+	}
+	_, err := c.conn.Write(c.packet.Bytes())
+	c.packet.Reset()
+	return err
+}
+
+// Flush sends all the pending metrics.
+func (c *statsdTraceMetricsClient) Flush() error {
+	err := c.flushPacket()
+	if err2 := c.Client.Flush(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// Close sends the pending metrics and closes the client.
+func (c *statsdTraceMetricsClient) Close() error {
+	c.flushPacket()
+	c.conn.Close()
+	return c.Client.Close()
+}
@@ -0,0 +1,248 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package writer
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/quantile"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/stats"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testTraceMetricsClient records the metrics sent to it.
+type testTraceMetricsClient struct {
+	mu            sync.Mutex
+	counts        map[string]int64     // by "name|tags"
+	distributions map[string][]float64 // by "name|tags"
+	rates         map[string]float64   // rate of the last distribution point, by "name|tags"
+}
+
+func newTestTraceMetricsClient() *testTraceMetricsClient {
+	return &testTraceMetricsClient{
+		counts:        make(map[string]int64),
+		distributions: make(map[string][]float64),
+		rates:         make(map[string]float64),
+	}
+}
+
+func (c *testTraceMetricsClient) Count(name string, value int64, tags []string, rate float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[name+"|"+strings.Join(tags, ",")] += value
+	return nil
+}
+
+func (c *testTraceMetricsClient) Distribution(name string, value float64, tags []string, rate float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := name + "|" + strings.Join(tags, ",")
+	c.distributions[key] = append(c.distributions[key], value)
+	c.rates[key] = rate
+	return nil
+}
+
+func (c *testTraceMetricsClient) Flush() error { return nil }
+
+func (c *testTraceMetricsClient) Close() error { return nil }
+
+func TestTraceMetricsWriter(t *testing.T) {
+	newTestWriter := func(tm *config.TraceMetricsConfig, in <-chan []stats.Bucket, out chan<- []stats.Bucket) (*TraceMetricsWriter, *testTraceMetricsClient) {
+		cfg := config.New()
+		cfg.TraceMetrics = tm
+		w := NewTraceMetricsWriter(cfg, in, out)
+		w.client.Close()
+		client := newTestTraceMetricsClient()
+		w.client = client
+		return w, client
+	}
+	newTestBucket := func(spans ...*pb.Span) stats.Bucket {
+		sb := stats.NewRawBucket(0, 1e10)
+		for _, s := range spans {
+			sb.HandleSpan(&stats.WeightedSpan{Span: s, Weight: 1, TopLevel: true}, "prod", []string{"http.status_code"}, []stats.SublayerValue{{
+				Metric: "_sublayers.duration.by_service",
+				Tag:    stats.Tag{Name: "sublayer_service", Value: s.Service},
+				Value:  float64(s.Duration),
+			}})
+		}
+		return sb.Export()
+	}
+	span := func(resource string, code string, duration time.Duration, err int32) *pb.Span {
+		return &pb.Span{
+			Service:  "web",
+			Name:     "http.request",
+			Resource: resource,
+			Duration: duration.Nanoseconds(),
+			Error:    err,
+			Meta:     map[string]string{"http.status_code": code},
+		}
+	}
+
+	t.Run("export", func(t *testing.T) {
+		assert := assert.New(t)
+		w, client := newTestWriter(&config.TraceMetricsConfig{
+			Enabled:                true,
+			Tags:                   []string{"env", "service", "resource"},
+			MaxResourcesPerService: 100,
+		}, nil, nil)
+
+		w.export([]stats.Bucket{newTestBucket(
+			span("GET /users", "200", time.Second, 0),
+			span("GET /users", "500", 3*time.Second, 1),
+			span("GET /a,b|c", "200", time.Second, 0),
+		)})
+
+		// the counts of the status codes are summed
+		tags := "|env:prod,resource:GET /users,service:web"
+		assert.EqualValues(2, client.counts["trace.http.request.hits"+tags])
+		assert.EqualValues(1, client.counts["trace.http.request.errors"+tags])
+		assert.NotContains(client.counts, "trace.http.request.duration"+tags)
+		for key := range client.counts {
+			assert.NotContains(key, "sublayer")
+		}
+		durations := client.distributions["trace.http.request.duration"+tags]
+		assert.Equal(1.0, client.rates["trace.http.request.duration"+tags])
+		sort.Float64s(durations)
+		if assert.Len(durations, 2) {
+			assert.InEpsilon(1, durations[0], 0.02)
+			assert.InEpsilon(3, durations[1], 0.02)
+		}
+
+		// the characters of the DogStatsD protocol are replaced
+		assert.EqualValues(1, client.counts["trace.http.request.hits|env:prod,resource:GET /a_b_c,service:web"])
+	})
+
+	t.Run("capped-points", func(t *testing.T) {
+		assert := assert.New(t)
+		w, client := newTestWriter(&config.TraceMetricsConfig{
+			Enabled: true,
+			Tags:    []string{"service"},
+		}, nil, nil)
+
+		spans := make([]*pb.Span, 150)
+		for i := range spans {
+			spans[i] = span("GET /users", "200", time.Second, 0)
+		}
+		w.export([]stats.Bucket{newTestBucket(spans...)})
+
+		// the distribution only holds a sample of the durations, sent with its rate
+		assert.Len(client.distributions["trace.http.request.duration|service:web"], maxDistributionPoints)
+		assert.Equal(float64(maxDistributionPoints)/150, client.rates["trace.http.request.duration|service:web"])
+		assert.EqualValues(150, client.counts["trace.http.request.hits|service:web"])
+		assert.NotContains(client.counts, "trace.http.request.duration.count|service:web")
+	})
+
+	t.Run("sketches", func(t *testing.T) {
+		assert := assert.New(t)
+		w, client := newTestWriter(&config.TraceMetricsConfig{
+			Enabled: true,
+			Tags:    []string{"service"},
+		}, nil, nil)
+
+		var ok, errs quantile.Agent
+		ok.Insert(1e9)
+		errs.Insert(2e9)
+		b := stats.NewBucket(0, 1e10)
+		b.Sketches["key"] = stats.SketchDistribution{
+			Name:   "http.request",
+			TagSet: stats.TagSet{{Name: "service", Value: "web"}},
+			OK:     ok.Finish(),
+			Error:  errs.Finish(),
+		}
+		w.export([]stats.Bucket{b})
+
+		durations := client.distributions["trace.http.request.duration|service:web"]
+		if assert.Len(durations, 2) {
+			assert.InEpsilon(1, durations[0], 0.02)
+			assert.InEpsilon(2, durations[1], 0.02)
+		}
+	})
+
+	t.Run("max-resources", func(t *testing.T) {
+		assert := assert.New(t)
+		w, client := newTestWriter(&config.TraceMetricsConfig{
+			Enabled:                true,
+			Tags:                   []string{"service", "resource"},
+			MaxResourcesPerService: 1,
+		}, nil, nil)
+
+		w.export([]stats.Bucket{newTestBucket(span("GET /users", "200", time.Second, 0))})
+		w.export([]stats.Bucket{newTestBucket(
+			span("GET /users", "200", time.Second, 0),
+			span("GET /items", "200", time.Second, 0),
+			span("GET /orders", "200", time.Second, 0),
+		)})
+
+		assert.EqualValues(2, client.counts["trace.http.request.hits|resource:GET /users,service:web"])
+		assert.EqualValues(2, client.counts["trace.http.request.hits|resource:_other,service:web"])
+		assert.Len(client.distributions["trace.http.request.duration|resource:_other,service:web"], 2)
+	})
+
+	t.Run("forward", func(t *testing.T) {
+		in := make(chan []stats.Bucket)
+		out := make(chan []stats.Bucket, 1)
+		w, client := newTestWriter(&config.TraceMetricsConfig{
+			Enabled: true,
+			Tags:    []string{"service"},
+		}, in, out)
+		go w.Run()
+
+		buckets := []stats.Bucket{newTestBucket(span("GET /users", "200", time.Second, 0))}
+		in <- buckets
+		assert.Equal(t, buckets, <-out)
+		w.Stop()
+		assert.EqualValues(t, 1, client.counts["trace.http.request.hits|service:web"])
+	})
+}
+
+func TestTraceMetricsResourcesReset(t *testing.T) {
+	cfg := config.New()
+	cfg.TraceMetrics = &config.TraceMetricsConfig{Enabled: true, Tags: []string{"service", "resource"}, MaxResourcesPerService: 1}
+	w := NewTraceMetricsWriter(cfg, nil, nil)
+	w.client.Close()
+	w.client = newTestTraceMetricsClient()
+
+	w.allowResource("web", "GET /users")
+	assert.False(t, w.allowResource("web", "GET /items"))
+	for i := 0; i < resourcesResetExports; i++ {
+		w.export(nil)
+	}
+	assert.Len(t, w.resources, 0)
+	assert.True(t, w.allowResource("web", "GET /items"))
+}
+
+func TestStatsdTraceMetricsClient(t *testing.T) {
+	assert := assert.New(t)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	client, err := newStatsdTraceMetricsClient(conn.LocalAddr().String())
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+	client.Distribution("trace.http.request.duration", 0.5, []string{"service:web"}, 0.25)
+	client.Distribution("trace.http.request.duration", 1, nil, 1)
+	assert.NoError(client.Flush())
+
+	// the points are sent with their rate, none of them is dropped
+	buf := make([]byte, maxDistributionPacketSize)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(err)
+	assert.Equal("trace.http.request.duration:0.5|d|@0.25|#service:web\ntrace.http.request.duration:1|d", string(buf[:n]))
+}
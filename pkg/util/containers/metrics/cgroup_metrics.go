@@ -432,3 +432,27 @@ func (c ContainerCgroup) ParseSingleStat(target, file string) (uint64, error) {
 	}
 	return value, nil
 }
+
+// psiTargets maps the resources of the pressure stall information to the
+// cgroup hierarchy holding their pressure file
+var psiTargets = map[string]string{
+	"cpu":    "cpu",
+	"memory": "memory",
+	"io":     "blkio",
+}
+
+// PSI returns the pressure stall information of a cgroup for a resource (cpu,
+// memory or io). The kernel only exposes it when PSI accounting is enabled,
+// nil is returned if the pressure file is missing.
+func (c ContainerCgroup) PSI(resource string) (*PSIStats, error) {
+	target, ok := psiTargets[resource]
+	if !ok {
+		return nil, fmt.Errorf("unknown pressure resource %s", resource)
+	}
+	psiFile := c.cgroupFilePath(target, resource+".pressure")
+	if psiFile == "" || !pathExists(psiFile) {
+		log.Debugf("Missing cgroup file: %s", psiFile)
+		return nil, nil
+	}
+	return ReadPSIFile(psiFile)
+}
@@ -224,3 +224,35 @@ func TestThreadCount(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, value, uint64(123))
 }
+
+func TestPSI(t *testing.T) {
+	tempFolder, err := newTempFolder("psi")
+	assert.Nil(t, err)
+	defer tempFolder.removeAll()
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, "memory", "blkio")
+
+	// PSI accounting disabled
+	stats, err := cgroup.PSI("memory")
+	assert.Nil(t, err)
+	assert.Nil(t, stats)
+
+	tempFolder.add("memory/memory.pressure", "some avg10=2.00 avg60=1.00 avg300=0.50 total=1000\nfull avg10=1.00 avg60=0.50 avg300=0.25 total=500\n")
+	stats, err = cgroup.PSI("memory")
+	assert.Nil(t, err)
+	assert.Equal(t, 2.0, stats.Some.Avg10)
+	assert.Equal(t, uint64(500), stats.Full.Total)
+
+	tempFolder.add("blkio/io.pressure", "some avg10=0.00 avg60=0.00 avg300=0.00 total=10\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=5\n")
+	stats, err = cgroup.PSI("io")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), stats.Some.Total)
+
+	// cpu hierarchy not mounted
+	stats, err = cgroup.PSI("cpu")
+	assert.Nil(t, err)
+	assert.Nil(t, stats)
+
+	_, err = cgroup.PSI("network")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadPSIFile parses a pressure stall information file, such as
+// /proc/pressure/memory or the memory.pressure file of a cgroup.
+func ReadPSIFile(path string) (*PSIStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParsePSI(f)
+}
+
+// ParsePSI parses the content of a pressure stall information file, made of
+// lines like:
+//
+//	some avg10=0.12 avg60=0.05 avg300=0.01 total=123456
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=6543
+func ParsePSI(r io.Reader) (*PSIStats, error) {
+	stats := &PSIStats{}
+	foundSome := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		line, err := parsePSILine(fields[1:])
+		if err != nil {
+			return nil, err
+		}
+		switch fields[0] {
+		case "some":
+			stats.Some = *line
+			foundSome = true
+		case "full":
+			stats.Full = line
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !foundSome {
+		return nil, fmt.Errorf("no \"some\" line found")
+	}
+	return stats, nil
+}
+
+func parsePSILine(fields []string) (*PSILine, error) {
+	line := &PSILine{}
+	for _, field := range fields {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid field %q", field)
+		}
+		var err error
+		switch kv[0] {
+		case "avg10":
+			line.Avg10, err = strconv.ParseFloat(kv[1], 64)
+		case "avg60":
+			line.Avg60, err = strconv.ParseFloat(kv[1], 64)
+		case "avg300":
+			line.Avg300, err = strconv.ParseFloat(kv[1], 64)
+		case "total":
+			line.Total, err = strconv.ParseUint(kv[1], 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: %s", field, err)
+		}
+	}
+	return line, nil
+}
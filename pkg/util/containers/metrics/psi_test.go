@@ -0,0 +1,40 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePSI(t *testing.T) {
+	stats, err := ParsePSI(strings.NewReader(`some avg10=1.50 avg60=0.75 avg300=0.10 total=123456
+full avg10=0.50 avg60=0.25 avg300=0.05 total=6543
+`))
+	require.NoError(t, err)
+	assert.Equal(t, PSILine{Avg10: 1.5, Avg60: 0.75, Avg300: 0.1, Total: 123456}, stats.Some)
+	require.NotNil(t, stats.Full)
+	assert.Equal(t, PSILine{Avg10: 0.5, Avg60: 0.25, Avg300: 0.05, Total: 6543}, *stats.Full)
+
+	// cpu pressure before Linux 5.13
+	stats, err = ParsePSI(strings.NewReader("some avg10=0.00 avg60=0.00 avg300=0.00 total=42\n"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), stats.Some.Total)
+	assert.Nil(t, stats.Full)
+
+	for _, invalid := range []string{
+		"",
+		"full avg10=0.00 avg60=0.00 avg300=0.00 total=0",
+		"some avg10=abc avg60=0.00 avg300=0.00 total=0",
+		"some avg10",
+	} {
+		_, err = ParsePSI(strings.NewReader(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
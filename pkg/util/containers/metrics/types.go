@@ -74,6 +74,24 @@ type CgroupIOStat struct {
 	DeviceWriteBytes map[string]uint64
 }
 
+// PSILine stores one line of a pressure stall information file: the
+// percentage of time some (or all) tasks were stalled on a resource over the
+// last 10, 60 and 300 seconds, and the total stall time in microseconds.
+type PSILine struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64
+}
+
+// PSIStats stores the pressure stall information of a resource (cpu, memory
+// or io). Full is nil when the kernel doesn't report it, which is the case
+// for cpu before Linux 5.13.
+type PSIStats struct {
+	Some PSILine
+	Full *PSILine
+}
+
 // ContainerCgroup is a structure that stores paths and mounts for a cgroup.
 // It provides several methods for collecting stats about the cgroup using the
 // paths and mounts metadata.
@@ -0,0 +1,102 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/diagnose/diagnosis"
+)
+
+func init() {
+	diagnosis.RegisterStructured("Proxy settings", diagnosis.CategoryConfiguration, func() []diagnosis.Result {
+		return diagnoseProxySettings(config.GetProxies())
+	})
+}
+
+// diagnoseProxySettings reports the proxies in use and warns about the
+// common proxy misconfigurations
+func diagnoseProxySettings(p *config.Proxy) []diagnosis.Result {
+	if p == nil || (p.HTTP == "" && p.HTTPS == "") {
+		return []diagnosis.Result{{
+			Status:  diagnosis.StatusPass,
+			Message: "No proxy configured",
+		}}
+	}
+
+	var results []diagnosis.Result
+	for _, proxy := range []struct {
+		setting string
+		value   string
+	}{
+		{"proxy.http", p.HTTP},
+		{"proxy.https", p.HTTPS},
+	} {
+		if proxy.value == "" {
+			continue
+		}
+		results = append(results, diagnoseProxyURL(proxy.setting, proxy.value, p.NoProxy))
+	}
+
+	if p.HTTP != "" && p.HTTPS == "" {
+		results = append(results, diagnosis.Result{
+			Target:      "proxy.https",
+			Status:      diagnosis.StatusWarning,
+			Message:     "An HTTP proxy is configured but no HTTPS proxy, the traffic to the Datadog intake is sent over HTTPS and won't use it",
+			Remediation: "Set `proxy.https` (or the DD_PROXY_HTTPS environment variable), usually to the same value as `proxy.http`.",
+		})
+	}
+	return results
+}
+
+func diagnoseProxyURL(setting, value string, noProxy []string) diagnosis.Result {
+	result := diagnosis.Result{
+		Target: setting,
+		Details: map[string]interface{}{
+			"no_proxy": noProxy,
+		},
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		result.Status = diagnosis.StatusWarning
+		result.Message = fmt.Sprintf("Unable to parse the proxy URL %q", scrubProxyURL(value))
+		result.Remediation = "Use the format 'http://[<USER>:<PASSWORD>@]<HOST>:<PORT>'."
+		return result
+	}
+	result.Details["url"] = scrubProxyURL(value)
+
+	if strings.ToLower(u.Scheme) == "https" {
+		result.Status = diagnosis.StatusWarning
+		result.Message = fmt.Sprintf("The proxy %s uses the https scheme, most proxies expect plain HTTP connections even when relaying HTTPS traffic", scrubProxyURL(value))
+		result.Remediation = "Unless your proxy accepts TLS connections, use an 'http://' URL."
+		return result
+	}
+
+	result.Status = diagnosis.StatusPass
+	result.Message = fmt.Sprintf("Using proxy %s", scrubProxyURL(value))
+	return result
+}
+
+// scrubProxyURL removes the credentials of a proxy URL
+func scrubProxyURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		// best effort: strip everything before the last '@'
+		if i := strings.LastIndex(value, "@"); i >= 0 {
+			return "********@" + value[i+1:]
+		}
+		return value
+	}
+	if u.User == nil {
+		return u.String()
+	}
+	u.User = nil
+	return strings.Replace(u.String(), "://", "://********:********@", 1)
+}
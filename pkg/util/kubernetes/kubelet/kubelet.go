@@ -10,6 +10,7 @@ package kubelet
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"expvar"
 	"fmt"
 	"io/ioutil"
@@ -34,6 +35,7 @@ import (
 const (
 	kubeletPodPath         = "/pods"
 	kubeletMetricsPath     = "/metrics"
+	kubeletSummaryPath     = "/stats/summary"
 	authorizationHeaderKey = "Authorization"
 	podListCacheKey        = "KubeletPodListCacheKey"
 	unreadyAnnotation      = "ad.datadoghq.com/tolerate-unready"
@@ -500,6 +502,24 @@ func (ku *KubeUtil) GetRawMetrics() ([]byte, error) {
 	return data, nil
 }
 
+// GetStatsSummary returns the node, pod and container statistics of the
+// kubelet /stats/summary endpoint
+func (ku *KubeUtil) GetStatsSummary() (*Summary, error) {
+	data, code, err := ku.QueryKubelet(kubeletSummaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("error performing kubelet query %s%s: %s", ku.kubeletApiEndpoint, kubeletSummaryPath, err)
+	}
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d on %s%s: %s", code, ku.kubeletApiEndpoint, kubeletSummaryPath, string(data))
+	}
+
+	var summary Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
 func (ku *KubeUtil) setupKubeletApiEndpoint() error {
 	// HTTPS
 	ku.kubeletApiEndpoint = fmt.Sprintf("https://%s:%d", ku.kubeletHost, config.Datadog.GetInt("kubernetes_https_kubelet_port"))
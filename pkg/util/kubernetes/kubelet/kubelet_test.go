@@ -43,8 +43,9 @@ const (
 // dummyKubelet allows tests to mock a kubelet's responses
 type dummyKubelet struct {
 	sync.Mutex
-	Requests chan *http.Request
-	PodsBody []byte
+	Requests    chan *http.Request
+	PodsBody    []byte
+	SummaryBody []byte
 
 	testingCertificate string
 	testingPrivateKey  string
@@ -70,6 +71,17 @@ func (d *dummyKubelet) loadPodList(podListJSONPath string) error {
 	return nil
 }
 
+func (d *dummyKubelet) loadSummary(summaryJSONPath string) error {
+	d.Lock()
+	defer d.Unlock()
+	summary, err := ioutil.ReadFile(summaryJSONPath)
+	if err != nil {
+		return err
+	}
+	d.SummaryBody = summary
+	return nil
+}
+
 func (d *dummyKubelet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	d.Lock()
 	defer d.Unlock()
@@ -87,6 +99,14 @@ func (d *dummyKubelet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		s, err := w.Write(d.PodsBody)
 		log.Debugf("dummyKubelet wrote %d bytes, err: %v", s, err)
 
+	case "/stats/summary":
+		if d.SummaryBody == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s, err := w.Write(d.SummaryBody)
+		log.Debugf("dummyKubelet wrote %d bytes, err: %v", s, err)
+
 	default:
 		w.WriteHeader(http.StatusNotFound)
 	}
@@ -265,6 +285,58 @@ func (suite *KubeletTestSuite) TestGetLocalPodList() {
 	}
 }
 
+func (suite *KubeletTestSuite) TestGetStatsSummary() {
+	mockConfig := config.Mock()
+
+	kubelet, err := newDummyKubelet("./testdata/podlist_1.8-2.json")
+	require.Nil(suite.T(), err)
+	ts, kubeletPort, err := kubelet.Start()
+	defer ts.Close()
+	require.Nil(suite.T(), err)
+
+	mockConfig.Set("kubernetes_kubelet_host", "localhost")
+	mockConfig.Set("kubernetes_http_kubelet_port", kubeletPort)
+	mockConfig.Set("kubelet_tls_verify", false)
+	mockConfig.Set("kubelet_auth_token_path", "")
+
+	kubeutil, err := GetKubeUtil()
+	require.Nil(suite.T(), err)
+	require.NotNil(suite.T(), kubeutil)
+	kubelet.dropRequests() // Throwing away first GETs
+
+	// summary API disabled
+	_, err = kubeutil.GetStatsSummary()
+	require.Error(suite.T(), err)
+	kubelet.dropRequests()
+
+	err = kubelet.loadSummary("./testdata/summary.json")
+	require.Nil(suite.T(), err)
+	summary, err := kubeutil.GetStatsSummary()
+	require.Nil(suite.T(), err)
+	require.NotNil(suite.T(), summary)
+
+	assert.Equal(suite.T(), "my-node-name", summary.Node.NodeName)
+	require.Len(suite.T(), summary.Node.SystemContainers, 2)
+	assert.Equal(suite.T(), "kubelet", summary.Node.SystemContainers[0].Name)
+	assert.EqualValues(suite.T(), 36402890, *summary.Node.SystemContainers[0].CPU.UsageNanoCores)
+	require.Len(suite.T(), summary.Pods, 1)
+	pod := summary.Pods[0]
+	assert.Equal(suite.T(), "kube-apiserver-my-node-name", pod.PodRef.Name)
+	assert.EqualValues(suite.T(), 57344, *pod.EphemeralStorage.UsedBytes)
+	assert.EqualValues(suite.T(), 1048576, *pod.Network.RxBytes)
+	require.Len(suite.T(), pod.Containers, 1)
+	assert.EqualValues(suite.T(), 10485760, *pod.Containers[0].Memory.WorkingSetBytes)
+	assert.Nil(suite.T(), pod.Containers[0].Memory.RSSBytes)
+
+	select {
+	case r := <-kubelet.Requests:
+		require.Equal(suite.T(), r.Method, "GET")
+		require.Equal(suite.T(), r.URL.Path, "/stats/summary")
+	case <-time.After(2 * time.Second):
+		require.FailNow(suite.T(), "Timeout on receive channel")
+	}
+}
+
 func (suite *KubeletTestSuite) TestGetNodeInfo() {
 	mockConfig := config.Mock()
 
@@ -857,7 +929,7 @@ func (suite *KubeletTestSuite) TestPodListExpire() {
 	require.Len(suite.T(), pods, 3)
 
 	// Test we kept the right pods
-	expectedNames := []string{"dd-agent-ntepl", "hello5-1550509440-rlgvf", "hello8-1550505780-kdnjx"}
+	expectedNames := []string{"kube-apiserver-my-node-name", "hello5-1550509440-rlgvf", "hello8-1550505780-kdnjx"}
 	var podNames []string
 	for _, p := range pods {
 		podNames = append(podNames, p.Metadata.Name)
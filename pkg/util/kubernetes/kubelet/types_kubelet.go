@@ -109,11 +109,13 @@ type Conditions struct {
 
 // ContainerStatus contains fields for unmarshalling a Pod.Status.Containers
 type ContainerStatus struct {
-	Name  string         `json:"name"`
-	Image string         `json:"image"`
-	ID    string         `json:"containerID"`
-	Ready bool           `json:"ready"`
-	State ContainerState `json:"state"`
+	Name         string         `json:"name"`
+	Image        string         `json:"image"`
+	ID           string         `json:"containerID"`
+	Ready        bool           `json:"ready"`
+	RestartCount int            `json:"restartCount"`
+	State        ContainerState `json:"state"`
+	LastState    ContainerState `json:"lastState"`
 }
 
 // IsPending returns if the container doesn't have an ID
@@ -142,6 +144,7 @@ type ContainerStateRunning struct {
 
 // ContainerStateTerminated is a terminated state of a container.
 type ContainerStateTerminated struct {
+	Reason     string    `json:"reason"`
 	ExitCode   int32     `json:"exitCode"`
 	StartedAt  time.Time `json:"startedAt"`
 	FinishedAt time.Time `json:"finishedAt"`
@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build kubelet
+
+package kubelet
+
+// Summary contains fields for unmarshalling the /stats/summary response
+type Summary struct {
+	Node NodeStats  `json:"node"`
+	Pods []PodStats `json:"pods,omitempty"`
+}
+
+// NodeStats contains fields for unmarshalling a Summary.Node
+type NodeStats struct {
+	NodeName         string           `json:"nodeName"`
+	SystemContainers []ContainerStats `json:"systemContainers,omitempty"`
+}
+
+// PodStats contains fields for unmarshalling a Summary.Pods
+type PodStats struct {
+	PodRef           PodReference     `json:"podRef"`
+	Containers       []ContainerStats `json:"containers,omitempty"`
+	Network          *NetworkStats    `json:"network,omitempty"`
+	EphemeralStorage *FsStats         `json:"ephemeral-storage,omitempty"`
+}
+
+// PodReference contains fields for unmarshalling a PodStats.PodRef
+type PodReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	UID       string `json:"uid"`
+}
+
+// ContainerStats contains fields for unmarshalling a PodStats.Containers
+// or a NodeStats.SystemContainers
+type ContainerStats struct {
+	Name   string       `json:"name"`
+	CPU    *CPUStats    `json:"cpu,omitempty"`
+	Memory *MemoryStats `json:"memory,omitempty"`
+	Rootfs *FsStats     `json:"rootfs,omitempty"`
+	Logs   *FsStats     `json:"logs,omitempty"`
+}
+
+// CPUStats contains fields for unmarshalling a ContainerStats.CPU
+type CPUStats struct {
+	UsageNanoCores       *uint64 `json:"usageNanoCores,omitempty"`
+	UsageCoreNanoSeconds *uint64 `json:"usageCoreNanoSeconds,omitempty"`
+}
+
+// MemoryStats contains fields for unmarshalling a ContainerStats.Memory
+type MemoryStats struct {
+	UsageBytes      *uint64 `json:"usageBytes,omitempty"`
+	WorkingSetBytes *uint64 `json:"workingSetBytes,omitempty"`
+	RSSBytes        *uint64 `json:"rssBytes,omitempty"`
+	PageFaults      *uint64 `json:"pageFaults,omitempty"`
+	MajorPageFaults *uint64 `json:"majorPageFaults,omitempty"`
+}
+
+// NetworkStats contains fields for unmarshalling a PodStats.Network, the
+// top-level counters are the ones of the default interface
+type NetworkStats struct {
+	InterfaceStats
+	Interfaces []InterfaceStats `json:"interfaces,omitempty"`
+}
+
+// InterfaceStats contains fields for unmarshalling a NetworkStats.Interfaces
+type InterfaceStats struct {
+	Name     string  `json:"name"`
+	RxBytes  *uint64 `json:"rxBytes,omitempty"`
+	RxErrors *uint64 `json:"rxErrors,omitempty"`
+	TxBytes  *uint64 `json:"txBytes,omitempty"`
+	TxErrors *uint64 `json:"txErrors,omitempty"`
+}
+
+// FsStats contains fields for unmarshalling filesystem usages
+type FsStats struct {
+	AvailableBytes *uint64 `json:"availableBytes,omitempty"`
+	CapacityBytes  *uint64 `json:"capacityBytes,omitempty"`
+	UsedBytes      *uint64 `json:"usedBytes,omitempty"`
+	InodesFree     *uint64 `json:"inodesFree,omitempty"`
+	Inodes         *uint64 `json:"inodes,omitempty"`
+	InodesUsed     *uint64 `json:"inodesUsed,omitempty"`
+}
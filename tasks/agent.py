@@ -54,12 +54,15 @@ AGENT_CORECHECKS = [
     "docker",
     "file_handle",
     "go_expvar",
+    "http",
     "io",
     "jmx",
+    "kubelet_core",
     "kubernetes_apiserver",
     "load",
     "memory",
     "ntp",
+    "pressure",
     "systemd",
     "uptime",
     "winproc",